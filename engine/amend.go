@@ -0,0 +1,75 @@
+package engine
+
+import (
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ErrInvalidAmend is returned when an order is amended to a quantity that is
+// not positive.
+var ErrInvalidAmend = errors.New("engine: amended quantity must be positive")
+
+// AmendOrder changes the price and remaining quantity of a resting order in
+// the book of the specified trading pair, keeping the rest of the order as it
+// is. How the amendment is carried out follows from what it changes:
+//
+//   - Lowering the quantity at the same price is a ReduceOrder: the order
+//     keeps its place in the queue, an Amended event carries the new
+//     quantity and the fill reports it as still open.
+//   - Raising the quantity or changing the price is a ReplaceOrder under the
+//     same ID: the order is canceled, with a Canceled fill and a Removed
+//     event, and entered again, with a New fill and, if it rests, an Added
+//     event under a new Seq at the back of the queue. At a new price it
+//     trades immediately if it crosses.
+//
+// Amending an order to its current price and quantity changes nothing.
+// orderID may also be the order's client order ID; pending stops cannot be
+// amended.
+//
+// Returns ErrInvalidAmend if qty is not positive, ErrPairNotFound if the pair
+// has no order book, ErrOrderNotFound if the order is not resting,
+// ErrOrderRejected if the amended order breaks the pair's trading rules, in
+// which case the order keeps resting unchanged, or the journal error if the
+// amendment could not be recorded.
+func (e *Engine) AmendOrder(pair, orderID string, price, qty decimal.Decimal) error {
+	if !qty.IsPositive() {
+		return ErrInvalidAmend
+	}
+	if _, exists := e.book(pair); !exists {
+		return ErrPairNotFound
+	}
+
+	return e.submit(pair, Order{}, func(book *OrderBook, res *MatchResult) error {
+		order, ok := book.Order(orderID)
+		if !ok || order.Type == TrailingStop || order.Type == MarketIfTouched {
+			return ErrOrderNotFound
+		}
+		by, replacement, replace := amendment(order, price, qty)
+		if replace {
+			_, err := e.replace(book, order.ID, true, replacement, res)
+			return err
+		}
+		if by.IsZero() {
+			return nil
+		}
+		return e.reduce(book, order.ID, by, res)
+	})
+}
+
+// amendment works out how amending order to price and qty is carried out:
+// by reducing it in place by the returned quantity, which is zero if nothing
+// changes, when only its quantity falls, or otherwise by replacing it with
+// the returned order.
+func amendment(order Order, price, qty decimal.Decimal) (by decimal.Decimal, replacement Order, replace bool) {
+	if order.Price.Equal(price) && qty.LessThanOrEqual(order.Qty) {
+		return order.Qty.Sub(qty), Order{}, false
+	}
+	// The order re-enters the book as a new arrival, so the book assigns it
+	// a fresh sequence number and receive time.
+	replacement = order
+	replacement.Price, replacement.Qty = price, qty
+	replacement.Seq, replacement.ReceivedAt = 0, time.Time{}
+	return decimal.Zero, replacement, true
+}
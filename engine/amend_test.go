@@ -0,0 +1,102 @@
+package engine
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// TestAmendOrderEvents tests the exact fills and events of each kind of amendment and that only a pure reduction keeps the queue position
+func TestAmendOrderEvents(t *testing.T) {
+	tests := []struct {
+		name     string
+		price    int64
+		qty      int64
+		fills    []FillStatus
+		events   []OrderEventType
+		position int
+		ahead    int64
+	}{
+		{"reduce", 100, 1, []FillStatus{New}, []OrderEventType{Amended}, 2, 2},
+		{"increase", 100, 3, []FillStatus{Canceled, New}, []OrderEventType{Removed, Added}, 3, 4},
+		{"reprice", 99, 2, []FillStatus{Canceled, New}, []OrderEventType{Removed, Added}, 1, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewEngine()
+			pair := "BTC-USD"
+			for _, id := range []string{"a", "b", "c"} {
+				engine.AddOrder(pair, Order{ID: id, Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(2)})
+			}
+			drainFills(engine)
+			before, _ := engine.getOrCreateBook(pair).Order("b")
+			drainOrderEvents(engine)
+
+			if err := engine.AmendOrder(pair, "b", decimal.NewFromInt(tt.price), decimal.NewFromInt(tt.qty)); err != nil {
+				t.Fatalf("Expected the amendment to succeed, got %v", err)
+			}
+
+			for i, status := range tt.fills {
+				fill := <-engine.FillStream
+				if fill.OrderID != "b" || fill.Status != status {
+					t.Errorf("Expected fill %d to be %s for b, got %+v", i, status, fill)
+				}
+				if i == len(tt.fills)-1 && !fill.RemainingQty.Equal(decimal.NewFromInt(tt.qty)) {
+					t.Errorf("Expected b to have %d remaining, got %s", tt.qty, fill.RemainingQty)
+				}
+			}
+			if len(engine.FillStream) != 0 {
+				t.Errorf("Expected no further fills, got %d", len(engine.FillStream))
+			}
+
+			events := drainOrderEvents(engine)
+			if len(events) != len(tt.events) {
+				t.Fatalf("Expected events %v, got %+v", tt.events, events)
+			}
+			for i, typ := range tt.events {
+				if events[i].Type != typ || events[i].Order.ID != "b" {
+					t.Errorf("Expected event %d to be %s for b, got %s for %s", i, typ, events[i].Type, events[i].Order.ID)
+				}
+			}
+			last := events[len(events)-1].Order
+			if !last.Qty.Equal(decimal.NewFromInt(tt.qty)) || !last.Price.Equal(decimal.NewFromInt(tt.price)) {
+				t.Errorf("Expected b to rest %d at %d, got %s at %s", tt.qty, tt.price, last.Qty, last.Price)
+			}
+			if keeps := last.Seq == before.Seq; keeps != (tt.name == "reduce") {
+				t.Errorf("Expected sequence kept only on a reduction, got %d before and %d after", before.Seq, last.Seq)
+			}
+
+			ahead, position, err := engine.QueuePosition(pair, "b")
+			if err != nil || position != tt.position || !ahead.Equal(decimal.NewFromInt(tt.ahead)) {
+				t.Errorf("Expected b at position %d with %d ahead, got %d with %s (%v)", tt.position, tt.ahead, position, ahead, err)
+			}
+		})
+	}
+}
+
+// TestAmendOrderErrors tests that invalid, missing and unchanged amendments leave the book alone
+func TestAmendOrderErrors(t *testing.T) {
+	engine := NewEngine()
+	pair := "BTC-USD"
+	engine.AddOrder(pair, Order{ID: "a", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(2)})
+	drainFills(engine)
+	drainOrderEvents(engine)
+
+	if err := engine.AmendOrder(pair, "a", decimal.NewFromInt(100), decimal.Zero); !errors.Is(err, ErrInvalidAmend) {
+		t.Errorf("Expected ErrInvalidAmend, got %v", err)
+	}
+	if err := engine.AmendOrder(pair, "missing", decimal.NewFromInt(100), decimal.NewFromInt(1)); !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("Expected ErrOrderNotFound, got %v", err)
+	}
+	if err := engine.AmendOrder("ETH-USD", "a", decimal.NewFromInt(100), decimal.NewFromInt(1)); !errors.Is(err, ErrPairNotFound) {
+		t.Errorf("Expected ErrPairNotFound, got %v", err)
+	}
+	if err := engine.AmendOrder(pair, "a", decimal.NewFromInt(100), decimal.NewFromInt(2)); err != nil {
+		t.Errorf("Expected an unchanged amendment to succeed, got %v", err)
+	}
+	if len(engine.FillStream) != 0 || len(engine.OrderEvents) != 0 {
+		t.Errorf("Expected no fills or events, got %d fills and %d events", len(engine.FillStream), len(engine.OrderEvents))
+	}
+}
@@ -0,0 +1,48 @@
+package engine
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// fillRestingAON executes the resting all-or-none orders on the side opposite
+// rested that the liquidity on the rested side can now fill completely. Such
+// an order is taken off the book and matched as if it had just arrived,
+// trading at the resting prices. Orders are tried in priority order until
+// none can be filled. The caller must hold ob.mutex.
+func (ob *OrderBook) fillRestingAON(res *MatchResult, rested Side, now int64) {
+	var h heap.Interface = ob.bids
+	side := &ob.bids.indexedHeap
+	if rested == Buy {
+		h = ob.asks
+		side = &ob.asks.indexedHeap
+	}
+
+	for {
+		var candidates orderHeap
+		for _, o := range side.orderHeap {
+			if o.AON && !o.expired(now) {
+				candidates = append(candidates, o)
+			}
+		}
+		if rested == Buy {
+			sort.Sort(askOrders(candidates))
+		} else {
+			sort.Sort(bidOrders(candidates))
+		}
+
+		var aon *Order
+		for _, o := range candidates {
+			if ob.fillsCompletely(*o, now) {
+				aon = o
+				break
+			}
+		}
+		if aon == nil {
+			return
+		}
+
+		order := ob.removeAt(h, side.indexOf(aon.ID))
+		ob.match(res, &order, now)
+	}
+}
@@ -0,0 +1,71 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// TestAONRestsUntilFillable tests that an all-or-none order rests untouched until it can fill completely
+func TestAONRestsUntilFillable(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	ob.Execute(Order{ID: "ask1", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(4)})
+
+	res := ob.Execute(Order{ID: "aon", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(10), AON: true})
+	if len(res.Trades) != 0 {
+		t.Fatalf("Expected AON order not to trade partially, got %d trades", len(res.Trades))
+	}
+	if bids, asks := ob.OpenOrderCount(); bids != 1 || asks != 1 {
+		t.Fatalf("Expected AON order to rest beside the ask, got %d bids and %d asks", bids, asks)
+	}
+	if err := ob.Validate(); err != nil {
+		t.Errorf("Expected a resting AON order not to count as crossing, got %v", err)
+	}
+
+	res = ob.Execute(Order{ID: "ask2", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(6)})
+	if len(res.Trades) != 2 {
+		t.Fatalf("Expected AON order to fill against both asks, got %d trades", len(res.Trades))
+	}
+	fill, _ := lastFill(res.Fills, "aon")
+	if fill.Status != Filled || !fill.CumulativeQty.Equal(decimal.NewFromInt(10)) {
+		t.Errorf("Expected AON order to be filled completely, got %+v", fill)
+	}
+	if bids, asks := ob.OpenOrderCount(); bids != 0 || asks != 0 {
+		t.Errorf("Expected an empty book, got %d bids and %d asks", bids, asks)
+	}
+}
+
+// TestAONMakerSkipped tests that a resting AON order is passed over by aggressors too small to fill it
+func TestAONMakerSkipped(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	ob.Execute(Order{ID: "aon", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(5), AON: true})
+	ob.Execute(Order{ID: "ask", Side: Sell, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(1)})
+
+	res := ob.Execute(Order{ID: "small", Side: Buy, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(2)})
+	if len(res.Trades) != 1 || res.Trades[0].SellOrderID != "ask" {
+		t.Fatalf("Expected the small buy to skip the AON order and trade with the next ask, got %+v", res.Trades)
+	}
+	if err := ob.Validate(); err != nil {
+		t.Errorf("Expected a valid book, got %v", err)
+	}
+
+	res = ob.Execute(Order{ID: "large", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(5)})
+	if len(res.Trades) != 1 || res.Trades[0].SellOrderID != "aon" || !res.Trades[0].Qty.Equal(decimal.NewFromInt(5)) {
+		t.Fatalf("Expected AON order to fill completely, got %+v", res.Trades)
+	}
+}
+
+// TestFOKIgnoresUnfillableAON tests that FOK orders do not count AON liquidity they cannot consume
+func TestFOKIgnoresUnfillableAON(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	ob.Execute(Order{ID: "aon", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(5), AON: true})
+	ob.Execute(Order{ID: "ask", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(2)})
+
+	res := ob.Execute(Order{ID: "fok", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(3), TimeInForce: FOK})
+	if len(res.Trades) != 0 {
+		t.Errorf("Expected FOK order to be canceled without trading, got %d trades", len(res.Trades))
+	}
+	if fill, _ := lastFill(res.Fills, "fok"); fill.Status != Canceled {
+		t.Errorf("Expected FOK order to be canceled, got %s", fill.Status)
+	}
+}
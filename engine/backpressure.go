@@ -0,0 +1,88 @@
+package engine
+
+import "sync/atomic"
+
+// Backpressure selects what happens when an outbound stream's channel is
+// full.
+type Backpressure string
+
+const (
+	// Block waits until the consumer makes room, so no event is lost but a
+	// slow consumer stalls the producer.
+	Block Backpressure = "block"
+	// DropNewest discards the event being sent.
+	DropNewest Backpressure = "drop_newest"
+	// DropOldest discards the oldest buffered event to make room for the
+	// event being sent.
+	DropOldest Backpressure = "drop_oldest"
+)
+
+// WithBackpressure applies policy to every outbound stream: TradeStream,
+// FillStream, PriceUpdates, DepthUpdates, OrderEvents and HaltEvents.
+// Without this option trades and fills block while market data, order events
+// and halt events are dropped when their channel is full.
+func WithBackpressure(policy Backpressure) Option {
+	return func(e *Engine) {
+		e.tradePolicy = policy
+		e.fillPolicy = policy
+		e.marketDataPolicy = policy
+	}
+}
+
+// dropCounters counts the events discarded from each outbound stream.
+type dropCounters struct {
+	trades atomic.Uint64
+	fills  atomic.Uint64
+	prices atomic.Uint64
+	depth  atomic.Uint64
+	events atomic.Uint64
+	halts  atomic.Uint64
+}
+
+// reset zeroes the counters.
+func (d *dropCounters) reset() {
+	d.trades.Store(0)
+	d.fills.Store(0)
+	d.prices.Store(0)
+	d.depth.Store(0)
+	d.events.Store(0)
+	d.halts.Store(0)
+}
+
+// deliver sends v on ch according to policy, counting discarded events in
+// dropped, and returns how many events it discarded. A blocking send gives up
+// when cancel is closed; a nil cancel blocks until the send succeeds.
+func deliver[T any](ch chan T, v T, policy Backpressure, dropped *atomic.Uint64, cancel <-chan struct{}) uint64 {
+	switch policy {
+	case DropNewest:
+		select {
+		case ch <- v:
+		default:
+			dropped.Add(1)
+			return 1
+		}
+	case DropOldest:
+		var n uint64
+		for {
+			select {
+			case ch <- v:
+				return n
+			default:
+			}
+			select {
+			case <-ch:
+				dropped.Add(1)
+				n++
+			default:
+			}
+		}
+	default:
+		select {
+		case ch <- v:
+		case <-cancel:
+			dropped.Add(1)
+			return 1
+		}
+	}
+	return 0
+}
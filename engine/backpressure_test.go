@@ -0,0 +1,89 @@
+package engine
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// TestDeliverPolicies tests each backpressure policy against an un-drained channel
+func TestDeliverPolicies(t *testing.T) {
+	t.Run("drop newest", func(t *testing.T) {
+		ch := make(chan int, 2)
+		var dropped atomic.Uint64
+		for i := 1; i <= 4; i++ {
+			deliver(ch, i, DropNewest, &dropped, nil)
+		}
+		if dropped.Load() != 2 || <-ch != 1 || <-ch != 2 {
+			t.Errorf("Expected oldest events kept and 2 dropped, got %d dropped", dropped.Load())
+		}
+	})
+
+	t.Run("drop oldest", func(t *testing.T) {
+		ch := make(chan int, 2)
+		var dropped atomic.Uint64
+		for i := 1; i <= 4; i++ {
+			deliver(ch, i, DropOldest, &dropped, nil)
+		}
+		if dropped.Load() != 2 || <-ch != 3 || <-ch != 4 {
+			t.Errorf("Expected newest events kept and 2 dropped, got %d dropped", dropped.Load())
+		}
+	})
+
+	t.Run("block", func(t *testing.T) {
+		ch := make(chan int, 1)
+		var dropped atomic.Uint64
+		deliver(ch, 1, Block, &dropped, nil)
+
+		sent := make(chan struct{})
+		go func() {
+			deliver(ch, 2, Block, &dropped, nil)
+			close(sent)
+		}()
+		select {
+		case <-sent:
+			t.Fatal("Expected send to block on a full channel")
+		case <-time.After(50 * time.Millisecond):
+		}
+		<-ch
+		<-sent
+		if v := <-ch; v != 2 || dropped.Load() != 0 {
+			t.Errorf("Expected blocked event to be delivered without drops, got %d with %d dropped", v, dropped.Load())
+		}
+
+		cancel := make(chan struct{})
+		close(cancel)
+		ch <- 3
+		deliver(ch, 4, Block, &dropped, cancel)
+		if dropped.Load() != 1 {
+			t.Errorf("Expected canceled blocking send to count as a drop, got %d", dropped.Load())
+		}
+	})
+}
+
+// TestEngineBackpressure tests that the engine applies its policy to an un-drained fill stream
+func TestEngineBackpressure(t *testing.T) {
+	for _, policy := range []Backpressure{DropNewest, DropOldest} {
+		t.Run(string(policy), func(t *testing.T) {
+			engine := NewEngine(WithBackpressure(policy))
+			capacity := cap(engine.FillStream)
+			for i := 0; i < capacity+5; i++ {
+				engine.AddOrder("BTC-USD", Order{ID: fmt.Sprintf("buy%d", i), Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+			}
+
+			if n := engine.drops.fills.Load(); n != 5 {
+				t.Errorf("Expected 5 dropped fills, got %d", n)
+			}
+			expected := "buy0"
+			if policy == DropOldest {
+				expected = "buy5"
+			}
+			if fill := <-engine.FillStream; fill.OrderID != expected {
+				t.Errorf("Expected first buffered fill %s, got %s", expected, fill.OrderID)
+			}
+		})
+	}
+}
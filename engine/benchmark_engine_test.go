@@ -0,0 +1,95 @@
+package engine
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+)
+
+var benchmarkPairs = []string{"BTC-USDT", "ETH-USDT", "LTC-USDT", "XRP-USDT", "SOL-USDT", "ADA-USDT", "DOT-USDT", "BNB-USDT"}
+
+// drainEngine consumes the engine output streams so benchmarks never block on them.
+func drainEngine(e *Engine) {
+	go func() {
+		for range e.TradeStream {
+		}
+	}()
+	go func() {
+		for range e.FillStream {
+		}
+	}()
+}
+
+// perOrderGoroutineAddOrder reproduces the original intake model, which spawned
+// two forwarding goroutines and two channels for every order.
+func perOrderGoroutineAddOrder(e *Engine, pair string, order Order) {
+	book := e.getOrCreateBook(pair)
+	tradeCh := make(chan Trade, 10)
+	fillCh := make(chan OrderFill, 10)
+
+	go func() {
+		for trade := range tradeCh {
+			e.recordTrade(trade)
+			e.TradeStream <- trade
+		}
+	}()
+
+	go func() {
+		for fill := range fillCh {
+			e.FillStream <- fill
+		}
+	}()
+
+	book.Match(order, tradeCh, fillCh, order.Qty)
+	close(tradeCh)
+	close(fillCh)
+}
+
+// benchmarkIntake submits orders from parallel goroutines spread across several pairs.
+func benchmarkIntake(b *testing.B, add func(e *Engine, pair string, order Order)) {
+	e := NewEngine()
+	drainEngine(e)
+
+	var next atomic.Int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		pair := benchmarkPairs[next.Add(1)%int64(len(benchmarkPairs))]
+		for pb.Next() {
+			i := next.Add(1)
+			add(e, pair, orders[i%int64(len(orders))])
+		}
+	})
+}
+
+// BenchmarkAddOrderSharded measures intake throughput through the shard workers.
+func BenchmarkAddOrderSharded(b *testing.B) {
+	benchmarkIntake(b, func(e *Engine, pair string, order Order) {
+		e.AddOrder(pair, order)
+	})
+}
+
+// BenchmarkAddOrderPerOrderGoroutines measures intake throughput of the
+// original per-order-goroutine model for comparison.
+func BenchmarkAddOrderPerOrderGoroutines(b *testing.B) {
+	benchmarkIntake(b, perOrderGoroutineAddOrder)
+}
+
+// BenchmarkAddOrderShardCounts compares intake throughput for different shard counts.
+func BenchmarkAddOrderShardCounts(b *testing.B) {
+	for _, n := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("shards=%d", n), func(b *testing.B) {
+			e := NewEngine(WithShards(n))
+			drainEngine(e)
+
+			var next atomic.Int64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				pair := benchmarkPairs[next.Add(1)%int64(len(benchmarkPairs))]
+				for pb.Next() {
+					i := next.Add(1)
+					e.AddOrder(pair, orders[i%int64(len(orders))])
+				}
+			})
+		})
+	}
+}
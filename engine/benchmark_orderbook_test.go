@@ -1,7 +1,10 @@
 package engine
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"math/rand/v2"
 	"runtime"
@@ -12,9 +15,6 @@ import (
 	"github.com/shopspring/decimal"
 )
 
-var book = NewOrderBook("BTC-USDT")
-var fillCount = 0
-var tradeCount = 0
 var orders = make([]Order, 0, 2000000)
 
 func init() {
@@ -46,39 +46,163 @@ func init() {
 }
 
 func BenchmarkWithRandomData(benchmark *testing.B) {
-	tradeCh := make(chan Trade, 100)
-	fillCh := make(chan OrderFill, 100)
+	e := NewEngine()
+	drainEngine(e)
 
-	go func() {
-		for trade := range tradeCh {
-			_ = trade
-			tradeCount++
+	// submit orders to the order book
+	for i := 0; i < benchmark.N; i++ {
+		e.AddOrder("BTC-USDT", orders[i%len(orders)])
+	}
+	_, trades, fills := e.Counters()
+	e.Close()
+
+	// Run garbage collection after each benchmark run to clean up memory
+	runtime.GC()
+
+	fmt.Printf("Total trades processed: %d, Total orders filled: %d\n", trades, fills)
+}
+
+// restingOrders returns n non-crossing orders: bids below 75000 and asks above
+func restingOrders(n int) []Order {
+	resting := make([]Order, 0, n)
+	for i, order := range orders[:n] {
+		order.Seq = uint64(i + 1)
+		if order.Side == Buy {
+			order.Price = order.Price.Div(decimal.NewFromInt(2))
+		} else {
+			order.Price = order.Price.Div(decimal.NewFromInt(2)).Add(decimal.NewFromInt(75001))
+		}
+		resting = append(resting, order)
+	}
+	return resting
+}
+
+// BenchmarkLoadResting measures seeding a book with one batch load
+func BenchmarkLoadResting(b *testing.B) {
+	resting := restingOrders(100000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ob := NewOrderBook("BTC-USDT")
+		ob.LoadResting(resting)
+	}
+}
+
+// BenchmarkSnapshotEncoding compares the size and speed of the binary and JSON
+// encodings of a large book snapshot
+func BenchmarkSnapshotEncoding(b *testing.B) {
+	ob := NewOrderBook("BTC-USDT")
+	ob.LoadResting(restingOrders(100000))
+	snapshot := ob.Snapshot()
+
+	encoders := map[string]func(io.Writer) error{
+		"gob":  snapshot.Encode,
+		"json": func(w io.Writer) error { return json.NewEncoder(w).Encode(snapshot) },
+	}
+	for _, name := range []string{"gob", "json"} {
+		encode := encoders[name]
+		b.Run(name, func(b *testing.B) {
+			var buf bytes.Buffer
+			for i := 0; i < b.N; i++ {
+				buf.Reset()
+				if err := encode(&buf); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(buf.Len()), "bytes")
+		})
+	}
+
+	decoders := map[string]func(io.Reader, *BookSnapshot) error{
+		"gob":  func(r io.Reader, s *BookSnapshot) error { return s.Decode(r) },
+		"json": func(r io.Reader, s *BookSnapshot) error { return json.NewDecoder(r).Decode(s) },
+	}
+	for _, name := range []string{"gob", "json"} {
+		var buf bytes.Buffer
+		if err := encoders[name](&buf); err != nil {
+			b.Fatal(err)
 		}
-	}()
-	go func() {
-		for fill := range fillCh {
-			_ = fill
-			if fill.Status == Filled || fill.Status == PartiallyFilled {
-				fillCount++
+		encoded, decode := buf.Bytes(), decoders[name]
+		b.Run(name+"-decode", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				var decoded BookSnapshot
+				if err := decode(bytes.NewReader(encoded), &decoded); err != nil {
+					b.Fatal(err)
+				}
 			}
+		})
+	}
+}
+
+// BenchmarkPushResting measures seeding a book by resting orders one at a time
+func BenchmarkPushResting(b *testing.B) {
+	resting := restingOrders(100000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ob := NewOrderBook("BTC-USDT")
+		ob.mutex.Lock()
+		for _, order := range resting {
+			ob.rest(order)
 		}
-	}()
+		ob.refreshTop()
+		ob.mutex.Unlock()
+	}
+}
 
-	// submit orders to the order book
-	for i := 0; i < benchmark.N; i++ {
-		book.Match(orders[i], tradeCh, fillCh, orders[i].Qty)
+// benchmarkRestWithCapacity rests 100,000 orders in a book created with hint
+func benchmarkRestWithCapacity(b *testing.B, hint int) {
+	resting := restingOrders(100000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ob := NewOrderBookWithCapacity("BTC-USDT", hint)
+		ob.mutex.Lock()
+		for _, order := range resting {
+			ob.rest(order)
+		}
+		ob.mutex.Unlock()
 	}
+}
 
-	// Wait for all trades and fills to be processed
-	for len(tradeCh) > 0 || len(fillCh) > 0 {
-		time.Sleep(100 * time.Millisecond)
+// BenchmarkRestDefaultCapacity measures resting orders in a default-sized book
+func BenchmarkRestDefaultCapacity(b *testing.B) {
+	benchmarkRestWithCapacity(b, defaultBookCapacity)
+}
+
+// BenchmarkRestWithCapacityHint measures resting orders in a book sized up front
+func BenchmarkRestWithCapacityHint(b *testing.B) {
+	benchmarkRestWithCapacity(b, 100000)
+}
+
+// benchmarkMatchTicks matches 100,000 random orders on a 0.01 tick, with the
+// book's tick size configured or not
+func benchmarkMatchTicks(b *testing.B, configured bool) {
+	tick := decimal.RequireFromString("0.01")
+	ticked := make([]Order, 100000)
+	for i, order := range orders[:len(ticked)] {
+		order.Price = roundTo(order.Price, tick, RoundNearest)
+		ticked[i] = order
 	}
 
-	close(tradeCh)
-	close(fillCh)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ob := NewOrderBook("BTC-USDT")
+		if configured {
+			ob.SetConfig(PairConfig{TickSize: tick})
+		}
+		var res MatchResult
+		for _, order := range ticked {
+			res.reset()
+			ob.executeInto(order, &res)
+		}
+	}
+}
 
-	// Run garbage collection after each benchmark run to clean up memory
-	runtime.GC()
+// BenchmarkMatchIntegerTicks measures matching with integer-tick price comparisons
+func BenchmarkMatchIntegerTicks(b *testing.B) {
+	benchmarkMatchTicks(b, true)
+}
 
-	fmt.Printf("Total trades processed: %d, Total orders filled: %d\n", tradeCount, fillCount)
+// BenchmarkMatchDecimalPrices measures matching with decimal price comparisons
+func BenchmarkMatchDecimalPrices(b *testing.B) {
+	benchmarkMatchTicks(b, false)
 }
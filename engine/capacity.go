@@ -0,0 +1,87 @@
+package engine
+
+import (
+	"container/heap"
+
+	"github.com/shopspring/decimal"
+)
+
+// WithMaxRestingOrders caps the number of orders resting in each book of the
+// engine at n, bounding the memory a single pair can take up. Once a book is
+// full, an order that would rest without trading is rejected with
+// RejectBookFull, and the remainder of an order that traded on entry is
+// canceled with that reason instead of resting. Orders that trade in full
+// are unaffected, and pending stops do not count towards the cap. A cap of
+// zero, the default, leaves books unbounded.
+func WithMaxRestingOrders(n int) Option {
+	return func(e *Engine) {
+		if n >= 0 {
+			e.maxResting = n
+		}
+	}
+}
+
+// WithMaxPriceLevels makes every book of the engine retain only the best n
+// price levels per side, a mode for strategies that only ever look at the top
+// of the book and want its heaps, and the cost of operating on them, kept
+// small. Whenever an order rests, the orders on its side priced beyond the
+// n-th level are canceled with OutsideDepth, including the new order itself
+// if it is the one outside. Orders evicted this way are gone for good even if
+// the book later thins out. Zero, the default, retains every level.
+func WithMaxPriceLevels(n int) Option {
+	return func(e *Engine) {
+		if n >= 0 {
+			e.maxLevels = n
+		}
+	}
+}
+
+// full reports whether the book holds as many resting orders as its cap
+// allows. The caller must hold ob.mutex.
+func (ob *OrderBook) full() bool {
+	return ob.maxResting > 0 && len(ob.bids.orderHeap)+len(ob.asks.orderHeap) >= ob.maxResting
+}
+
+// marketable reports whether order crosses the best order resting on the
+// opposite side. The caller must hold ob.mutex.
+func (ob *OrderBook) marketable(order *Order) bool {
+	h := ob.bids.orderHeap
+	if order.Side == Buy {
+		h = ob.asks.orderHeap
+	}
+	return len(h) > 0 && crosses(order, h[0])
+}
+
+// trimLevels cancels the orders on side priced beyond the best maxLevels price
+// levels in priority order, reporting each one as canceled with OutsideDepth
+// in res. The caller must hold ob.mutex.
+func (ob *OrderBook) trimLevels(res *MatchResult, side Side, now int64) {
+	if ob.maxLevels <= 0 {
+		return
+	}
+	var h, sorted heap.Interface = ob.asks, askOrders(ob.asks.clone())
+	orders := &ob.asks.indexedHeap
+	if side == Buy {
+		h, sorted = ob.bids, bidOrders(ob.bids.clone())
+		orders = &ob.bids.indexedHeap
+	}
+
+	var evicted []string
+	var price decimal.Decimal
+	levels := 0
+	for sorted.Len() > 0 {
+		o := heap.Pop(sorted).(*Order)
+		if levels == 0 || !o.Price.Equal(price) {
+			levels++
+			price = o.Price
+		}
+		if levels > ob.maxLevels {
+			evicted = append(evicted, o.ID)
+		}
+	}
+	for _, id := range evicted {
+		fill := canceledFill(ob.Pair, ob.removeAt(h, orders.indexOf(id)), now)
+		fill.Reason = OutsideDepth
+		res.Fills = append(res.Fills, fill)
+	}
+}
@@ -0,0 +1,92 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// TestMaxRestingOrders tests that a full book rejects resting orders while crossing orders still trade
+func TestMaxRestingOrders(t *testing.T) {
+	engine := NewEngine(WithMaxRestingOrders(3))
+	pair := "BTC-USD"
+	for i, price := range []int64{100, 99, 98} {
+		engine.AddOrder(pair, Order{ID: string(rune('a' + i)), Side: Buy, Price: decimal.NewFromInt(price), Qty: decimal.NewFromInt(1)})
+	}
+	drainFills(engine)
+
+	engine.AddOrder(pair, Order{ID: "extra", Side: Sell, Price: decimal.NewFromInt(105), Qty: decimal.NewFromInt(1)})
+	if fill := <-engine.FillStream; fill.Status != Rejected || fill.Reason != RejectBookFull {
+		t.Errorf("Expected the resting order to be rejected as the book is full, got %+v", fill)
+	}
+
+	engine.AddOrder(pair, Order{ID: "cross", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	if trade := <-engine.TradeStream; trade.SellOrderID != "cross" || !trade.Price.Equal(decimal.NewFromInt(100)) {
+		t.Errorf("Expected the crossing order to trade at 100, got %+v", trade)
+	}
+	drainFills(engine)
+
+	// The fill freed a slot, and a cancel frees another
+	engine.AddOrder(pair, Order{ID: "rest1", Side: Sell, Price: decimal.NewFromInt(105), Qty: decimal.NewFromInt(1)})
+	if fill := <-engine.FillStream; fill.Status != New {
+		t.Errorf("Expected the order to rest in the freed slot, got %+v", fill)
+	}
+	engine.AddOrder(pair, Order{ID: "rest2", Side: Sell, Price: decimal.NewFromInt(106), Qty: decimal.NewFromInt(1)})
+	if fill := <-engine.FillStream; fill.Status != Rejected {
+		t.Errorf("Expected the book to be full again, got %+v", fill)
+	}
+	if err := engine.CancelOrder(pair, "b"); err != nil {
+		t.Fatalf("Expected the cancel to succeed, got %v", err)
+	}
+	drainFills(engine)
+	engine.AddOrder(pair, Order{ID: "rest3", Side: Sell, Price: decimal.NewFromInt(106), Qty: decimal.NewFromInt(1)})
+	if fill := <-engine.FillStream; fill.Status != New {
+		t.Errorf("Expected the cancel to free a slot, got %+v", fill)
+	}
+	if bids, asks, _ := engine.OpenOrderCount(pair); bids+asks != 3 {
+		t.Errorf("Expected 3 resting orders, got %d", bids+asks)
+	}
+}
+
+// TestMaxPriceLevels tests that orders priced beyond the retained levels are evicted with a canceled fill
+func TestMaxPriceLevels(t *testing.T) {
+	engine := NewEngine(WithMaxPriceLevels(2))
+	pair := "BTC-USD"
+	for _, o := range []struct {
+		id    string
+		price int64
+	}{{"b100", 100}, {"b99", 99}, {"b99-2", 99}} {
+		engine.AddOrder(pair, Order{ID: o.id, Side: Buy, Price: decimal.NewFromInt(o.price), Qty: decimal.NewFromInt(1)})
+	}
+	engine.AddOrder(pair, Order{ID: "ask", Side: Sell, Price: decimal.NewFromInt(110), Qty: decimal.NewFromInt(1)})
+	drainFills(engine)
+
+	// A better bid pushes both orders at 99 out of the top two levels
+	engine.AddOrder(pair, Order{ID: "b101", Side: Buy, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(1)})
+	if fill := <-engine.FillStream; fill.OrderID != "b101" || fill.Status != New {
+		t.Errorf("Expected b101 to rest, got %+v", fill)
+	}
+	for _, id := range []string{"b99", "b99-2"} {
+		if fill := <-engine.FillStream; fill.OrderID != id || fill.Status != Canceled || fill.Reason != OutsideDepth {
+			t.Errorf("Expected %s to be canceled outside the depth, got %+v", id, fill)
+		}
+	}
+
+	// An order that would rest beyond the retained levels is evicted itself
+	engine.AddOrder(pair, Order{ID: "b98", Side: Buy, Price: decimal.NewFromInt(98), Qty: decimal.NewFromInt(1)})
+	<-engine.FillStream
+	if fill := <-engine.FillStream; fill.OrderID != "b98" || fill.Status != Canceled || fill.Reason != OutsideDepth {
+		t.Errorf("Expected b98 to be canceled outside the depth, got %+v", fill)
+	}
+
+	depth := engine.GetOrderBookDepth(pair, 10)
+	if len(depth.Bids) != 2 || !depth.Bids[0].Price.Equal(decimal.NewFromInt(101)) || !depth.Bids[1].Price.Equal(decimal.NewFromInt(100)) {
+		t.Errorf("Expected bids at 101 and 100 only, got %v", depth.Bids)
+	}
+	if len(depth.Asks) != 1 {
+		t.Errorf("Expected the ask side untouched, got %v", depth.Asks)
+	}
+	if bids, _, _ := engine.OpenOrderCount(pair); bids != 2 {
+		t.Errorf("Expected 2 resting bids, got %d", bids)
+	}
+}
@@ -0,0 +1,45 @@
+package engine
+
+import "time"
+
+// Clock is the engine's source of time. All timestamps on fills, trades and
+// market data, and all time-based order behaviour, are derived from it so that
+// tests and replays can control time deterministically.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts an ordinary function to the Clock interface.
+type ClockFunc func() time.Time
+
+// Now calls f.
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// realClock is the default Clock backed by time.Now.
+type realClock struct{}
+
+// Now returns the current wall-clock time.
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// WithClock sets the clock used by the engine and every book it creates.
+func WithClock(c Clock) Option {
+	return func(e *Engine) {
+		e.clock = c
+	}
+}
+
+// stamp returns t, the current time of the book's clock, moved a nanosecond
+// past the previous stamp if the clock has not advanced since, so stamps
+// order orders arriving faster than the clock's resolution. The caller must
+// hold ob.mutex.
+func (ob *OrderBook) stamp(t time.Time) time.Time {
+	if !t.After(ob.stamped) {
+		t = ob.stamped.Add(time.Nanosecond)
+	}
+	ob.stamped = t
+	return t
+}
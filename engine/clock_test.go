@@ -0,0 +1,109 @@
+package engine
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// fakeClock is a manually advanced Clock for deterministic tests
+type fakeClock struct {
+	now   time.Time
+	mutex sync.Mutex
+}
+
+// newFakeClock creates a fake clock starting at the given time
+func newFakeClock(start time.Time) *fakeClock {
+	return &fakeClock{now: start}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// TestEngineUsesInjectedClock tests that event timestamps come from the configured clock
+func TestEngineUsesInjectedClock(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	clock := newFakeClock(start)
+	engine := NewEngine(WithClock(clock))
+	pair := "BTC-USD"
+
+	engine.AddOrder(pair, Order{ID: "sell1", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	newFill := <-engine.FillStream
+	if newFill.Timestamp != start.Unix() {
+		t.Errorf("Expected NEW fill timestamp %d, got %d", start.Unix(), newFill.Timestamp)
+	}
+
+	clock.Advance(90 * time.Second)
+	engine.AddOrder(pair, Order{ID: "buy1", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+
+	expected := start.Add(90 * time.Second).Unix()
+	trade := <-engine.TradeStream
+	if trade.Timestamp != expected {
+		t.Errorf("Expected trade timestamp %d, got %d", expected, trade.Timestamp)
+	}
+	for i := 0; i < 2; i++ {
+		if fill := <-engine.FillStream; fill.Timestamp != expected {
+			t.Errorf("Expected fill timestamp %d, got %d", expected, fill.Timestamp)
+		}
+	}
+
+	if depth := engine.GetOrderBookDepth(pair, 5); depth.Timestamp != expected {
+		t.Errorf("Expected depth timestamp %d, got %d", expected, depth.Timestamp)
+	}
+}
+
+// TestOrderBookSetClock tests replacing the clock on a standalone order book
+func TestOrderBookSetClock(t *testing.T) {
+	at := time.Unix(1700000000, 0)
+	ob := NewOrderBook("BTC-USDT")
+	ob.SetClock(ClockFunc(func() time.Time { return at }))
+
+	result := ob.Execute(Order{ID: "buy1", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	if len(result.Fills) != 1 || result.Fills[0].Timestamp != at.Unix() {
+		t.Errorf("Expected a single fill stamped %d, got %+v", at.Unix(), result.Fills)
+	}
+}
+
+// TestReceivedAtMonotonic tests that ReceivedAt is populated and strictly increasing even when the clock stands still
+func TestReceivedAtMonotonic(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	engine := NewEngine(WithClock(newFakeClock(start)))
+	pair := "BTC-USD"
+
+	var last time.Time
+	for i := 0; i < 100; i++ {
+		id := fmt.Sprintf("buy%d", i)
+		engine.AddOrder(pair, Order{ID: id, Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+		order, ok := engine.GetOrder(pair, id)
+		if !ok {
+			t.Fatalf("Expected %s to rest", id)
+		}
+		if order.ReceivedAt.Before(start) || !order.ReceivedAt.After(last) {
+			t.Fatalf("Expected %s to be received after %v, got %v", id, last, order.ReceivedAt)
+		}
+		last = order.ReceivedAt
+	}
+
+	engine.AddOrder(pair, Order{ID: "sell", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	if trade := <-engine.TradeStream; !trade.MatchedAt.After(last) {
+		t.Errorf("Expected the trade to be matched after the last order was received, got %v", trade.MatchedAt)
+	}
+	for len(engine.FillStream) > 0 {
+		if fill := <-engine.FillStream; fill.ExecutedQty.IsPositive() && fill.MatchedAt.IsZero() {
+			t.Errorf("Expected execution fill for %s to carry MatchedAt", fill.OrderID)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package engine
+
+import "errors"
+
+// ErrEngineClosed is returned when an operation is submitted to an engine
+// that has been closed.
+var ErrEngineClosed = errors.New("engine: closed")
+
+// enter admits a submission unless the engine is closed. Every admitted
+// submission must be released with e.inflight.Done once it has been
+// processed.
+func (e *Engine) enter() bool {
+	e.gate.RLock()
+	defer e.gate.RUnlock()
+
+	if e.closed {
+		return false
+	}
+	e.inflight.Add(1)
+	return true
+}
+
+// Close shuts the engine down gracefully. New orders and other operations
+// are refused from the moment Close is called, while those already submitted
+// are matched and their events published as usual. The expiry sweeper and
+// snapshotter stop at once; once the submitted work has all been processed
+// the price broadcaster and depth streamer are stopped, the shard workers exit
+// and every output stream is closed, so a consumer ranging over TradeStream or
+// FillStream receives every event and then sees the channel close.
+//
+// Under the default Block policy Close waits for consumers to make room for
+// the remaining events, so streams must keep being drained until they close.
+// Read-only queries such as GetOrderBookDepth keep working on the final book
+// state. The journal is not closed. Returns ErrEngineClosed if the engine was
+// already closed.
+func (e *Engine) Close() error {
+	e.gate.Lock()
+	if e.closed {
+		e.gate.Unlock()
+		return ErrEngineClosed
+	}
+	e.closed = true
+	close(e.closing)
+	e.gate.Unlock()
+
+	e.inflight.Wait()
+	e.priceLoop.close()
+	e.depthLoop.close()
+	for _, s := range e.shards {
+		close(s.jobs)
+	}
+	e.workers.Wait()
+
+	close(e.TradeStream)
+	close(e.FillStream)
+	close(e.OrderEvents)
+	close(e.HaltEvents)
+	close(e.PriceUpdates)
+	close(e.DepthUpdates)
+	return nil
+}
@@ -0,0 +1,160 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// TestCloseDrainsInFlightOrders tests that Close delivers the events of every accepted order before closing the streams
+func TestCloseDrainsInFlightOrders(t *testing.T) {
+	engine := NewEngine(WithShards(2))
+	engine.StartPriceBroadcaster(PriceBroadcastConfig{})
+	engine.StartExpirySweeper(defaultPriceInterval)
+
+	var trades []Trade
+	fills := make(map[string][]OrderFill)
+	var consumers sync.WaitGroup
+	consumers.Add(2)
+	go func() {
+		defer consumers.Done()
+		for trade := range engine.TradeStream {
+			trades = append(trades, trade)
+		}
+	}()
+	go func() {
+		defer consumers.Done()
+		for fill := range engine.FillStream {
+			fills[fill.OrderID] = append(fills[fill.OrderID], fill)
+		}
+	}()
+
+	var mutex sync.Mutex
+	var accepted []string
+	var submitters sync.WaitGroup
+	for i := 0; i < 200; i++ {
+		submitters.Add(1)
+		go func(i int) {
+			defer submitters.Done()
+			side := Buy
+			if i%2 == 1 {
+				side = Sell
+			}
+			id := fmt.Sprintf("order%d", i)
+			pair := []string{"BTC-USD", "ETH-USD"}[i%4/2]
+			err := engine.AddOrderCtx(context.Background(), pair, Order{ID: id, Side: side, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+			if err == nil {
+				mutex.Lock()
+				accepted = append(accepted, id)
+				mutex.Unlock()
+			} else if err != ErrEngineClosed {
+				t.Errorf("Expected nil or ErrEngineClosed, got %v", err)
+			}
+		}(i)
+		if i == 100 {
+			go engine.Close()
+		}
+	}
+	submitters.Wait()
+	consumers.Wait()
+
+	if len(accepted) == 0 {
+		t.Fatal("Expected some orders to be accepted before Close")
+	}
+	for _, id := range accepted {
+		if len(fills[id]) == 0 || fills[id][0].Status != New {
+			t.Errorf("Expected a New fill for accepted order %s, got %+v", id, fills[id])
+		}
+	}
+	if len(fills) != len(accepted) {
+		t.Errorf("Expected fills for the %d accepted orders only, got %d", len(accepted), len(fills))
+	}
+	filled := 0
+	for _, id := range accepted {
+		if last := fills[id][len(fills[id])-1]; last.Status == Filled {
+			filled++
+		}
+	}
+	if filled != 2*len(trades) {
+		t.Errorf("Expected two filled orders per trade, got %d filled and %d trades", filled, len(trades))
+	}
+
+	if err := engine.AddOrderCtx(context.Background(), "BTC-USD", Order{ID: "late", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)}); err != ErrEngineClosed {
+		t.Errorf("Expected ErrEngineClosed after Close, got %v", err)
+	}
+	engine.AddOrder("BTC-USD", Order{ID: "late", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	if err := engine.Close(); err != ErrEngineClosed {
+		t.Errorf("Expected a second Close to return ErrEngineClosed, got %v", err)
+	}
+	// Ranging terminates only once the stopped broadcaster's stream is closed
+	for range engine.PriceUpdates {
+	}
+}
+
+// TestCloseIdleEngine tests that closing an engine with nothing in flight closes every stream
+func TestCloseIdleEngine(t *testing.T) {
+	engine := NewEngine()
+	engine.AddOrder("BTC-USD", Order{ID: "bid", Side: Buy, Price: decimal.NewFromInt(99), Qty: decimal.NewFromInt(1)})
+	if err := engine.Close(); err != nil {
+		t.Fatalf("Expected Close to succeed, got %v", err)
+	}
+
+	if fill, ok := <-engine.FillStream; !ok || fill.OrderID != "bid" {
+		t.Errorf("Expected the buffered fill to survive Close, got %+v", fill)
+	}
+	if _, ok := <-engine.FillStream; ok {
+		t.Error("Expected FillStream to be closed")
+	}
+	if _, ok := <-engine.TradeStream; ok {
+		t.Error("Expected TradeStream to be closed")
+	}
+	if bids, _, _ := engine.OpenOrderCount("BTC-USD"); bids != 1 {
+		t.Errorf("Expected the book to stay queryable after Close, got %d bids", bids)
+	}
+	engine.StartDepthStreamer(DepthStreamConfig{})
+	engine.StopDepthStreamer()
+}
+
+// countingStore is a Store that counts the snapshots saved to it
+type countingStore struct {
+	*MemoryStore
+	saves atomic.Int64
+}
+
+func (s *countingStore) SaveSnapshot(pair string, snapshot BookSnapshot) error {
+	s.saves.Add(1)
+	return s.MemoryStore.SaveSnapshot(pair, snapshot)
+}
+
+// TestCloseStopsSnapshotter tests that the snapshotter stops saving once the engine is closed
+func TestCloseStopsSnapshotter(t *testing.T) {
+	engine := NewEngine()
+	engine.AddOrder("BTC-USD", Order{ID: "bid", Side: Buy, Price: decimal.NewFromInt(99), Qty: decimal.NewFromInt(1)})
+	store := &countingStore{MemoryStore: NewMemoryStore()}
+	engine.StartSnapshotter(store, time.Millisecond)
+
+	deadline := time.After(500 * time.Millisecond)
+	for store.saves.Load() == 0 {
+		select {
+		case <-deadline:
+			t.Fatal("Expected the snapshotter to save before Close")
+		case <-time.After(time.Millisecond):
+		}
+	}
+	if err := engine.Close(); err != nil {
+		t.Fatalf("Expected Close to succeed, got %v", err)
+	}
+
+	// A save already under way when Close was called may still complete
+	time.Sleep(20 * time.Millisecond)
+	saves := store.saves.Load()
+	time.Sleep(20 * time.Millisecond)
+	if got := store.saves.Load(); got != saves {
+		t.Errorf("Expected no saves after Close, got %d more", got-saves)
+	}
+}
@@ -0,0 +1,68 @@
+package engine
+
+// Stream names an outbound stream in MetricsCollector calls.
+type Stream string
+
+const (
+	StreamTrades       Stream = "trades"        // TradeStream
+	StreamFills        Stream = "fills"         // FillStream
+	StreamPriceUpdates Stream = "price_updates" // PriceUpdates
+	StreamDepthUpdates Stream = "depth_updates" // DepthUpdates
+	StreamOrderEvents  Stream = "order_events"  // OrderEvents
+	StreamHaltEvents   Stream = "halt_events"   // HaltEvents
+)
+
+// MetricsCollector receives the engine's counters and gauges as they change,
+// so they can be exported to a monitoring system such as Prometheus without
+// the engine depending on its client library.
+//
+// Methods are called synchronously from the matching shards and the
+// broadcasters, so implementations must be safe for concurrent use and
+// should return quickly.
+type MetricsCollector interface {
+	// IncOrders counts an order submitted for pair, whether it was entered
+	// or rejected.
+	IncOrders(pair string)
+	// IncTrades counts a trade executed in pair.
+	IncTrades(pair string)
+	// IncFills counts a fill event emitted for an order in pair.
+	IncFills(pair string)
+	// AddDropped counts n events discarded from a full stream.
+	AddDropped(stream Stream, n uint64)
+	// SetRestingOrders reports the number of orders resting on each side of
+	// pair after every operation on it.
+	SetRestingOrders(pair string, bids, asks int)
+}
+
+// NopCollector is a MetricsCollector that discards everything. It is the
+// engine default.
+type NopCollector struct{}
+
+// IncOrders does nothing.
+func (NopCollector) IncOrders(string) {}
+
+// IncTrades does nothing.
+func (NopCollector) IncTrades(string) {}
+
+// IncFills does nothing.
+func (NopCollector) IncFills(string) {}
+
+// AddDropped does nothing.
+func (NopCollector) AddDropped(Stream, uint64) {}
+
+// SetRestingOrders does nothing.
+func (NopCollector) SetRestingOrders(string, int, int) {}
+
+// WithMetricsCollector makes the engine report its counters and gauges to c.
+func WithMetricsCollector(c MetricsCollector) Option {
+	return func(e *Engine) {
+		e.collector = c
+	}
+}
+
+// dropped reports n events discarded from stream to the collector.
+func (e *Engine) dropped(stream Stream, n uint64) {
+	if n > 0 {
+		e.collector.AddDropped(stream, n)
+	}
+}
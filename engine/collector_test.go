@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// recordingCollector is a MetricsCollector that records every call
+type recordingCollector struct {
+	mutex   sync.Mutex
+	orders  map[string]int
+	trades  map[string]int
+	fills   map[string]int
+	dropped map[Stream]uint64
+	resting map[string][2]int
+}
+
+func newRecordingCollector() *recordingCollector {
+	return &recordingCollector{
+		orders:  make(map[string]int),
+		trades:  make(map[string]int),
+		fills:   make(map[string]int),
+		dropped: make(map[Stream]uint64),
+		resting: make(map[string][2]int),
+	}
+}
+
+func (c *recordingCollector) IncOrders(pair string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.orders[pair]++
+}
+
+func (c *recordingCollector) IncTrades(pair string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.trades[pair]++
+}
+
+func (c *recordingCollector) IncFills(pair string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.fills[pair]++
+}
+
+func (c *recordingCollector) AddDropped(stream Stream, n uint64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.dropped[stream] += n
+}
+
+func (c *recordingCollector) SetRestingOrders(pair string, bids, asks int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.resting[pair] = [2]int{bids, asks}
+}
+
+// TestMetricsCollectorCounts tests that the collector sees the orders, trades, fills and resting orders of a session
+func TestMetricsCollectorCounts(t *testing.T) {
+	collector := newRecordingCollector()
+	engine := NewEngine(WithMetricsCollector(collector))
+	pair := "BTC-USD"
+
+	engine.AddOrder(pair, Order{ID: "ask1", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	engine.AddOrder(pair, Order{ID: "ask2", Side: Sell, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(1)})
+	engine.AddOrder(pair, Order{ID: "bid1", Side: Buy, Price: decimal.NewFromInt(98), Qty: decimal.NewFromInt(1)})
+	// Sweeps both asks: two trades, each with a maker and a taker fill
+	engine.AddOrder(pair, Order{ID: "buy", Side: Buy, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(2)})
+	engine.AddOrder(pair, Order{ID: "bad", Side: Buy, Price: decimal.NewFromInt(101), Qty: decimal.Zero})
+
+	if collector.orders[pair] != 5 {
+		t.Errorf("Expected 5 orders, got %d", collector.orders[pair])
+	}
+	if collector.trades[pair] != 2 {
+		t.Errorf("Expected 2 trades, got %d", collector.trades[pair])
+	}
+	// Four New acknowledgments, four execution fills and one rejection
+	if collector.fills[pair] != 9 {
+		t.Errorf("Expected 9 fills, got %d", collector.fills[pair])
+	}
+	if resting := collector.resting[pair]; resting != [2]int{1, 0} {
+		t.Errorf("Expected 1 resting bid and no asks, got %v", resting)
+	}
+
+	if err := engine.CancelOrder(pair, "bid1"); err != nil {
+		t.Fatalf("Expected cancel to succeed, got %v", err)
+	}
+	if resting := collector.resting[pair]; resting != [2]int{0, 0} {
+		t.Errorf("Expected no resting orders after the cancel, got %v", resting)
+	}
+	if collector.fills[pair] != 10 {
+		t.Errorf("Expected the Canceled fill to be counted, got %d", collector.fills[pair])
+	}
+}
+
+// TestMetricsCollectorDropped tests that discarded events are reported per stream
+func TestMetricsCollectorDropped(t *testing.T) {
+	collector := newRecordingCollector()
+	engine := NewEngine(WithMetricsCollector(collector))
+	engine.AddOrder("BTC-USD", Order{ID: "bid", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+
+	rounds := cap(engine.PriceUpdates) + 3
+	for i := 0; i < rounds; i++ {
+		engine.broadcastPrices(0, nil, nil)
+	}
+	if n := collector.dropped[StreamPriceUpdates]; n != 3 {
+		t.Errorf("Expected 3 dropped price updates, got %d", n)
+	}
+	if n := collector.dropped[StreamTrades]; n != 0 {
+		t.Errorf("Expected no dropped trades, got %d", n)
+	}
+}
@@ -0,0 +1,195 @@
+package engine
+
+import "github.com/shopspring/decimal"
+
+// Rounding selects how prices are rounded to the tick size.
+type Rounding string
+
+const (
+	// RoundNearest rounds to the nearest tick, halves away from zero. An
+	// empty Rounding is treated as RoundNearest.
+	RoundNearest Rounding = "nearest"
+	// RoundDown rounds toward negative infinity.
+	RoundDown Rounding = "down"
+	// RoundUp rounds toward positive infinity.
+	RoundUp Rounding = "up"
+)
+
+// ExecutionPrice selects the price crossing orders trade at.
+type ExecutionPrice string
+
+const (
+	// ExecuteAtMaker trades at the resting order's price, so an incoming
+	// order that crosses by more than it needs to keeps the difference as
+	// price improvement. An empty ExecutionPrice is treated as
+	// ExecuteAtMaker.
+	ExecuteAtMaker ExecutionPrice = "maker"
+	// ExecuteAtMid trades at the midpoint of the incoming and resting
+	// orders' prices, rounded to the tick size, giving both sides price
+	// improvement.
+	ExecuteAtMid ExecutionPrice = "mid"
+	// ExecuteAtTaker trades at the incoming order's limit price, the worst
+	// price the aggressor accepted, giving it no price improvement. It suits
+	// simulations that want a worst-case fill; incoming orders without a
+	// limit price still trade at the resting order's price.
+	ExecuteAtTaker ExecutionPrice = "taker"
+)
+
+// PriceImprovement names the ExecutionPrice choices by which side a crossing
+// order trades at the price of, and so who forgoes the price improvement.
+type PriceImprovement = ExecutionPrice
+
+const (
+	// MakerPrice is ExecuteAtMaker, the default: an aggressive buy at 2
+	// against an ask at 1 fills at 1, and the taker keeps the improvement.
+	MakerPrice PriceImprovement = ExecuteAtMaker
+	// TakerPrice is ExecuteAtTaker: the same buy fills at its limit of 2.
+	TakerPrice PriceImprovement = ExecuteAtTaker
+	// WorstCasePrice is TakerPrice, filling every aggressor at the worst
+	// price it accepted, for simulations that want pessimistic fills.
+	WorstCasePrice PriceImprovement = TakerPrice
+)
+
+// PairConfig holds per-pair trading rules enforced when orders are accepted.
+// Zero values disable the corresponding rule, so an unconfigured pair accepts
+// every order unchanged.
+type PairConfig struct {
+	MinQty      decimal.Decimal `json:"min_qty"`      // Smallest accepted order quantity
+	MinNotional decimal.Decimal `json:"min_notional"` // Smallest accepted price * quantity
+
+	TickSize     decimal.Decimal `json:"tick_size"`               // Price increment orders are rounded to
+	StepSize     decimal.Decimal `json:"step_size"`               // Quantity increment orders are rounded down to
+	BuyRounding  Rounding        `json:"buy_rounding,omitempty"`  // How buy prices are rounded to the tick
+	SellRounding Rounding        `json:"sell_rounding,omitempty"` // How sell prices are rounded to the tick
+
+	LULD           LULDConfig     `json:"luld"`                      // Circuit breaker that halts the pair on extreme moves
+	ExecutionPrice ExecutionPrice `json:"execution_price,omitempty"` // Price crossing orders trade at, the maker's price if empty
+	STP            STPMode        `json:"stp,omitempty"`             // Self-trade prevention, the engine's default if empty
+
+	ReferencePrice       ReferencePriceSource   `json:"reference_price,omitempty"` // Price stops, market-if-touched orders and the LULD breaker follow, the last trade if empty
+	CustomReferencePrice func() decimal.Decimal `json:"-"`                         // Reference price read under ReferenceCustom
+
+	MakerFee decimal.Decimal `json:"maker_fee"` // Fraction of the notional charged to the resting order, negative for a rebate
+	TakerFee decimal.Decimal `json:"taker_fee"` // Fraction of the notional charged to the incoming order
+}
+
+// fee returns the fee charged for executing qty at price, on the maker's
+// side of the trade if maker is set and the taker's otherwise.
+func (c PairConfig) fee(qty, price decimal.Decimal, maker bool) decimal.Decimal {
+	rate := c.TakerFee
+	if maker {
+		rate = c.MakerFee
+	}
+	if rate.IsZero() {
+		return decimal.Zero
+	}
+	return qty.Mul(price).Mul(rate)
+}
+
+// round returns order with its price rounded to the tick size in the
+// direction configured for its side and its quantity rounded down to the step
+// size, so an order is never filled for more than was requested.
+func (c PairConfig) round(order Order) Order {
+	mode := c.SellRounding
+	if order.Side == Buy {
+		mode = c.BuyRounding
+	}
+	order.Price = roundTo(order.Price, c.TickSize, mode)
+	order.Qty = roundTo(order.Qty, c.StepSize, RoundDown)
+	return order
+}
+
+// roundTo rounds value to a multiple of increment. A zero increment leaves
+// value unchanged.
+func roundTo(value, increment decimal.Decimal, mode Rounding) decimal.Decimal {
+	if increment.IsZero() {
+		return value
+	}
+
+	steps := value.Div(increment)
+	switch mode {
+	case RoundDown:
+		steps = steps.Floor()
+	case RoundUp:
+		steps = steps.Ceil()
+	default:
+		steps = steps.Round(0)
+	}
+	return steps.Mul(increment)
+}
+
+// check returns the reason order violates c, or an empty reason if it is
+// acceptable. With a StepSize configured, an order whose quantity rounded down
+// to nothing is invalid. Orders without a price, such as unpriced trailing
+// stops, are not subject to the notional check.
+func (c PairConfig) check(order Order) RejectReason {
+	if !c.StepSize.IsZero() && !order.Qty.IsPositive() {
+		return RejectInvalidQty
+	}
+	if !c.MinQty.IsZero() && order.Qty.LessThan(c.MinQty) {
+		return RejectBelowMinQty
+	}
+	if !c.MinNotional.IsZero() && !order.Price.IsZero() && order.Price.Mul(order.Qty).LessThan(c.MinNotional) {
+		return RejectBelowMinNotional
+	}
+	return ""
+}
+
+// onGrid returns RejectOffTick if the price of order is not a multiple of the
+// tick size or RejectOffStep if its quantity is not a multiple of the step
+// size, and an empty reason otherwise. Orders without a price and pegged
+// orders, whose price the book derives, are not subject to the tick check.
+// Intake rounds orders onto the grid; amendments, which change an order the
+// trader already sees resting, are checked against it instead.
+func (c PairConfig) onGrid(order Order) RejectReason {
+	if !c.TickSize.IsZero() && order.Type != Pegged && !order.Price.IsZero() && !order.Price.Mod(c.TickSize).IsZero() {
+		return RejectOffTick
+	}
+	if !c.StepSize.IsZero() && !order.Qty.Mod(c.StepSize).IsZero() {
+		return RejectOffStep
+	}
+	return ""
+}
+
+// Config returns the trading rules of the book.
+func (ob *OrderBook) Config() PairConfig {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+	return ob.config
+}
+
+// SetConfig replaces the trading rules of the book. Resting orders are not
+// re-checked.
+func (ob *OrderBook) SetConfig(c PairConfig) {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	retick := !c.TickSize.Equal(ob.config.TickSize)
+	ob.config = c
+	if retick {
+		ob.retick()
+	}
+}
+
+// SetPairConfig sets the trading rules for the specified trading pair. The
+// rules apply to the pair's existing book and to any book created for the
+// pair later.
+func (e *Engine) SetPairConfig(pair string, c PairConfig) {
+	e.mutex.Lock()
+	e.pairConfigs[pair] = c
+	book, exists := e.books[pair]
+	e.mutex.Unlock()
+
+	if exists {
+		book.SetConfig(c)
+	}
+}
+
+// PairConfig returns the trading rules configured for the specified trading
+// pair. The ok result is false if the pair has not been configured.
+func (e *Engine) PairConfig(pair string) (PairConfig, bool) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	c, ok := e.pairConfigs[pair]
+	return c, ok
+}
@@ -0,0 +1,203 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// TestMinQtyAndNotional tests that orders below a pair's minimums are rejected with a reason
+func TestMinQtyAndNotional(t *testing.T) {
+	engine := NewEngine()
+	pair := "BTC-USD"
+	engine.SetPairConfig(pair, PairConfig{MinQty: decimal.NewFromFloat(0.01), MinNotional: decimal.NewFromInt(10)})
+
+	tests := []struct {
+		name     string
+		price    float64
+		qty      float64
+		expected FillStatus
+		reason   RejectReason
+	}{
+		{"below min qty", 50000, 0.001, Rejected, RejectBelowMinQty},
+		{"below min notional", 100, 0.05, Rejected, RejectBelowMinNotional},
+		{"passes both", 100, 0.5, New, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine.AddOrder(pair, Order{ID: tt.name, Side: Buy, Price: decimal.NewFromFloat(tt.price), Qty: decimal.NewFromFloat(tt.qty)})
+			fill := <-engine.FillStream
+			if fill.Status != tt.expected || fill.Reason != tt.reason {
+				t.Errorf("Expected %s with reason %q, got %s with reason %q", tt.expected, tt.reason, fill.Status, fill.Reason)
+			}
+		})
+	}
+
+	if bids, _, _ := engine.OpenOrderCount(pair); bids != 1 {
+		t.Errorf("Expected only the passing order to rest, got %d bids", bids)
+	}
+}
+
+// TestUnconfiguredPairSkipsMinimums tests that pairs without a config accept dust orders
+func TestUnconfiguredPairSkipsMinimums(t *testing.T) {
+	engine := NewEngine()
+	engine.SetPairConfig("BTC-USD", PairConfig{MinQty: decimal.NewFromInt(1)})
+
+	engine.AddOrder("ETH-USD", Order{ID: "dust", Side: Buy, Price: decimal.NewFromFloat(0.01), Qty: decimal.NewFromFloat(0.0001)})
+	if fill := <-engine.FillStream; fill.Status != New {
+		t.Errorf("Expected dust order on unconfigured pair to be accepted, got %s", fill.Status)
+	}
+
+	if _, ok := engine.PairConfig("ETH-USD"); ok {
+		t.Error("Expected no config for ETH-USD")
+	}
+	if c, ok := engine.PairConfig("BTC-USD"); !ok || !c.MinQty.Equal(decimal.NewFromInt(1)) {
+		t.Errorf("Expected BTC-USD config with MinQty 1, got %+v", c)
+	}
+}
+
+// TestTickAndStepRounding tests that prices and quantities are rounded to the pair's precision
+func TestTickAndStepRounding(t *testing.T) {
+	engine := NewEngine()
+	pair := "BTC-USD"
+	engine.SetPairConfig(pair, PairConfig{
+		TickSize:     decimal.NewFromFloat(0.5),
+		StepSize:     decimal.NewFromFloat(0.001),
+		BuyRounding:  RoundDown,
+		SellRounding: RoundUp,
+	})
+
+	engine.AddOrder(pair, Order{ID: "sell1", Side: Sell, Price: decimal.NewFromFloat(100.1), Qty: decimal.RequireFromString("0.123456789")})
+	fill := <-engine.FillStream
+	if !fill.OriginalQty.Equal(decimal.NewFromFloat(0.123)) {
+		t.Errorf("Expected quantity rounded down to 0.123, got %s", fill.OriginalQty)
+	}
+	if !fill.Price.Equal(decimal.NewFromFloat(100.5)) {
+		t.Errorf("Expected sell price rounded up to 100.5, got %s", fill.Price)
+	}
+
+	engine.AddOrder(pair, Order{ID: "buy1", Side: Buy, Price: decimal.NewFromFloat(100.9), Qty: decimal.RequireFromString("0.5")})
+	if trade := <-engine.TradeStream; !trade.Qty.Equal(decimal.NewFromFloat(0.123)) {
+		t.Errorf("Expected trade for the rounded quantity 0.123, got %s", trade.Qty)
+	}
+	drainFills(engine)
+	if bid, _, _, _, _ := engine.TopOfBook(pair); !bid.Equal(decimal.NewFromFloat(100.5)) {
+		t.Errorf("Expected buy price rounded down to 100.5, got %s", bid)
+	}
+
+	engine.AddOrder(pair, Order{ID: "dust", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.RequireFromString("0.0004")})
+	if fill := <-engine.FillStream; fill.Status != Rejected || fill.Reason != RejectInvalidQty {
+		t.Errorf("Expected quantity rounding to zero to be rejected, got %s %q", fill.Status, fill.Reason)
+	}
+}
+
+// TestRoundTo tests each rounding mode
+func TestRoundTo(t *testing.T) {
+	tick := decimal.NewFromFloat(0.25)
+	value := decimal.NewFromFloat(10.3)
+	tests := []struct {
+		mode     Rounding
+		expected float64
+	}{
+		{RoundNearest, 10.25},
+		{"", 10.25},
+		{RoundDown, 10.25},
+		{RoundUp, 10.5},
+	}
+	for _, tt := range tests {
+		if got := roundTo(value, tick, tt.mode); !got.Equal(decimal.NewFromFloat(tt.expected)) {
+			t.Errorf("Expected %q rounding to give %v, got %s", tt.mode, tt.expected, got)
+		}
+	}
+	if got := roundTo(value, decimal.Zero, RoundUp); !got.Equal(value) {
+		t.Errorf("Expected zero increment to leave value unchanged, got %s", got)
+	}
+}
+
+// TestExecuteAtMid tests that mid mode executes crossing orders at the midpoint of their prices
+func TestExecuteAtMid(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	ob.SetConfig(PairConfig{ExecutionPrice: ExecuteAtMid})
+	ob.Execute(Order{ID: "sell", Side: Sell, Price: decimal.NewFromInt(1), Qty: decimal.NewFromInt(1)})
+
+	res := ob.Execute(Order{ID: "buy", Side: Buy, Price: decimal.NewFromInt(2), Qty: decimal.NewFromInt(1)})
+	mid := decimal.RequireFromString("1.5")
+	if len(res.Trades) != 1 || !res.Trades[0].Price.Equal(mid) {
+		t.Fatalf("Expected a trade at 1.5, got %+v", res.Trades)
+	}
+	for _, fill := range res.Fills {
+		if fill.Status != New && !fill.FillPrice.Equal(mid) {
+			t.Errorf("Expected %s to fill at 1.5, got %s", fill.OrderID, fill.FillPrice)
+		}
+	}
+
+	// With a tick size the midpoint is rounded to the tick
+	ob.SetConfig(PairConfig{ExecutionPrice: ExecuteAtMid, TickSize: decimal.NewFromInt(1)})
+	ob.Execute(Order{ID: "sell2", Side: Sell, Price: decimal.NewFromInt(10), Qty: decimal.NewFromInt(1)})
+	res = ob.Execute(Order{ID: "buy2", Side: Buy, Price: decimal.NewFromInt(13), Qty: decimal.NewFromInt(1)})
+	if len(res.Trades) != 1 || !res.Trades[0].Price.Equal(decimal.NewFromInt(12)) {
+		t.Errorf("Expected a trade at 11.5 rounded to 12, got %+v", res.Trades)
+	}
+}
+
+// TestExecuteAtMakerImprovesTaker tests that by default an aggressive buy at 2 against an ask at 1 fills at 1
+func TestExecuteAtMakerImprovesTaker(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	ob.Execute(Order{ID: "sell", Side: Sell, Price: decimal.NewFromInt(1), Qty: decimal.NewFromInt(1)})
+
+	res := ob.Execute(Order{ID: "buy", Side: Buy, Price: decimal.NewFromInt(2), Qty: decimal.NewFromInt(1)})
+	if len(res.Trades) != 1 || !res.Trades[0].Price.Equal(decimal.NewFromInt(1)) {
+		t.Fatalf("Expected a trade at the maker's price of 1, got %+v", res.Trades)
+	}
+	fill := res.Fills[len(res.Fills)-1]
+	if fill.OrderID != "buy" || !fill.FillPrice.Equal(decimal.NewFromInt(1)) || !fill.AvgFillPrice.Equal(decimal.NewFromInt(1)) {
+		t.Errorf("Expected the buy limited at 2 to fill at 1, got %+v", fill)
+	}
+}
+
+// TestExecuteAtTaker tests that taker mode fills at the aggressor's limit price
+func TestExecuteAtTaker(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	ob.SetConfig(PairConfig{ExecutionPrice: ExecuteAtTaker})
+	ob.Execute(Order{ID: "sell", Side: Sell, Price: decimal.NewFromInt(1), Qty: decimal.NewFromInt(1)})
+
+	res := ob.Execute(Order{ID: "buy", Side: Buy, Price: decimal.NewFromInt(2), Qty: decimal.NewFromInt(1)})
+	if len(res.Trades) != 1 || !res.Trades[0].Price.Equal(decimal.NewFromInt(2)) {
+		t.Fatalf("Expected a trade at the taker's limit of 2, got %+v", res.Trades)
+	}
+	for _, fill := range res.Fills {
+		if fill.Status != New && !fill.FillPrice.Equal(decimal.NewFromInt(2)) {
+			t.Errorf("Expected %s to fill at 2, got %s", fill.OrderID, fill.FillPrice)
+		}
+	}
+
+	// A market order has no limit and still trades at the maker's price
+	ob.Execute(Order{ID: "sell2", Side: Sell, Price: decimal.NewFromInt(3), Qty: decimal.NewFromInt(1)})
+	res = ob.Execute(Order{ID: "market", Side: Buy, Type: Market, Qty: decimal.NewFromInt(1)})
+	if len(res.Trades) != 1 || !res.Trades[0].Price.Equal(decimal.NewFromInt(3)) {
+		t.Errorf("Expected a market order to trade at 3, got %+v", res.Trades)
+	}
+}
+
+// TestPriceImprovement tests that each PriceImprovement mode fills an aggressive buy at 2 against an ask at 1 at the expected price
+func TestPriceImprovement(t *testing.T) {
+	tests := []struct {
+		mode  PriceImprovement
+		price int64
+	}{
+		{MakerPrice, 1},
+		{TakerPrice, 2},
+		{WorstCasePrice, 2},
+	}
+	for _, tt := range tests {
+		ob := NewOrderBook("BTC-USD")
+		ob.SetConfig(PairConfig{ExecutionPrice: tt.mode})
+		ob.Execute(Order{ID: "sell", Side: Sell, Price: decimal.NewFromInt(1), Qty: decimal.NewFromInt(1)})
+
+		res := ob.Execute(Order{ID: "buy", Side: Buy, Price: decimal.NewFromInt(2), Qty: decimal.NewFromInt(1)})
+		if len(res.Trades) != 1 || !res.Trades[0].Price.Equal(decimal.NewFromInt(tt.price)) {
+			t.Errorf("Expected %s to trade at %d, got %+v", tt.mode, tt.price, res.Trades)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package engine
+
+import "github.com/shopspring/decimal"
+
+// CrossedBook reports a book found crossed, with its best bid at or above
+// its best ask, after an order or operation on it had been processed. A
+// correct book is never left crossed by matching, so this points at a bug or
+// at crossed orders loaded with LoadResting or Restore.
+type CrossedBook struct {
+	Pair      string          `json:"pair"`      // Trading pair identifier
+	BestBid   decimal.Decimal `json:"best_bid"`  // Best bid, not counting all-or-none orders
+	BestAsk   decimal.Decimal `json:"best_ask"`  // Best ask, not counting all-or-none orders
+	Uncrossed bool            `json:"uncrossed"` // Whether the engine went on to uncross the book
+	Timestamp int64           `json:"timestamp"` // Unix timestamp of the check
+}
+
+// WithCrossCheck makes the engine check after every order and operation that
+// the book it touched is not crossed. A crossed book is counted in
+// Metrics().CrossedBooks and reported to the listeners registered with
+// OnCrossedBook; with uncross set the engine then matches the crossed orders
+// as Uncross does, unless the pair is halted, and publishes the result like
+// any other. The check costs O(1) per job unless all-or-none orders rest at
+// the top of the book.
+func WithCrossCheck(uncross bool) Option {
+	return func(e *Engine) {
+		e.crossCheck = true
+		e.uncrossCrossed = uncross
+	}
+}
+
+// OnCrossedBook registers fn to be called whenever WithCrossCheck finds a
+// book crossed. Listeners run synchronously on the matching goroutine, before
+// any uncross, so they should return quickly.
+func (e *Engine) OnCrossedBook(fn func(CrossedBook)) {
+	e.crossListeners.add(fn)
+}
+
+// crossed returns the best bid and best ask of the book, disregarding
+// all-or-none orders, and reports whether the bid is at or above the ask.
+func (ob *OrderBook) crossed() (bid, ask decimal.Decimal, ok bool) {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+	return ob.crossedPrices()
+}
+
+// crossedPrices is crossed for a caller that holds ob.mutex. All-or-none
+// orders may rest crossed while they cannot be filled completely, so only
+// the other orders have to be uncrossed.
+func (ob *OrderBook) crossedPrices() (bid, ask decimal.Decimal, ok bool) {
+	bid, hasBid := bestMatchable(ob.bids.orderHeap, decimal.Decimal.GreaterThan)
+	ask, hasAsk := bestMatchable(ob.asks.orderHeap, decimal.Decimal.LessThan)
+	return bid, ask, hasBid && hasAsk && bid.GreaterThanOrEqual(ask)
+}
+
+// checkCross reports book if a job left it crossed and, if configured,
+// uncrosses it and publishes the result.
+func (e *Engine) checkCross(book *OrderBook, res *MatchResult) {
+	if !e.reportCross(book) {
+		return
+	}
+	res.reset()
+	book.uncrossInto(res)
+	e.settle(book, res)
+	e.publish(res)
+}
+
+// reportCross counts and reports book if the cross check is enabled and
+// finds it crossed, and reports whether the book should then be uncrossed.
+func (e *Engine) reportCross(book *OrderBook) bool {
+	if !e.crossCheck {
+		return false
+	}
+	bid, ask, crossed := book.crossed()
+	if !crossed {
+		return false
+	}
+
+	// A halted pair must not trade, so it is only reported.
+	uncross := e.uncrossCrossed && !book.Halted()
+	e.crossedBooks.Add(1)
+	e.crossListeners.notify(CrossedBook{
+		Pair:      book.Pair,
+		BestBid:   bid,
+		BestAsk:   ask,
+		Uncrossed: uncross,
+		Timestamp: e.clock.Now().Unix(),
+	})
+	return uncross
+}
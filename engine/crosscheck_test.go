@@ -0,0 +1,71 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// TestCrossCheck tests that a crossed book is detected after the next job, and uncrossed only when configured
+func TestCrossCheck(t *testing.T) {
+	for _, uncross := range []bool{false, true} {
+		engine := NewEngine(WithCrossCheck(uncross))
+		pair := "BTC-USD"
+		var reports []CrossedBook
+		engine.OnCrossedBook(func(c CrossedBook) { reports = append(reports, c) })
+
+		// Loading resting orders skips matching, so it can leave the book crossed
+		engine.getOrCreateBook(pair).LoadResting([]Order{
+			{ID: "bid", Side: Buy, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(1)},
+			{ID: "ask", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)},
+		})
+		engine.AddOrder(pair, Order{ID: "far", Side: Buy, Price: decimal.NewFromInt(50), Qty: decimal.NewFromInt(1)})
+
+		if len(reports) != 1 {
+			t.Fatalf("Expected one crossed book report with uncross=%v, got %+v", uncross, reports)
+		}
+		report := reports[0]
+		if report.Pair != pair || !report.BestBid.Equal(decimal.NewFromInt(101)) || !report.BestAsk.Equal(decimal.NewFromInt(100)) || report.Uncrossed != uncross {
+			t.Errorf("Expected a report of 101 over 100 with uncrossed=%v, got %+v", uncross, report)
+		}
+		if n := engine.Metrics().CrossedBooks; n != 1 {
+			t.Errorf("Expected 1 crossed book counted, got %d", n)
+		}
+
+		err := engine.getOrCreateBook(pair).Validate()
+		if uncross && err != nil {
+			t.Errorf("Expected the book uncrossed, got %v", err)
+		}
+		if !uncross && err == nil {
+			t.Error("Expected the book left crossed")
+		}
+		// Uncrossing trades the two orders, and the book stays clean afterwards
+		wantTrades, wantReports := 0, 2
+		if uncross {
+			wantTrades, wantReports = 1, 1
+		}
+		if trades := len(engine.TradeStream); trades != wantTrades {
+			t.Errorf("Expected uncross=%v to publish its trade only when enabled, got %d trades", uncross, trades)
+		}
+
+		engine.AddOrder(pair, Order{ID: "far2", Side: Sell, Price: decimal.NewFromInt(200), Qty: decimal.NewFromInt(1)})
+		if len(reports) != wantReports {
+			t.Errorf("Expected %d reports once the next order is processed, got %d", wantReports, len(reports))
+		}
+	}
+}
+
+// TestCrossCheckDisabled tests that a crossed book goes unreported without the check
+func TestCrossCheckDisabled(t *testing.T) {
+	engine := NewEngine()
+	reported := false
+	engine.OnCrossedBook(func(CrossedBook) { reported = true })
+	engine.getOrCreateBook("BTC-USD").LoadResting([]Order{
+		{ID: "bid", Side: Buy, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(1)},
+		{ID: "ask", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)},
+	})
+	engine.AddOrder("BTC-USD", Order{ID: "far", Side: Buy, Price: decimal.NewFromInt(50), Qty: decimal.NewFromInt(1)})
+	if reported || engine.Metrics().CrossedBooks != 0 {
+		t.Error("Expected no cross check without WithCrossCheck")
+	}
+}
@@ -1,8 +1,13 @@
 package engine
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"runtime"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/shopspring/decimal"
@@ -26,8 +31,8 @@ import (
 //	engine := NewEngine()
 //
 //	// Start real-time data streams
-//	engine.StartPriceBroadcaster()
-//	engine.StartDepthStreamer(10)
+//	engine.StartPriceBroadcaster(PriceBroadcastConfig{})
+//	engine.StartDepthStreamer(DepthStreamConfig{Depth: 10})
 //
 //	// Listen for trades
 //	go func() {
@@ -46,12 +51,22 @@ import (
 //	}
 //	engine.AddOrder("BTC-USD", buyOrder)
 
+var (
+	// ErrPairNotFound is returned when an operation targets a trading pair
+	// that has no order book.
+	ErrPairNotFound = errors.New("engine: pair not found")
+
+	// ErrOrderNotFound is returned when an operation targets an order that is
+	// not resting in the book.
+	ErrOrderNotFound = errors.New("engine: order not found")
+)
+
 // TradeStats holds aggregate trading statistics for a trading pair.
 // It tracks cumulative trading activity including total volume, value, and trade count.
 type TradeStats struct {
-	TotalQty   decimal.Decimal // Cumulative quantity of all trades
-	TotalValue decimal.Decimal // Cumulative value of all trades (qty * price)
-	TradeCount int64           // Total number of trades executed
+	TotalQty   decimal.Decimal `json:"total_qty"`   // Cumulative quantity of all trades
+	TotalValue decimal.Decimal `json:"total_value"` // Cumulative value of all trades (qty * price)
+	TradeCount int64           `json:"trade_count"` // Total number of trades executed
 }
 
 // Engine is the core trading engine that manages multiple order books and provides
@@ -63,13 +78,72 @@ type TradeStats struct {
 // real-time data feeds through Go channels.
 type Engine struct {
 	books        map[string]*OrderBook  // Order books indexed by trading pair
-	mutex        sync.Mutex             // Protects concurrent access to engine state
+	mutex        sync.RWMutex           // Protects engine state; readers such as the broadcasters share it
 	TradeStream  chan Trade             // Stream of executed trades
 	PriceUpdates chan PriceUpdate       // Stream of best bid/ask price updates
 	DepthUpdates chan DepthUpdate       // Stream of order book depth snapshots
 	FillStream   chan OrderFill         // Stream of order fill events
+	OrderEvents  chan OrderEvent        // Stream of level-3 per-order book changes
+	HaltEvents   chan HaltEvent         // Stream of trading halts and resumptions
 	tradeStats   map[string]*TradeStats // Trading statistics by pair
 	tradeCounter int64                  // Global trade counter for unique IDs
+	orderCounter atomic.Uint64          // Last server-generated order ID
+	shards       []*shard               // Matching workers, each owning a subset of pairs
+	shardCount   int                    // Number of matching workers
+	journal      Journal                // Write-ahead log of orders, trades and cancels
+	collector    MetricsCollector       // Receives counters and gauges for export
+	clock        Clock                  // Source of time for timestamps
+	session      Session                // Trading session that Day orders expire with
+	pairConfigs  map[string]PairConfig  // Trading rules by pair
+
+	checkInvariants bool         // Validate books after every match
+	limiter         *rateLimiter // Per-account order rate limit, nil if unlimited
+	policy          MatchPolicy  // Matching policy for new books, PriceTimePolicy if nil
+	replaceMissing  bool         // Enter ReplaceOrder's new order even if the old one is gone
+	uncrossOnResume bool         // Match crossed resting orders when a pair resumes
+	crossCheck      bool         // Check books for crossing after every job
+	uncrossCrossed  bool         // Match crossed resting orders the cross check finds
+	stp             STPMode      // Self-trade prevention for pairs whose config sets none
+	maxResting      int          // Most orders resting per book, unbounded if zero
+	maxLevels       int          // Most price levels retained per side, unbounded if zero
+
+	tradeHistory      map[string]*ring[Trade]        // Recent trades by pair
+	tradeHistorySize  int                            // Trades retained per pair
+	fillHistory       map[string]*ring[OrderFill]    // Recent fills by pair
+	fillHistorySize   int                            // Fills retained per pair
+	reports           map[string]*orderReports       // Execution reports by pair
+	reportHistorySize int                            // Finished orders whose reports are retained per pair
+	spreads           map[string]*ring[spreadSample] // Spread samples by pair
+	spreadHistorySize int                            // Spread samples retained per pair
+	rollingStats      map[string]*rollingStats       // Bucketed 24h statistics by pair
+
+	tradeListeners listenerSet[Trade]       // Callbacks registered with OnTrade
+	fillListeners  listenerSet[OrderFill]   // Callbacks registered with OnFill
+	depthListeners listenerSet[DepthUpdate] // Callbacks registered with OnDepth
+	priceListeners listenerSet[PriceUpdate] // Callbacks registered with OnPrice
+	crossListeners listenerSet[CrossedBook] // Callbacks registered with OnCrossedBook
+
+	preTrade  preTradeHooks      // Hooks registered with UsePreTrade
+	postTrade listenerSet[Trade] // Hooks registered with UsePostTrade
+
+	priceLoop loop // Runs the price broadcaster
+	depthLoop loop // Runs the depth streamer
+
+	gate     sync.RWMutex   // Read-held while a submission is admitted; Close takes it to stop intake
+	closed   bool           // Set by Close; guarded by gate
+	closing  chan struct{}  // Closed by Close to stop background goroutines
+	inflight sync.WaitGroup // Submissions admitted but not yet processed
+	workers  sync.WaitGroup // Running shard workers
+
+	tradePolicy      Backpressure  // Behaviour of TradeStream when full
+	fillPolicy       Backpressure  // Behaviour of FillStream when full
+	marketDataPolicy Backpressure  // Behaviour of PriceUpdates, DepthUpdates, OrderEvents and HaltEvents when full
+	drops            dropCounters  // Events discarded per stream
+	selfTrades       atomic.Uint64 // Self-trades prevented across all pairs
+	crossedBooks     atomic.Uint64 // Crossed books found by the cross check
+	counts           throughput    // Orders, trades and fills processed
+
+	fees feeLedger // Fees accrued per account
 }
 
 // NewEngine creates and initializes a new trading engine with default channel capacities.
@@ -80,18 +154,52 @@ type Engine struct {
 //   - PriceUpdates: 100 (moderate capacity for price updates)
 //   - DepthUpdates: 100 (moderate capacity for depth updates)
 //   - FillStream: 1000 (high capacity for fill events)
+//   - OrderEvents: 1000 (level-3 events; dropped when full, detectable as
+//     sequence gaps)
+//   - HaltEvents: 100 (trading halts and resumptions; dropped when full)
+//
+// By default one matching shard is started per available CPU; use WithShards
+// to override.
 //
 // Returns a fully initialized engine ready for trading operations.
-func NewEngine() *Engine {
-	return &Engine{
+func NewEngine(opts ...Option) *Engine {
+	e := &Engine{
 		books:        make(map[string]*OrderBook),
 		TradeStream:  make(chan Trade, 1000),
 		PriceUpdates: make(chan PriceUpdate, 100),
 		DepthUpdates: make(chan DepthUpdate, 100),
 		FillStream:   make(chan OrderFill, 1000),
+		OrderEvents:  make(chan OrderEvent, 1000),
+		HaltEvents:   make(chan HaltEvent, 100),
 		tradeStats:   make(map[string]*TradeStats),
 		tradeCounter: 0,
+		shardCount:   runtime.GOMAXPROCS(0),
+		journal:      NopJournal{},
+		collector:    NopCollector{},
+		clock:        realClock{},
+		pairConfigs:  make(map[string]PairConfig),
+
+		tradeHistory:      make(map[string]*ring[Trade]),
+		tradeHistorySize:  defaultTradeHistorySize,
+		fillHistory:       make(map[string]*ring[OrderFill]),
+		fillHistorySize:   defaultFillHistorySize,
+		reports:           make(map[string]*orderReports),
+		reportHistorySize: defaultReportHistorySize,
+		spreads:           make(map[string]*ring[spreadSample]),
+		spreadHistorySize: defaultSpreadHistorySize,
+		rollingStats:      make(map[string]*rollingStats),
+
+		tradePolicy:      Block,
+		fillPolicy:       Block,
+		marketDataPolicy: DropNewest,
+
+		closing: make(chan struct{}),
 	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	e.startShards()
+	return e
 }
 
 // getOrCreateBook retrieves an existing order book for the specified trading pair
@@ -101,13 +209,30 @@ func NewEngine() *Engine {
 // Parameters:
 //   - pair: Trading pair identifier (e.g., "BTC-USD", "ETH-BTC")
 //
-// Returns the order book for the specified pair, creating it if necessary.
+// Returns the order book for the specified pair, creating it if necessary. The
+// common case of an existing book only takes the read lock, so order intake
+// does not contend with market-data readers.
 func (e *Engine) getOrCreateBook(pair string) *OrderBook {
+	if book, exists := e.book(pair); exists {
+		return book
+	}
+
 	e.mutex.Lock()
 	defer e.mutex.Unlock()
 	book, exists := e.books[pair]
 	if !exists {
 		book = NewOrderBook(pair)
+		book.clock = e.clock
+		book.session = e.session
+		book.config = e.pairConfigs[pair]
+		book.checkInvariants = e.checkInvariants
+		book.recordEvents = true
+		if e.policy != nil {
+			book.policy = e.policy
+		}
+		book.stp = e.stp
+		book.maxResting = e.maxResting
+		book.maxLevels = e.maxLevels
 		e.books[pair] = book
 	}
 	return book
@@ -124,8 +249,13 @@ func (e *Engine) getOrCreateBook(pair string) *OrderBook {
 //   - Trade statistics updates
 //   - Order book maintenance
 //
-// The method operates asynchronously for trade and fill event processing to ensure
-// low-latency order processing.
+// The order is queued on the shard that owns the pair and AddOrder returns once
+// the shard has matched it. Orders for the same pair are processed strictly in
+// submission order, while orders for pairs on different shards match
+// concurrently. By the time AddOrder returns, the order's trade and fill events
+// have been delivered to TradeStream and FillStream. Orders added after Close
+// are discarded. An order without an ID is given a server-generated one; use
+// SubmitOrder to learn it.
 //
 // Parameters:
 //   - pair: Trading pair identifier (e.g., "BTC-USD")
@@ -136,95 +266,383 @@ func (e *Engine) getOrCreateBook(pair string) *OrderBook {
 //   - OrderFill events sent to FillStream channel
 //   - Updated trade statistics
 func (e *Engine) AddOrder(pair string, order Order) {
-	book := e.getOrCreateBook(pair)
-	tradeCh := make(chan Trade, 10)
-	fillCh := make(chan OrderFill, 10)
+	e.assignID(&order)
+	_ = e.submit(pair, order, nil)
+}
+
+// AddOrderCtx is like AddOrder but honors ctx while the order waits to be
+// accepted, for example behind a saturated shard queue. If ctx is canceled or
+// its deadline passes before the order is queued, the order is not added and
+// the context's error is returned. Once accepted, matching always completes.
+// Returns ErrEngineClosed if Close has been called.
+func (e *Engine) AddOrderCtx(ctx context.Context, pair string, order Order) error {
+	e.assignID(&order)
+	return e.submitCtx(ctx, pair, order, nil)
+}
+
+// CancelOrder removes a resting order from the book of the specified trading
+// pair and emits a Canceled fill for it. The cancel is journaled and processed
+// on the pair's shard, so it is ordered with respect to every other operation
+// on the pair. orderID may also be the order's client order ID.
+//
+// Returns ErrPairNotFound if the pair has no order book, ErrOrderNotFound if
+// the order is not resting, or the journal error if the cancel could not be
+// recorded.
+func (e *Engine) CancelOrder(pair, orderID string) error {
+	if _, exists := e.book(pair); !exists {
+		return ErrPairNotFound
+	}
+
+	return e.submit(pair, Order{}, func(book *OrderBook, res *MatchResult) error {
+		if id, ok := book.resolve(orderID); ok {
+			orderID = id
+		}
+		if err := e.journal.AppendCancel(pair, orderID); err != nil {
+			return err
+		}
+		order, ok := book.Cancel(orderID)
+		if !ok {
+			return ErrOrderNotFound
+		}
+		res.Fills = append(res.Fills, canceledFill(pair, order, e.clock.Now().Unix()))
+		return nil
+	})
+}
+
+// CancelAll removes every resting order for the specified trading pair and
+// emits a Canceled fill for each one. The book is flattened atomically on the
+// pair's shard, so no match can interleave with the cancel. Returns the number
+// of orders canceled, which is zero for an unknown pair.
+func (e *Engine) CancelAll(pair string) int {
+	return e.cancelAll(pair, nil)
+}
+
+// CancelAllSide removes every resting order on one side of the specified
+// trading pair and emits a Canceled fill for each one. Returns the number of
+// orders canceled, which is zero for an unknown pair.
+func (e *Engine) CancelAllSide(pair string, side Side) int {
+	return e.cancelAll(pair, &side)
+}
+
+// cancelAll flattens one side of a book, or both if side is nil. Every cancel
+// is journaled before the book is touched; if the journal fails part way,
+// only the orders that were journaled are canceled.
+func (e *Engine) cancelAll(pair string, side *Side) int {
+	if _, exists := e.book(pair); !exists {
+		return 0
+	}
+
+	count := 0
+	_ = e.submit(pair, Order{}, func(book *OrderBook, res *MatchResult) error {
+		err := e.cancelBook(book, side, res)
+		count = len(res.Fills)
+		return err
+	})
+	return count
+}
+
+// cancelBook cancels every resting order on one side of book, or both if side
+// is nil, appending a Canceled fill to res for each. It must run on the
+// pair's shard.
+func (e *Engine) cancelBook(book *OrderBook, side *Side, res *MatchResult) error {
+	now := e.clock.Now().Unix()
+	ids := book.orderIDs(side)
+	for i, id := range ids {
+		if err := e.journal.AppendCancel(book.Pair, id); err != nil {
+			for _, id := range ids[:i] {
+				if order, ok := book.Cancel(id); ok {
+					res.Fills = append(res.Fills, canceledFill(book.Pair, order, now))
+				}
+			}
+			return err
+		}
+	}
+
+	var canceled []Order
+	if side == nil {
+		canceled = book.CancelAll()
+	} else {
+		canceled = book.CancelSide(*side)
+	}
+	for _, order := range canceled {
+		res.Fills = append(res.Fills, canceledFill(book.Pair, order, now))
+	}
+	return nil
+}
+
+// RemoveBook cancels every resting order for the specified trading pair,
+// emitting a Canceled fill for each, and then discards the pair's order book
+// and statistics. The removal runs on the pair's shard, so it cannot interleave
+// with matching; a later order for the pair starts a fresh book.
+//
+// Returns ErrPairNotFound if the pair has no order book, or the journal error
+// if the cancels could not be recorded, in which case the book is kept.
+func (e *Engine) RemoveBook(pair string) error {
+	if _, exists := e.book(pair); !exists {
+		return ErrPairNotFound
+	}
 
-	go func() {
-		for trade := range tradeCh {
-			e.TradeStream <- trade
+	return e.submit(pair, Order{}, func(book *OrderBook, res *MatchResult) error {
+		if err := e.cancelBook(book, nil, res); err != nil {
+			return err
+		}
+
+		e.mutex.Lock()
+		defer e.mutex.Unlock()
+		delete(e.books, pair)
+		delete(e.tradeStats, pair)
+		delete(e.tradeHistory, pair)
+		delete(e.fillHistory, pair)
+		delete(e.reports, pair)
+		delete(e.spreads, pair)
+		delete(e.rollingStats, pair)
+		return nil
+	})
+}
+
+// Reset returns the engine to the state of a freshly created one without
+// reallocating its channels: every order book is discarded together with its
+// trade statistics, its trade, fill and spread history and its execution
+// reports, per-account rate limits are refilled, accrued fees are discarded,
+// the Counters and Metrics are zeroed, and the trade counter behind
+// GetNextTradeID and the sequence of generated order IDs restart.
+//
+// Each book is dropped on its pair's shard, so no match interleaves with the
+// reset, and a Removed order event is published for every resting order so
+// level-3 consumers see the book empty. Options, pair configuration,
+// registered listeners and running broadcasters are unaffected. The journal is
+// not written to; an engine that replays its journal should start a new one
+// after a reset.
+func (e *Engine) Reset() {
+	for _, pair := range e.Pairs() {
+		_ = e.submit(pair, Order{}, func(book *OrderBook, res *MatchResult) error {
+			book.Clear()
 
 			e.mutex.Lock()
-			stats := e.tradeStats[pair]
-			if stats == nil {
-				stats = &TradeStats{}
-				e.tradeStats[pair] = stats
+			defer e.mutex.Unlock()
+			delete(e.books, pair)
+			return nil
+		})
+	}
+
+	e.mutex.Lock()
+	clear(e.tradeStats)
+	clear(e.tradeHistory)
+	clear(e.fillHistory)
+	clear(e.reports)
+	clear(e.spreads)
+	clear(e.rollingStats)
+	e.tradeCounter = 0
+	e.mutex.Unlock()
+
+	if e.limiter != nil {
+		e.limiter.reset()
+	}
+	e.fees.reset()
+	e.counts.reset()
+	e.drops.reset()
+	e.selfTrades.Store(0)
+	e.crossedBooks.Store(0)
+	e.orderCounter.Store(0)
+}
+
+// ClearOptions controls what Engine.ClearBook does besides dropping the
+// resting orders.
+type ClearOptions struct {
+	EmitCancels bool // Emit a Canceled fill for every dropped order
+	ResetStats  bool // Also discard the pair's trade statistics, history and execution reports
+}
+
+// ClearBook drops every resting order and pending trailing stop of the
+// specified trading pair, leaving an empty book that keeps accepting orders.
+// The clear runs on the pair's shard, so no match can interleave with it, and
+// every dropped order is journaled as a cancel. Canceled fills are only
+// emitted if opts.EmitCancels is set, and trade statistics are kept unless
+// opts.ResetStats is set.
+//
+// Returns ErrPairNotFound if the pair has no order book, or the journal error
+// if the cancels could not be recorded, in which case only the orders that
+// were journaled are dropped.
+func (e *Engine) ClearBook(pair string, opts ClearOptions) error {
+	if _, exists := e.book(pair); !exists {
+		return ErrPairNotFound
+	}
+
+	return e.submit(pair, Order{}, func(book *OrderBook, res *MatchResult) error {
+		if opts.EmitCancels {
+			if err := e.cancelBook(book, nil, res); err != nil {
+				return err
+			}
+		} else {
+			ids := book.orderIDs(nil)
+			for i, id := range ids {
+				if err := e.journal.AppendCancel(pair, id); err != nil {
+					for _, id := range ids[:i] {
+						book.Cancel(id)
+					}
+					return err
+				}
 			}
-			stats.TotalQty = stats.TotalQty.Add(trade.Qty)
-			stats.TotalValue = stats.TotalValue.Add(trade.Qty.Mul(trade.Price))
-			stats.TradeCount++
-			e.mutex.Unlock()
+			book.Clear()
 		}
-	}()
 
-	go func() {
-		for fill := range fillCh {
-			e.FillStream <- fill
+		if opts.ResetStats {
+			e.mutex.Lock()
+			defer e.mutex.Unlock()
+			delete(e.tradeStats, pair)
+			delete(e.tradeHistory, pair)
+			delete(e.fillHistory, pair)
+			delete(e.reports, pair)
+			delete(e.spreads, pair)
+			delete(e.rollingStats, pair)
 		}
-	}()
+		return nil
+	})
+}
 
-	originalQty := order.Qty
-	book.Match(order, tradeCh, fillCh, originalQty)
-	close(tradeCh)
-	close(fillCh)
+// book returns the order book for pair if one exists.
+func (e *Engine) book(pair string) (*OrderBook, bool) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	book, exists := e.books[pair]
+	return book, exists
+}
+
+// Pairs returns the identifiers of every trading pair that currently has an
+// order book, sorted in ascending order.
+func (e *Engine) Pairs() []string {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	pairs := make([]string, 0, len(e.books))
+	for pair := range e.books {
+		pairs = append(pairs, pair)
+	}
+	sort.Strings(pairs)
+	return pairs
+}
+
+// recordTrade folds an executed trade into the cumulative statistics, the
+// recent trade history and the rolling 24h statistics of its pair.
+func (e *Engine) recordTrade(trade Trade) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	stats := e.tradeStats[trade.Pair]
+	if stats == nil {
+		stats = &TradeStats{}
+		e.tradeStats[trade.Pair] = stats
+	}
+	stats.TotalQty = stats.TotalQty.Add(trade.Qty)
+	stats.TotalValue = stats.TotalValue.Add(trade.Qty.Mul(trade.Price))
+	stats.TradeCount++
+
+	history := e.tradeHistory[trade.Pair]
+	if history == nil {
+		history = newRing[Trade](e.tradeHistorySize)
+		e.tradeHistory[trade.Pair] = history
+	}
+	history.add(trade)
+
+	rolling := e.rollingStats[trade.Pair]
+	if rolling == nil {
+		rolling = &rollingStats{}
+		e.rollingStats[trade.Pair] = rolling
+	}
+	rolling.add(trade)
+	rolling.expire(trade.Timestamp - int64(rollingWindow/time.Second))
 }
 
 // StartPriceBroadcaster starts a background goroutine that continuously broadcasts
 // price updates for all active trading pairs. The broadcaster sends periodic updates
 // containing best bid/ask prices and average trade prices.
 //
-// Update frequency: 500ms
+// Update frequency: cfg.Interval (500ms by default), overridable per pair
 // Channel: PriceUpdates
 //
 // Price updates include:
-//   - Best bid price (highest buy order)
-//   - Best ask price (lowest sell order)
-//   - Volume-weighted average price (if trades have occurred)
+//   - Best bid price (highest buy order) and the quantity resting at it
+//   - Best ask price (lowest sell order) and the quantity resting at it
+//   - Mid price (if both sides are present)
+//   - Volume-weighted average price (if trades have occurred), over the
+//     recent cfg.VWAPWindow if set
+//   - Timestamp of the snapshot
 //
-// The broadcaster runs indefinitely until the program terminates. If the PriceUpdates
-// channel is full, updates are skipped to prevent blocking.
-func (e *Engine) StartPriceBroadcaster() {
-	go func() {
-		for {
-			var updates []PriceUpdate
-
-			e.mutex.Lock()
-			for pair, book := range e.books {
-				update := PriceUpdate{
-					Pair:    pair,
-					BestBid: decimal.NewFromFloat(book.BestBid()),
-					BestAsk: decimal.NewFromFloat(book.BestAsk()),
-				}
-				stats := e.tradeStats[pair]
-				if stats != nil && !stats.TotalQty.IsZero() {
-					update.AvgPrice = stats.TotalValue.Div(stats.TotalQty)
-				}
-				updates = append(updates, update)
+// Prices and quantities for each pair come from a single TopOfBook snapshot.
+// With cfg.OnChange set, a pair is only sent when its best bid, best ask or
+// average price differs from the last update sent for it, or when
+// cfg.Heartbeat has passed since then.
+//
+// The broadcaster runs until StopPriceBroadcaster is called; calling
+// StartPriceBroadcaster while it is already running has no effect. If the
+// PriceUpdates channel is full, updates are skipped to prevent blocking unless
+// another policy is set with WithBackpressure.
+func (e *Engine) StartPriceBroadcaster(cfg PriceBroadcastConfig) {
+	sched := newSchedule(cfg.Interval, defaultPriceInterval, cfg.Pairs)
+	changes := newPriceChanges(cfg.Heartbeat)
+	e.priceLoop.start(sched.tick(), func(stop <-chan struct{}) {
+		now := time.Now()
+		e.broadcastPrices(cfg.VWAPWindow, func(update PriceUpdate) bool {
+			if !sched.due(update.Pair, now) {
+				return false
 			}
-			e.mutex.Unlock()
+			return !cfg.OnChange || changes.changed(update, now)
+		}, stop)
+	})
+}
 
-			for _, update := range updates {
-				select {
-				case e.PriceUpdates <- update:
-				default:
-					// Skip if channel is full
-				}
-			}
+// StopPriceBroadcaster stops the price broadcaster started by
+// StartPriceBroadcaster and waits for it to exit. It has no effect if the
+// broadcaster is not running.
+func (e *Engine) StopPriceBroadcaster() {
+	e.priceLoop.halt()
+}
+
+// broadcastPrices sends one price update for every active trading pair whose
+// update send accepts, or for every pair if send is nil. AvgPrice averages the
+// trades within window, or all trades if window is zero. A blocking send
+// gives up when stop is closed.
+func (e *Engine) broadcastPrices(window time.Duration, send func(update PriceUpdate) bool, stop <-chan struct{}) {
+	var updates []PriceUpdate
 
-			time.Sleep(500 * time.Millisecond)
+	e.mutex.RLock()
+	for pair, book := range e.books {
+		bid, ask, bidQty, askQty := book.TopOfBook()
+		update := PriceUpdate{
+			Pair:      pair,
+			BestBid:   bid,
+			BestAsk:   ask,
+			BidQty:    bidQty,
+			AskQty:    askQty,
+			Timestamp: e.clock.Now().Unix(),
 		}
-	}()
+		if !bidQty.IsZero() && !askQty.IsZero() {
+			update.Mid = bid.Add(ask).Div(decimal.NewFromInt(2))
+		}
+		update.AvgPrice = e.avgPrice(pair, window, update.Timestamp)
+		if send != nil && !send(update) {
+			continue
+		}
+		updates = append(updates, update)
+	}
+	e.mutex.RUnlock()
+
+	for _, update := range updates {
+		e.dropped(StreamPriceUpdates, deliver(e.PriceUpdates, update, e.marketDataPolicy, &e.drops.prices, stop))
+		e.priceListeners.notify(update)
+	}
 }
 
 // StartDepthStreamer starts a background goroutine that continuously broadcasts
 // order book depth updates for all active trading pairs. The streamer provides
-// real-time snapshots of market depth at the specified number of price levels.
+// real-time snapshots of market depth at the configured number of price levels.
 //
-// Update frequency: 100ms
+// Update frequency: cfg.Interval (100ms by default), overridable per pair
 // Channel: DepthUpdates
 //
 // Parameters:
-//   - depth: Number of price levels to include on each side (bids/asks)
+//   - cfg: Update interval and number of price levels per side (10 by default),
+//     optionally overridden per pair
 //
 // Depth updates include:
 //   - Top N bid levels (buy orders)
@@ -232,43 +650,72 @@ func (e *Engine) StartPriceBroadcaster() {
 //   - Timestamp of the snapshot
 //   - Total trade count for the pair
 //
-// The streamer runs indefinitely until the program terminates. If the DepthUpdates
-// channel is full, updates are skipped to prevent blocking.
-func (e *Engine) StartDepthStreamer(depth int) {
-	go func() {
-		for {
-			var updates []DepthUpdate
+// With cfg.OnChange set, a pair is only sent when its book changed since the
+// last update sent for it, or when cfg.Heartbeat has passed since then, so the
+// streamer's cost follows trading activity rather than the number of pairs.
+//
+// The streamer runs until StopDepthStreamer is called; calling StartDepthStreamer
+// while it is already running has no effect, even with a different config. If the
+// DepthUpdates channel is full, updates are skipped to prevent blocking unless
+// another policy is set with WithBackpressure.
+func (e *Engine) StartDepthStreamer(cfg DepthStreamConfig) {
+	intervals := make(map[string]time.Duration, len(cfg.Pairs))
+	for pair, override := range cfg.Pairs {
+		intervals[pair] = override.Interval
+	}
+	sched := newSchedule(cfg.Interval, defaultDepthInterval, intervals)
+	changes := newDepthChanges(cfg.Heartbeat)
+	e.depthLoop.start(sched.tick(), func(stop <-chan struct{}) {
+		now := time.Now()
+		e.streamDepth(func(pair string, book *OrderBook) (int, bool) {
+			if !sched.due(pair, now) {
+				return 0, false
+			}
+			return cfg.depth(pair), !cfg.OnChange || changes.changed(pair, book, now)
+		}, stop)
+	})
+}
 
-			e.mutex.Lock()
-			for pair, book := range e.books {
-				stats := e.tradeStats[pair]
-				tradeCount := int64(0)
-				if stats != nil {
-					tradeCount = stats.TradeCount
-				}
+// StopDepthStreamer stops the depth streamer started by StartDepthStreamer
+// and waits for it to exit. It has no effect if the streamer is not running.
+func (e *Engine) StopDepthStreamer() {
+	e.depthLoop.halt()
+}
 
-				update := DepthUpdate{
-					Pair:       pair,
-					Bids:       book.GetBidDepth(depth),
-					Asks:       book.GetAskDepth(depth),
-					Timestamp:  time.Now().Unix(),
-					TradeCount: tradeCount,
-				}
-				updates = append(updates, update)
-			}
-			e.mutex.Unlock()
+// streamDepth sends one depth update for every active trading pair that plan
+// marks as due, with the number of levels plan returns for it. plan is asked
+// before the pair's depth is read. A blocking send gives up when stop is
+// closed.
+func (e *Engine) streamDepth(plan func(pair string, book *OrderBook) (depth int, due bool), stop <-chan struct{}) {
+	var updates []DepthUpdate
 
-			for _, update := range updates {
-				select {
-				case e.DepthUpdates <- update:
-				default:
-					// Skip if channel is full
-				}
-			}
+	e.mutex.RLock()
+	for pair, book := range e.books {
+		depth, due := plan(pair, book)
+		if !due {
+			continue
+		}
+		stats := e.tradeStats[pair]
+		tradeCount := int64(0)
+		if stats != nil {
+			tradeCount = stats.TradeCount
+		}
 
-			time.Sleep(100 * time.Millisecond)
+		update := DepthUpdate{
+			Pair:       pair,
+			Bids:       book.GetBidDepth(depth),
+			Asks:       book.GetAskDepth(depth),
+			Timestamp:  e.clock.Now().Unix(),
+			TradeCount: tradeCount,
 		}
-	}()
+		updates = append(updates, update)
+	}
+	e.mutex.RUnlock()
+
+	for _, update := range updates {
+		e.dropped(StreamDepthUpdates, deliver(e.DepthUpdates, update, e.marketDataPolicy, &e.drops.depth, stop))
+		e.depthListeners.notify(update)
+	}
 }
 
 // GetOrderBookDepth returns a snapshot of the current order book depth for the
@@ -287,13 +734,26 @@ func (e *Engine) StartDepthStreamer(depth int) {
 //   - Current ask levels (lowest to highest price)
 //   - Current timestamp
 //   - Total trade count for the pair
+//
+// Use OrderBookDepth to tell a missing pair apart from an empty book without
+// a nil check.
 func (e *Engine) GetOrderBookDepth(pair string, depth int) *DepthUpdate {
-	e.mutex.Lock()
-	defer e.mutex.Unlock()
+	update, _ := e.OrderBookDepth(pair, depth)
+	return update
+}
+
+// OrderBookDepth is like GetOrderBookDepth but reports a missing pair as an
+// error instead of a nil snapshot. A pair whose book exists but is empty
+// yields a valid snapshot with empty, non-nil Bids and Asks.
+//
+// Returns ErrPairNotFound if the pair has no order book.
+func (e *Engine) OrderBookDepth(pair string, depth int) (*DepthUpdate, error) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
 
 	book, exists := e.books[pair]
 	if !exists {
-		return nil
+		return nil, ErrPairNotFound
 	}
 
 	stats := e.tradeStats[pair]
@@ -306,9 +766,210 @@ func (e *Engine) GetOrderBookDepth(pair string, depth int) *DepthUpdate {
 		Pair:       pair,
 		Bids:       book.GetBidDepth(depth),
 		Asks:       book.GetAskDepth(depth),
-		Timestamp:  time.Now().Unix(),
+		Timestamp:  e.clock.Now().Unix(),
 		TradeCount: tradeCount,
+	}, nil
+}
+
+// SnapshotAll returns the depth of every trading pair, up to depth levels per
+// side, as of a single instant. Every book is locked before any is read, so
+// no order is matched on any pair while the snapshot is taken and the books
+// are mutually consistent, unlike calling GetOrderBookDepth pair by pair.
+// Books are locked in pair order under the engine's read lock; matching only
+// ever holds one book lock and never takes the engine lock while holding it,
+// so the snapshot cannot deadlock with it. TradeCount reflects the trades
+// published so far and may lag a match that completed just before the
+// snapshot.
+func (e *Engine) SnapshotAll(depth int) map[string]DepthUpdate {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	pairs := make([]string, 0, len(e.books))
+	for pair := range e.books {
+		pairs = append(pairs, pair)
+	}
+	sort.Strings(pairs)
+	for _, pair := range pairs {
+		e.books[pair].mutex.Lock()
+	}
+	defer func() {
+		for _, pair := range pairs {
+			e.books[pair].mutex.Unlock()
+		}
+	}()
+
+	now := e.clock.Now().Unix()
+	snapshot := make(map[string]DepthUpdate, len(pairs))
+	for _, pair := range pairs {
+		book := e.books[pair]
+		update := DepthUpdate{
+			Pair:      pair,
+			Bids:      book.bidLevels(depth),
+			Asks:      book.askLevels(depth),
+			Timestamp: now,
+		}
+		if stats := e.tradeStats[pair]; stats != nil {
+			update.TradeCount = stats.TradeCount
+		}
+		snapshot[pair] = update
+	}
+	return snapshot
+}
+
+// GetOrderBookDepthNotional is like GetOrderBookDepth but expresses every
+// level in notional terms, with Quantity replaced by price * quantity and
+// CumQuantity by the cumulative notional. Returns nil if the pair doesn't
+// exist.
+func (e *Engine) GetOrderBookDepthNotional(pair string, depth int) *DepthUpdate {
+	update := e.GetOrderBookDepth(pair, depth)
+	if update != nil {
+		inNotional(update.Bids)
+		inNotional(update.Asks)
+	}
+	return update
+}
+
+// GetOrderBookDepthGrouped is like GetOrderBookDepth but buckets each side
+// into price bands of width grouping, as GetBidDepthGrouped and
+// GetAskDepthGrouped do, for zoomable depth charts. depth counts bands rather
+// than price levels. Returns nil if the pair doesn't exist.
+func (e *Engine) GetOrderBookDepthGrouped(pair string, depth int, grouping decimal.Decimal) *DepthUpdate {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	book, exists := e.books[pair]
+	if !exists {
+		return nil
+	}
+
+	tradeCount := int64(0)
+	if stats := e.tradeStats[pair]; stats != nil {
+		tradeCount = stats.TradeCount
+	}
+
+	return &DepthUpdate{
+		Pair:       pair,
+		Bids:       book.GetBidDepthGrouped(depth, grouping),
+		Asks:       book.GetAskDepthGrouped(depth, grouping),
+		Timestamp:  e.clock.Now().Unix(),
+		TradeCount: tradeCount,
+	}
+}
+
+// GetTradeStats returns a copy of the cumulative trade statistics for the
+// specified trading pair. The ok result is false if the pair has never traded.
+func (e *Engine) GetTradeStats(pair string) (TradeStats, bool) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	stats, exists := e.tradeStats[pair]
+	if !exists {
+		return TradeStats{}, false
+	}
+	return *stats, true
+}
+
+// TotalStats returns the cumulative trade statistics of every pair summed
+// together, read under one lock so they describe the same instant. Values are
+// added as they are, whatever the quote currency of each pair. All three are
+// zero if the engine has not traded.
+func (e *Engine) TotalStats() (totalVolume, totalValue decimal.Decimal, totalTrades int64) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	for _, stats := range e.tradeStats {
+		totalVolume = totalVolume.Add(stats.TotalQty)
+		totalValue = totalValue.Add(stats.TotalValue)
+		totalTrades += stats.TradeCount
+	}
+	return totalVolume, totalValue, totalTrades
+}
+
+// ResetTradeStats zeroes the cumulative trade statistics for the specified
+// trading pair. It has no effect if the pair has never traded.
+func (e *Engine) ResetTradeStats(pair string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if stats, exists := e.tradeStats[pair]; exists {
+		*stats = TradeStats{}
+	}
+}
+
+// TopOfBook returns a consistent snapshot of the best bid and ask prices and the
+// aggregate quantity resting at each for the specified trading pair. The ok
+// result is false if no order book exists for the pair.
+func (e *Engine) TopOfBook(pair string) (bid, ask, bidQty, askQty decimal.Decimal, ok bool) {
+	book, exists := e.book(pair)
+	if !exists {
+		return decimal.Zero, decimal.Zero, decimal.Zero, decimal.Zero, false
+	}
+
+	bid, ask, bidQty, askQty = book.TopOfBook()
+	return bid, ask, bidQty, askQty, true
+}
+
+// MidPrice returns the exact midpoint of the best bid and ask prices of the
+// specified trading pair. The ok result is false if the pair has no book or
+// either side of it is empty.
+func (e *Engine) MidPrice(pair string) (decimal.Decimal, bool) {
+	book, exists := e.book(pair)
+	if !exists {
+		return decimal.Zero, false
+	}
+	return book.MidPrice()
+}
+
+// OpenOrderCount returns the number of orders resting on each side of the
+// specified trading pair. The ok result is false if the pair has no book.
+func (e *Engine) OpenOrderCount(pair string) (bids, asks int, ok bool) {
+	book, exists := e.book(pair)
+	if !exists {
+		return 0, 0, false
+	}
+	bids, asks = book.OpenOrderCount()
+	return bids, asks, true
+}
+
+// RestingVolume returns the total quantity resting on each side of the
+// specified trading pair. The ok result is false if the pair has no book.
+func (e *Engine) RestingVolume(pair string) (bidVol, askVol decimal.Decimal, ok bool) {
+	book, exists := e.book(pair)
+	if !exists {
+		return decimal.Zero, decimal.Zero, false
+	}
+	bidVol, askVol = book.RestingVolume()
+	return bidVol, askVol, true
+}
+
+// QueuePosition reports where a resting order in the book of the specified
+// trading pair stands in the time queue at its price: the quantity resting
+// ahead of it at the same price and its 1-based position there. orderID may
+// also be the order's client order ID.
+//
+// Returns ErrPairNotFound if the pair has no order book, or ErrOrderNotFound
+// if the order is not resting.
+func (e *Engine) QueuePosition(pair, orderID string) (ahead decimal.Decimal, position int, err error) {
+	book, exists := e.book(pair)
+	if !exists {
+		return decimal.Zero, 0, ErrPairNotFound
+	}
+	ahead, position, ok := book.QueuePosition(orderID)
+	if !ok {
+		return decimal.Zero, 0, ErrOrderNotFound
+	}
+	return ahead, position, nil
+}
+
+// GetImbalance returns the order-flow imbalance over the top levels price
+// levels of the specified trading pair (see OrderBook.Imbalance). Returns zero
+// if the pair has no book.
+func (e *Engine) GetImbalance(pair string, levels int) decimal.Decimal {
+	book, exists := e.book(pair)
+	if !exists {
+		return decimal.Zero
 	}
+	return book.Imbalance(levels)
 }
 
 // GetNextTradeID generates a unique identifier for trade events. Trade IDs are
@@ -318,7 +979,8 @@ func (e *Engine) GetOrderBookDepth(pair string, depth int) *DepthUpdate {
 //   - Unique trade ID in format "T{number}" (e.g., "T1", "T2", "T123")
 //
 // This method is thread-safe and ensures no duplicate trade IDs are generated
-// even under high concurrency.
+// even under high concurrency. Trades produced by matching carry their own
+// per-pair IDs (see Trade.ID); this generator is independent of them.
 func (e *Engine) GetNextTradeID() string {
 	e.mutex.Lock()
 	defer e.mutex.Unlock()
@@ -1,6 +1,8 @@
 package engine
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"testing"
@@ -183,11 +185,8 @@ func TestTradeStatsUpdate(t *testing.T) {
 	<-engine.TradeStream
 
 	// Check trade statistics
-	engine.mutex.Lock()
-	stats := engine.tradeStats[pair]
-	engine.mutex.Unlock()
-
-	if stats == nil {
+	stats, ok := engine.GetTradeStats(pair)
+	if !ok {
 		t.Fatal("Trade stats should be created")
 	}
 
@@ -324,6 +323,56 @@ func TestConcurrentTradeIDGeneration(t *testing.T) {
 	}
 }
 
+// TestPerPairTradeIDs tests that trade IDs are sequential within each pair
+func TestPerPairTradeIDs(t *testing.T) {
+	engine := NewEngine()
+	for i := 0; i < 3; i++ {
+		for _, pair := range []string{"BTC-USD", "ETH-USD"} {
+			engine.AddOrder(pair, Order{ID: fmt.Sprintf("s%d", i), Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+			engine.AddOrder(pair, Order{ID: fmt.Sprintf("b%d", i), Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+		}
+	}
+
+	for _, pair := range []string{"BTC-USD", "ETH-USD"} {
+		trades := engine.RecentTrades(pair, 10)
+		if len(trades) != 3 {
+			t.Fatalf("Expected 3 trades for %s, got %d", pair, len(trades))
+		}
+		for i, trade := range trades {
+			expected := fmt.Sprintf("%s-%d", pair, 3-i)
+			if trade.ID != expected {
+				t.Errorf("Expected trade ID %s, got %s", expected, trade.ID)
+			}
+		}
+	}
+}
+
+// TestOrderBookDepthMissingVersusEmpty tests that a missing pair is an error while an empty book is a valid snapshot
+func TestOrderBookDepthMissingVersusEmpty(t *testing.T) {
+	engine := NewEngine()
+	pair := "BTC-USD"
+
+	depth, err := engine.OrderBookDepth(pair, 5)
+	if !errors.Is(err, ErrPairNotFound) || depth != nil {
+		t.Errorf("Expected ErrPairNotFound for a missing pair, got %+v and %v", depth, err)
+	}
+
+	engine.AddOrder(pair, Order{ID: "buy", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	if err := engine.CancelOrder(pair, "buy"); err != nil {
+		t.Fatalf("Expected cancel to succeed, got %v", err)
+	}
+	depth, err = engine.OrderBookDepth(pair, 5)
+	if err != nil || depth == nil {
+		t.Fatalf("Expected a snapshot of the empty book, got %v", err)
+	}
+	if depth.Pair != pair || depth.Bids == nil || depth.Asks == nil || len(depth.Bids) != 0 || len(depth.Asks) != 0 {
+		t.Errorf("Expected empty non-nil sides, got %+v", depth)
+	}
+	if legacy := engine.GetOrderBookDepth(pair, 5); legacy == nil || legacy.Bids == nil {
+		t.Errorf("Expected GetOrderBookDepth to return the same empty snapshot, got %+v", legacy)
+	}
+}
+
 // TestGetOrderBookDepth tests depth retrieval
 func TestGetOrderBookDepth(t *testing.T) {
 	engine := NewEngine()
@@ -411,7 +460,7 @@ func TestStartPriceBroadcaster(t *testing.T) {
 	engine.AddOrder(pair, buyOrder)
 
 	// Start the price broadcaster
-	engine.StartPriceBroadcaster()
+	engine.StartPriceBroadcaster(PriceBroadcastConfig{})
 
 	// Wait for a price update
 	select {
@@ -455,7 +504,7 @@ func TestStartDepthStreamer(t *testing.T) {
 	engine.AddOrder(pair, buyOrder)
 
 	// Start the depth streamer
-	engine.StartDepthStreamer(5)
+	engine.StartDepthStreamer(DepthStreamConfig{Depth: 5})
 
 	// Wait for a depth update
 	select {
@@ -540,3 +589,796 @@ func TestConcurrentOrderProcessing(t *testing.T) {
 	// We don't require specific numbers since timing can vary
 	t.Logf("Processed %d trades and %d fills during concurrent processing", tradeCount, fillCount)
 }
+
+// TestEngineTopOfBook tests the engine-level top of book wrapper
+func TestEngineTopOfBook(t *testing.T) {
+	engine := NewEngine()
+	pair := "BTC-USD"
+
+	if _, _, _, _, ok := engine.TopOfBook(pair); ok {
+		t.Error("Expected ok=false for non-existent pair")
+	}
+
+	engine.AddOrder(pair, Order{ID: "buy1", Side: Buy, Price: decimal.NewFromFloat(49900), Qty: decimal.NewFromFloat(1.25)})
+	engine.AddOrder(pair, Order{ID: "sell1", Side: Sell, Price: decimal.NewFromFloat(50100), Qty: decimal.NewFromFloat(0.75)})
+
+	bid, ask, bidQty, askQty, ok := engine.TopOfBook(pair)
+	if !ok {
+		t.Fatal("Expected ok=true for existing pair")
+	}
+	if !bid.Equal(decimal.NewFromFloat(49900)) || !ask.Equal(decimal.NewFromFloat(50100)) {
+		t.Errorf("Expected 49900/50100, got %s/%s", bid.String(), ask.String())
+	}
+	if !bidQty.Equal(decimal.NewFromFloat(1.25)) || !askQty.Equal(decimal.NewFromFloat(0.75)) {
+		t.Errorf("Expected quantities 1.25/0.75, got %s/%s", bidQty.String(), askQty.String())
+	}
+}
+
+// TestPriceUpdateTopQuantities tests that price updates carry the top-level sizes
+func TestPriceUpdateTopQuantities(t *testing.T) {
+	engine := NewEngine()
+	pair := "BTC-USD"
+
+	engine.AddOrder(pair, Order{ID: "buy1", Side: Buy, Price: decimal.NewFromFloat(49900), Qty: decimal.NewFromFloat(1.0)})
+	engine.AddOrder(pair, Order{ID: "buy2", Side: Buy, Price: decimal.NewFromFloat(49900), Qty: decimal.NewFromFloat(0.5)})
+	engine.AddOrder(pair, Order{ID: "buy3", Side: Buy, Price: decimal.NewFromFloat(49800), Qty: decimal.NewFromFloat(4.0)})
+	engine.AddOrder(pair, Order{ID: "sell1", Side: Sell, Price: decimal.NewFromFloat(50100), Qty: decimal.NewFromFloat(2.0)})
+
+	engine.StartPriceBroadcaster(PriceBroadcastConfig{})
+
+	select {
+	case update := <-engine.PriceUpdates:
+		if !update.BidQty.Equal(decimal.NewFromFloat(1.5)) {
+			t.Errorf("Expected bid quantity 1.5, got %s", update.BidQty.String())
+		}
+		if !update.AskQty.Equal(decimal.NewFromFloat(2.0)) {
+			t.Errorf("Expected ask quantity 2, got %s", update.AskQty.String())
+		}
+		if !update.Mid.Equal(decimal.NewFromFloat(50000)) {
+			t.Errorf("Expected mid 50000, got %s", update.Mid.String())
+		}
+		if update.Timestamp == 0 {
+			t.Error("Timestamp should be set")
+		}
+	case <-time.After(1 * time.Second):
+		t.Error("Expected a price update within 1 second")
+	}
+}
+
+// TestCancelOrder tests canceling a resting order through the engine
+func TestCancelOrder(t *testing.T) {
+	engine := NewEngine()
+	pair := "BTC-USD"
+
+	if err := engine.CancelOrder(pair, "missing"); !errors.Is(err, ErrPairNotFound) {
+		t.Errorf("Expected ErrPairNotFound, got %v", err)
+	}
+
+	engine.AddOrder(pair, Order{ID: "buy1", Side: Buy, Price: decimal.NewFromFloat(49900), Qty: decimal.NewFromFloat(1.0)})
+	<-engine.FillStream
+
+	if err := engine.CancelOrder(pair, "buy1"); err != nil {
+		t.Fatalf("Expected cancel to succeed, got %v", err)
+	}
+
+	fill := <-engine.FillStream
+	if fill.Status != Canceled {
+		t.Errorf("Expected status CANCELED, got %s", fill.Status)
+	}
+	if fill.OrderID != "buy1" {
+		t.Errorf("Expected order ID 'buy1', got %s", fill.OrderID)
+	}
+
+	depth := engine.GetOrderBookDepth(pair, 5)
+	if len(depth.Bids) != 0 {
+		t.Errorf("Expected empty bid side after cancel, got %d levels", len(depth.Bids))
+	}
+
+	if err := engine.CancelOrder(pair, "buy1"); !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("Expected ErrOrderNotFound, got %v", err)
+	}
+}
+
+// TestCancelAll tests flattening a whole book
+func TestCancelAll(t *testing.T) {
+	engine := NewEngine()
+	pair := "BTC-USD"
+
+	if n := engine.CancelAll(pair); n != 0 {
+		t.Errorf("Expected 0 cancels for unknown pair, got %d", n)
+	}
+
+	for i := 0; i < 3; i++ {
+		engine.AddOrder(pair, Order{ID: fmt.Sprintf("buy%d", i), Side: Buy, Price: decimal.NewFromInt(int64(100 - i)), Qty: decimal.NewFromInt(1)})
+		engine.AddOrder(pair, Order{ID: fmt.Sprintf("sell%d", i), Side: Sell, Price: decimal.NewFromInt(int64(105 + i)), Qty: decimal.NewFromInt(1)})
+	}
+	for len(engine.FillStream) > 0 {
+		<-engine.FillStream
+	}
+
+	if n := engine.CancelAll(pair); n != 6 {
+		t.Errorf("Expected 6 orders canceled, got %d", n)
+	}
+
+	canceled := 0
+	for len(engine.FillStream) > 0 {
+		if fill := <-engine.FillStream; fill.Status == Canceled {
+			canceled++
+		}
+	}
+	if canceled != 6 {
+		t.Errorf("Expected 6 cancel fills, got %d", canceled)
+	}
+
+	depth := engine.GetOrderBookDepth(pair, 10)
+	if len(depth.Bids) != 0 || len(depth.Asks) != 0 {
+		t.Errorf("Expected empty book, got %d bids and %d asks", len(depth.Bids), len(depth.Asks))
+	}
+}
+
+// TestCancelAllSide tests clearing only one side of a book
+func TestCancelAllSide(t *testing.T) {
+	engine := NewEngine()
+	pair := "BTC-USD"
+
+	for i := 0; i < 3; i++ {
+		engine.AddOrder(pair, Order{ID: fmt.Sprintf("buy%d", i), Side: Buy, Price: decimal.NewFromInt(int64(100 - i)), Qty: decimal.NewFromInt(1)})
+	}
+	engine.AddOrder(pair, Order{ID: "sell1", Side: Sell, Price: decimal.NewFromInt(105), Qty: decimal.NewFromInt(1)})
+	for len(engine.FillStream) > 0 {
+		<-engine.FillStream
+	}
+
+	if n := engine.CancelAllSide(pair, Buy); n != 3 {
+		t.Errorf("Expected 3 bids canceled, got %d", n)
+	}
+	if len(engine.FillStream) != 3 {
+		t.Errorf("Expected 3 cancel fills, got %d", len(engine.FillStream))
+	}
+
+	depth := engine.GetOrderBookDepth(pair, 10)
+	if len(depth.Bids) != 0 {
+		t.Errorf("Expected no bids, got %d levels", len(depth.Bids))
+	}
+	if len(depth.Asks) != 1 {
+		t.Errorf("Expected the ask side untouched, got %d levels", len(depth.Asks))
+	}
+}
+
+// TestEngineOpenOrderMetrics tests the engine wrappers for open order counts and resting volume
+func TestEngineOpenOrderMetrics(t *testing.T) {
+	engine := NewEngine()
+	pair := "BTC-USD"
+
+	if _, _, ok := engine.OpenOrderCount(pair); ok {
+		t.Error("Expected ok=false for non-existent pair")
+	}
+
+	engine.AddOrder(pair, Order{ID: "buy1", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromFloat(2.0)})
+	engine.AddOrder(pair, Order{ID: "sell1", Side: Sell, Price: decimal.NewFromInt(105), Qty: decimal.NewFromFloat(1.0)})
+	engine.AddOrder(pair, Order{ID: "sell2", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromFloat(0.5)})
+
+	bids, asks, ok := engine.OpenOrderCount(pair)
+	if !ok || bids != 1 || asks != 1 {
+		t.Errorf("Expected 1 bid and 1 ask, got %d and %d (ok=%v)", bids, asks, ok)
+	}
+
+	bidVol, askVol, ok := engine.RestingVolume(pair)
+	if !ok || !bidVol.Equal(decimal.NewFromFloat(1.5)) || !askVol.Equal(decimal.NewFromFloat(1.0)) {
+		t.Errorf("Expected volumes 1.5/1, got %s/%s (ok=%v)", bidVol, askVol, ok)
+	}
+}
+
+// TestGetImbalance tests the engine imbalance wrapper
+func TestGetImbalance(t *testing.T) {
+	engine := NewEngine()
+	pair := "BTC-USD"
+
+	if got := engine.GetImbalance(pair, 5); !got.IsZero() {
+		t.Errorf("Expected zero imbalance for unknown pair, got %s", got.String())
+	}
+
+	engine.AddOrder(pair, Order{ID: "buy1", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(3)})
+	engine.AddOrder(pair, Order{ID: "sell1", Side: Sell, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(1)})
+
+	if got := engine.GetImbalance(pair, 5); !got.Equal(decimal.NewFromFloat(0.5)) {
+		t.Errorf("Expected imbalance 0.5, got %s", got.String())
+	}
+}
+
+// TestGetTradeStats tests reading and resetting trade statistics through the accessors
+func TestGetTradeStats(t *testing.T) {
+	engine := NewEngine()
+	pair := "BTC-USD"
+
+	if _, ok := engine.GetTradeStats(pair); ok {
+		t.Error("Expected no stats for a pair that has not traded")
+	}
+
+	engine.AddOrder(pair, Order{ID: "sell1", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(2)})
+	engine.AddOrder(pair, Order{ID: "buy1", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(2)})
+
+	stats, ok := engine.GetTradeStats(pair)
+	if !ok {
+		t.Fatal("Expected stats for traded pair")
+	}
+	if stats.TradeCount != 1 || !stats.TotalQty.Equal(decimal.NewFromInt(2)) || !stats.TotalValue.Equal(decimal.NewFromInt(200)) {
+		t.Errorf("Expected 1 trade of qty 2 and value 200, got %+v", stats)
+	}
+
+	// The returned value is a copy
+	stats.TradeCount = 99
+	if again, _ := engine.GetTradeStats(pair); again.TradeCount != 1 {
+		t.Errorf("Expected live stats to be unaffected by copy, got %d", again.TradeCount)
+	}
+
+	engine.ResetTradeStats(pair)
+	stats, ok = engine.GetTradeStats(pair)
+	if !ok {
+		t.Fatal("Expected stats to remain present after reset")
+	}
+	if stats.TradeCount != 0 || !stats.TotalQty.IsZero() || !stats.TotalValue.IsZero() {
+		t.Errorf("Expected zeroed stats after reset, got %+v", stats)
+	}
+
+	engine.ResetTradeStats("ETH-USD")
+	if _, ok := engine.GetTradeStats("ETH-USD"); ok {
+		t.Error("Expected reset of unknown pair to have no effect")
+	}
+}
+
+// TestTotalStats tests that the totals across pairs equal the sum of the per-pair statistics
+func TestTotalStats(t *testing.T) {
+	engine := NewEngine()
+	if volume, value, trades := engine.TotalStats(); !volume.IsZero() || !value.IsZero() || trades != 0 {
+		t.Errorf("Expected zeros before any trade, got %s/%s/%d", volume, value, trades)
+	}
+
+	pairs := []string{"BTC-USD", "ETH-USD", "SOL-USD"}
+	for i, pair := range pairs {
+		price := decimal.NewFromInt(int64(100 * (i + 1)))
+		engine.AddOrder(pair, Order{ID: pair + "-sell", Side: Sell, Price: price, Qty: decimal.RequireFromString("2.5")})
+		for j := 0; j <= i; j++ {
+			engine.AddOrder(pair, Order{ID: fmt.Sprintf("%s-buy%d", pair, j), Side: Buy, Price: price, Qty: decimal.RequireFromString("0.5")})
+		}
+	}
+
+	var wantVolume, wantValue decimal.Decimal
+	var wantTrades int64
+	for _, pair := range pairs {
+		stats, _ := engine.GetTradeStats(pair)
+		wantVolume = wantVolume.Add(stats.TotalQty)
+		wantValue = wantValue.Add(stats.TotalValue)
+		wantTrades += stats.TradeCount
+	}
+	volume, value, trades := engine.TotalStats()
+	if !volume.Equal(wantVolume) || !value.Equal(wantValue) || trades != wantTrades {
+		t.Errorf("Expected totals %s/%s/%d, got %s/%s/%d", wantVolume, wantValue, wantTrades, volume, value, trades)
+	}
+	if trades != 6 || !volume.Equal(decimal.NewFromInt(3)) || !value.Equal(decimal.NewFromInt(700)) {
+		t.Errorf("Expected 6 trades of volume 3 and value 700, got %d/%s/%s", trades, volume, value)
+	}
+}
+
+// TestRemoveBook tests that removing a populated book cancels its orders and discards it
+func TestRemoveBook(t *testing.T) {
+	engine := NewEngine()
+	pair := "BTC-USD"
+
+	if err := engine.RemoveBook(pair); !errors.Is(err, ErrPairNotFound) {
+		t.Errorf("Expected ErrPairNotFound for unknown pair, got %v", err)
+	}
+
+	engine.AddOrder(pair, Order{ID: "sell1", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(2)})
+	engine.AddOrder(pair, Order{ID: "buy1", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	engine.AddOrder(pair, Order{ID: "buy2", Side: Buy, Price: decimal.NewFromInt(95), Qty: decimal.NewFromInt(1)})
+	for len(engine.FillStream) > 0 {
+		<-engine.FillStream
+	}
+
+	if err := engine.RemoveBook(pair); err != nil {
+		t.Fatalf("Expected book to be removed, got %v", err)
+	}
+
+	canceled := 0
+	for len(engine.FillStream) > 0 {
+		if fill := <-engine.FillStream; fill.Status == Canceled {
+			canceled++
+		}
+	}
+	if canceled != 2 {
+		t.Errorf("Expected 2 cancel fills, got %d", canceled)
+	}
+
+	if depth := engine.GetOrderBookDepth(pair, 10); depth != nil {
+		t.Errorf("Expected nil depth for removed pair, got %+v", depth)
+	}
+	if _, ok := engine.GetTradeStats(pair); ok {
+		t.Error("Expected trade stats to be removed with the book")
+	}
+	if err := engine.RemoveBook(pair); !errors.Is(err, ErrPairNotFound) {
+		t.Errorf("Expected ErrPairNotFound on second removal, got %v", err)
+	}
+}
+
+// TestClearBook tests that clearing a book empties it, optionally emitting cancels and resetting stats
+func TestClearBook(t *testing.T) {
+	engine := NewEngine()
+	pair := "BTC-USD"
+
+	if err := engine.ClearBook(pair, ClearOptions{}); !errors.Is(err, ErrPairNotFound) {
+		t.Errorf("Expected ErrPairNotFound for unknown pair, got %v", err)
+	}
+
+	populate := func() {
+		engine.AddOrder(pair, Order{ID: "sell1", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(2)})
+		engine.AddOrder(pair, Order{ID: "buy1", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+		engine.AddOrder(pair, Order{ID: "buy2", Side: Buy, Price: decimal.NewFromInt(95), Qty: decimal.NewFromInt(1)})
+		drainFills(engine)
+	}
+
+	populate()
+	if err := engine.ClearBook(pair, ClearOptions{}); err != nil {
+		t.Fatalf("Expected book to be cleared, got %v", err)
+	}
+	if n := len(engine.FillStream); n != 0 {
+		t.Errorf("Expected no cancel fills, got %d", n)
+	}
+	depth := engine.GetOrderBookDepth(pair, 10)
+	if depth == nil || len(depth.Bids) != 0 || len(depth.Asks) != 0 {
+		t.Errorf("Expected empty depth after clear, got %+v", depth)
+	}
+	if bid, ask, _, _, _ := engine.TopOfBook(pair); !bid.IsZero() || !ask.IsZero() {
+		t.Errorf("Expected no best prices after clear, got %s/%s", bid, ask)
+	}
+	if stats, ok := engine.GetTradeStats(pair); !ok || stats.TradeCount != 1 {
+		t.Errorf("Expected trade stats to be preserved, got %+v", stats)
+	}
+
+	populate()
+	if err := engine.ClearBook(pair, ClearOptions{EmitCancels: true, ResetStats: true}); err != nil {
+		t.Fatalf("Expected book to be cleared, got %v", err)
+	}
+	canceled := 0
+	for len(engine.FillStream) > 0 {
+		if fill := <-engine.FillStream; fill.Status == Canceled {
+			canceled++
+		}
+	}
+	if canceled != 2 {
+		t.Errorf("Expected 2 cancel fills, got %d", canceled)
+	}
+	if _, ok := engine.GetTradeStats(pair); ok {
+		t.Error("Expected trade stats to be reset")
+	}
+
+	engine.AddOrder(pair, Order{ID: "after", Side: Buy, Price: decimal.NewFromInt(90), Qty: decimal.NewFromInt(1)})
+	if bids, _, _ := engine.OpenOrderCount(pair); bids != 1 {
+		t.Errorf("Expected the cleared book to accept orders, got %d bids", bids)
+	}
+}
+
+// TestReset tests that a reset empties every book and zeroes the stats without disturbing subscribers
+func TestReset(t *testing.T) {
+	engine := NewEngine()
+	var seen int
+	var mu sync.Mutex
+	engine.OnTrade(func(Trade) {
+		mu.Lock()
+		seen++
+		mu.Unlock()
+	})
+
+	pairs := []string{"BTC-USD", "ETH-USD", "SOL-USD"}
+	for _, pair := range pairs {
+		engine.AddOrder(pair, Order{ID: pair + "-sell", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(2)})
+		engine.AddOrder(pair, Order{ID: pair + "-buy", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	}
+	engine.GetNextTradeID()
+
+	engine.Reset()
+	for _, pair := range pairs {
+		if depth := engine.GetOrderBookDepth(pair, 10); depth != nil {
+			t.Errorf("Expected no book for %s after reset, got %+v", pair, depth)
+		}
+		if _, ok := engine.GetTradeStats(pair); ok {
+			t.Errorf("Expected no trade stats for %s after reset", pair)
+		}
+		if trades := engine.RecentTrades(pair, 10); len(trades) != 0 {
+			t.Errorf("Expected no trade history for %s after reset, got %d", pair, len(trades))
+		}
+	}
+	if pairs := engine.Pairs(); len(pairs) != 0 {
+		t.Errorf("Expected no pairs after reset, got %v", pairs)
+	}
+	if id := engine.GetNextTradeID(); id != "T1" {
+		t.Errorf("Expected trade counter to restart at T1, got %s", id)
+	}
+	if orders, trades, fills := engine.Counters(); orders != 0 || trades != 0 || fills != 0 {
+		t.Errorf("Expected zeroed counters after reset, got %d orders, %d trades and %d fills", orders, trades, fills)
+	}
+	if metrics := engine.Metrics(); metrics != (EngineMetrics{}) {
+		t.Errorf("Expected zeroed metrics after reset, got %+v", metrics)
+	}
+	if id, _ := engine.SubmitOrder("ETH-USD", Order{Side: Buy, Price: decimal.NewFromInt(1), Qty: decimal.NewFromInt(1)}); id != orderIDPrefix+"1" {
+		t.Errorf("Expected generated order IDs to restart, got %s", id)
+	}
+
+	// The engine keeps trading and existing subscribers keep receiving
+	engine.AddOrder("BTC-USD", Order{ID: "sell", Side: Sell, Price: decimal.NewFromInt(50), Qty: decimal.NewFromInt(1)})
+	engine.AddOrder("BTC-USD", Order{ID: "buy", Side: Buy, Price: decimal.NewFromInt(50), Qty: decimal.NewFromInt(1)})
+	if stats, ok := engine.GetTradeStats("BTC-USD"); !ok || stats.TradeCount != 1 {
+		t.Errorf("Expected a fresh trade count of 1, got %+v", stats)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if seen != 4 {
+		t.Errorf("Expected the listener to see 4 trades, got %d", seen)
+	}
+}
+
+// TestPairs tests that active trading pairs are listed in sorted order
+func TestPairs(t *testing.T) {
+	engine := NewEngine()
+
+	if pairs := engine.Pairs(); len(pairs) != 0 {
+		t.Errorf("Expected no pairs, got %v", pairs)
+	}
+
+	for _, pair := range []string{"SOL-USD", "BTC-USD", "ETH-USD"} {
+		engine.AddOrder(pair, Order{ID: pair, Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	}
+
+	expected := []string{"BTC-USD", "ETH-USD", "SOL-USD"}
+	pairs := engine.Pairs()
+	if fmt.Sprint(pairs) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v, got %v", expected, pairs)
+	}
+
+	if err := engine.RemoveBook("ETH-USD"); err != nil {
+		t.Fatalf("Expected book to be removed, got %v", err)
+	}
+	if pairs := engine.Pairs(); fmt.Sprint(pairs) != "[BTC-USD SOL-USD]" {
+		t.Errorf("Expected [BTC-USD SOL-USD], got %v", pairs)
+	}
+}
+
+// TestAddOrderCtx tests that a canceled context keeps an order out of the book
+func TestAddOrderCtx(t *testing.T) {
+	engine := NewEngine()
+	pair := "BTC-USD"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := engine.AddOrderCtx(ctx, pair, Order{ID: "buy1", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if pairs := engine.Pairs(); len(pairs) != 0 {
+		t.Errorf("Expected no book to be created, got %v", pairs)
+	}
+	if len(engine.FillStream) != 0 {
+		t.Errorf("Expected no fills, got %d", len(engine.FillStream))
+	}
+
+	if err := engine.AddOrderCtx(context.Background(), pair, Order{ID: "buy2", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)}); err != nil {
+		t.Fatalf("Expected order to be accepted, got %v", err)
+	}
+	if bids, _, _ := engine.OpenOrderCount(pair); bids != 1 {
+		t.Errorf("Expected 1 resting bid, got %d", bids)
+	}
+}
+
+// TestStartPriceBroadcasterTwice tests that a second start is a no-op and stop halts updates
+func TestStartPriceBroadcasterTwice(t *testing.T) {
+	engine := NewEngine()
+	engine.AddOrder("BTC-USD", Order{ID: "buy1", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+
+	engine.StartPriceBroadcaster(PriceBroadcastConfig{})
+	engine.StartPriceBroadcaster(PriceBroadcastConfig{})
+	time.Sleep(200 * time.Millisecond)
+	if n := len(engine.PriceUpdates); n != 1 {
+		t.Errorf("Expected a single broadcaster to send 1 update, got %d", n)
+	}
+
+	engine.StopPriceBroadcaster()
+	engine.StopPriceBroadcaster()
+	for len(engine.PriceUpdates) > 0 {
+		<-engine.PriceUpdates
+	}
+	time.Sleep(600 * time.Millisecond)
+	if n := len(engine.PriceUpdates); n != 0 {
+		t.Errorf("Expected no updates after stop, got %d", n)
+	}
+
+	// The broadcaster can be restarted after a stop
+	engine.StartPriceBroadcaster(PriceBroadcastConfig{})
+	defer engine.StopPriceBroadcaster()
+	select {
+	case <-engine.PriceUpdates:
+	case <-time.After(time.Second):
+		t.Error("Expected updates after restart")
+	}
+}
+
+// TestStartDepthStreamerTwice tests that a second depth streamer start is a no-op
+func TestStartDepthStreamerTwice(t *testing.T) {
+	engine := NewEngine()
+	engine.AddOrder("BTC-USD", Order{ID: "buy1", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+
+	engine.StartDepthStreamer(DepthStreamConfig{Depth: 5})
+	engine.StartDepthStreamer(DepthStreamConfig{Depth: 5})
+	time.Sleep(50 * time.Millisecond)
+	engine.StopDepthStreamer()
+	if n := len(engine.DepthUpdates); n != 1 {
+		t.Errorf("Expected a single streamer to send 1 update, got %d", n)
+	}
+}
+
+// TestStreamIntervals tests that the configured intervals speed up and throttle updates
+func TestStreamIntervals(t *testing.T) {
+	engine := NewEngine()
+	engine.AddOrder("BTC-USD", Order{ID: "buy1", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	engine.AddOrder("ETH-USD", Order{ID: "buy2", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+
+	engine.StartPriceBroadcaster(PriceBroadcastConfig{Interval: 10 * time.Millisecond})
+	time.Sleep(200 * time.Millisecond)
+	engine.StopPriceBroadcaster()
+	if n := len(engine.PriceUpdates); n < 10 {
+		t.Errorf("Expected a 10ms interval to send at least 10 updates in 200ms, got %d", n)
+	}
+
+	engine.StartDepthStreamer(DepthStreamConfig{
+		Interval: 10 * time.Millisecond,
+		Depth:    3,
+		Pairs:    map[string]DepthStreamOverride{"ETH-USD": {Interval: time.Hour, Depth: 1}},
+	})
+	time.Sleep(200 * time.Millisecond)
+	engine.StopDepthStreamer()
+
+	counts := make(map[string]int)
+	for len(engine.DepthUpdates) > 0 {
+		counts[(<-engine.DepthUpdates).Pair]++
+	}
+	if counts["BTC-USD"] < 10 {
+		t.Errorf("Expected at least 10 BTC-USD depth updates, got %d", counts["BTC-USD"])
+	}
+	if counts["ETH-USD"] != 1 {
+		t.Errorf("Expected the hourly ETH-USD override to send 1 update, got %d", counts["ETH-USD"])
+	}
+}
+
+// TestPriceBroadcastOnChange tests that unchanged pairs are not rebroadcast until their top of book moves
+func TestPriceBroadcastOnChange(t *testing.T) {
+	engine := NewEngine()
+	pair := "BTC-USD"
+	engine.AddOrder(pair, Order{ID: "buy1", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+
+	engine.StartPriceBroadcaster(PriceBroadcastConfig{Interval: 10 * time.Millisecond, OnChange: true})
+	defer engine.StopPriceBroadcaster()
+	time.Sleep(100 * time.Millisecond)
+	if n := len(engine.PriceUpdates); n != 1 {
+		t.Fatalf("Expected 1 update for an unchanged book, got %d", n)
+	}
+	<-engine.PriceUpdates
+
+	engine.AddOrder(pair, Order{ID: "buy2", Side: Buy, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(1)})
+	select {
+	case update := <-engine.PriceUpdates:
+		if !update.BestBid.Equal(decimal.NewFromInt(101)) {
+			t.Errorf("Expected best bid 101, got %s", update.BestBid)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected an update after the best bid changed")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := len(engine.PriceUpdates); n != 0 {
+		t.Errorf("Expected no further updates, got %d", n)
+	}
+}
+
+// TestPriceChangesHeartbeat tests that an unchanged pair is resent once the heartbeat passes
+func TestPriceChangesHeartbeat(t *testing.T) {
+	changes := newPriceChanges(time.Second)
+	update := PriceUpdate{Pair: "BTC-USD", BestBid: decimal.NewFromInt(100)}
+	now := time.Now()
+
+	if !changes.changed(update, now) {
+		t.Error("Expected the first update to be sent")
+	}
+	if changes.changed(update, now.Add(500*time.Millisecond)) {
+		t.Error("Expected an unchanged update within the heartbeat to be skipped")
+	}
+	if !changes.changed(update, now.Add(time.Second)) {
+		t.Error("Expected the heartbeat to resend an unchanged update")
+	}
+}
+
+// TestDepthStreamOnChange tests that an idle pair produces no depth updates while an active one does
+func TestDepthStreamOnChange(t *testing.T) {
+	engine := NewEngine()
+	engine.AddOrder("BTC-USD", Order{ID: "btc1", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	engine.AddOrder("ETH-USD", Order{ID: "eth1", Side: Buy, Price: decimal.NewFromInt(10), Qty: decimal.NewFromInt(1)})
+
+	engine.StartDepthStreamer(DepthStreamConfig{Interval: 10 * time.Millisecond, OnChange: true})
+	defer engine.StopDepthStreamer()
+	time.Sleep(100 * time.Millisecond)
+	if n := len(engine.DepthUpdates); n != 2 {
+		t.Fatalf("Expected 1 update per unchanged book, got %d", n)
+	}
+	<-engine.DepthUpdates
+	<-engine.DepthUpdates
+
+	engine.AddOrder("BTC-USD", Order{ID: "btc2", Side: Buy, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(1)})
+	select {
+	case update := <-engine.DepthUpdates:
+		if update.Pair != "BTC-USD" || len(update.Bids) != 2 {
+			t.Errorf("Expected BTC-USD depth with 2 bid levels, got %+v", update)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected an update after the BTC-USD book changed")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := len(engine.DepthUpdates); n != 0 {
+		t.Errorf("Expected no further updates, got %d", n)
+	}
+}
+
+// TestDepthChangesHeartbeat tests that an unchanged book is resent once the heartbeat passes
+func TestDepthChangesHeartbeat(t *testing.T) {
+	changes := newDepthChanges(time.Second)
+	book := NewOrderBook("BTC-USD")
+	now := time.Now()
+
+	if !changes.changed("BTC-USD", book, now) {
+		t.Error("Expected a new book to be sent")
+	}
+	if changes.changed("BTC-USD", book, now.Add(500*time.Millisecond)) {
+		t.Error("Expected an unchanged book within the heartbeat to be skipped")
+	}
+	book.Execute(Order{ID: "buy", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	if !changes.changed("BTC-USD", book, now.Add(600*time.Millisecond)) {
+		t.Error("Expected a changed book to be sent")
+	}
+	if !changes.changed("BTC-USD", book, now.Add(2*time.Second)) {
+		t.Error("Expected the heartbeat to resend an unchanged book")
+	}
+}
+
+// TestDepthStreamConfigDefaults tests the depth and interval fallbacks of the stream configs
+func TestDepthStreamConfigDefaults(t *testing.T) {
+	cfg := DepthStreamConfig{Pairs: map[string]DepthStreamOverride{"ETH-USD": {Depth: 2}}}
+	if d := cfg.depth("BTC-USD"); d != defaultStreamDepth {
+		t.Errorf("Expected default depth %d, got %d", defaultStreamDepth, d)
+	}
+	if d := cfg.depth("ETH-USD"); d != 2 {
+		t.Errorf("Expected override depth 2, got %d", d)
+	}
+
+	sched := newSchedule(0, defaultPriceInterval, map[string]time.Duration{"ETH-USD": time.Second})
+	if tick := sched.tick(); tick != defaultPriceInterval {
+		t.Errorf("Expected tick %v, got %v", defaultPriceInterval, tick)
+	}
+	now := time.Now()
+	if !sched.due("ETH-USD", now) || sched.due("ETH-USD", now.Add(defaultPriceInterval)) {
+		t.Error("Expected ETH-USD to be sent once per second")
+	}
+	if !sched.due("ETH-USD", now.Add(time.Second)) {
+		t.Error("Expected ETH-USD to be due after its interval")
+	}
+}
+
+// TestBroadcastDuringOrderFlow tests that the broadcasters run concurrently with order intake on new and existing pairs
+func TestBroadcastDuringOrderFlow(t *testing.T) {
+	engine := NewEngine()
+	engine.StartPriceBroadcaster(PriceBroadcastConfig{})
+	engine.StartDepthStreamer(DepthStreamConfig{Depth: 5})
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				pair := fmt.Sprintf("PAIR-%d", i%10)
+				side := Buy
+				if i%2 == 1 {
+					side = Sell
+				}
+				engine.AddOrder(pair, Order{ID: fmt.Sprintf("g%d-%d", g, i), Side: side, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+				engine.broadcastPrices(0, nil, nil)
+				engine.streamDepth(func(string, *OrderBook) (int, bool) { return 5, true }, nil)
+			}
+		}(g)
+	}
+	go func() {
+		for range engine.TradeStream {
+		}
+	}()
+	go func() {
+		for range engine.FillStream {
+		}
+	}()
+	wg.Wait()
+
+	engine.StopPriceBroadcaster()
+	engine.StopDepthStreamer()
+	if pairs := engine.Pairs(); len(pairs) != 10 {
+		t.Errorf("Expected 10 pairs, got %d", len(pairs))
+	}
+}
+
+// TestQueuePosition tests that a resting order reports the quantity and orders ahead of it at its price
+func TestQueuePosition(t *testing.T) {
+	engine := NewEngine()
+	pair := "BTC-USD"
+	if _, _, err := engine.QueuePosition(pair, "bid2"); !errors.Is(err, ErrPairNotFound) {
+		t.Errorf("Expected ErrPairNotFound, got %v", err)
+	}
+
+	engine.AddOrder(pair, Order{ID: "bid1", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.RequireFromString("1.5")})
+	engine.AddOrder(pair, Order{ID: "better", Side: Buy, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(7)})
+	engine.AddOrder(pair, Order{ID: "bid2", ClientOrderID: "mine", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(2)})
+	engine.AddOrder(pair, Order{ID: "bid3", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(3)})
+
+	ahead, position, err := engine.QueuePosition(pair, "bid2")
+	if err != nil || !ahead.Equal(decimal.RequireFromString("1.5")) || position != 2 {
+		t.Errorf("Expected 1.5 ahead at position 2, got %s at %d (%v)", ahead, position, err)
+	}
+	if ahead, position, _ := engine.QueuePosition(pair, "bid1"); !ahead.IsZero() || position != 1 {
+		t.Errorf("Expected the first order at the front, got %s at %d", ahead, position)
+	}
+	if ahead, position, _ := engine.QueuePosition(pair, "mine"); !ahead.Equal(decimal.RequireFromString("1.5")) || position != 2 {
+		t.Errorf("Expected the client ID to resolve to bid2, got %s at %d", ahead, position)
+	}
+
+	// A fill at the front shrinks the quantity ahead
+	engine.AddOrder(pair, Order{ID: "sell", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(8)})
+	if ahead, position, _ := engine.QueuePosition(pair, "bid2"); !ahead.Equal(decimal.RequireFromString("0.5")) || position != 2 {
+		t.Errorf("Expected 0.5 ahead after the partial fill, got %s at %d", ahead, position)
+	}
+	if _, _, err := engine.QueuePosition(pair, "better"); !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("Expected ErrOrderNotFound for a filled order, got %v", err)
+	}
+}
+
+// TestSnapshotAllConsistent tests that SnapshotAll never sees one pair ahead of another under concurrent order flow
+func TestSnapshotAllConsistent(t *testing.T) {
+	engine := NewEngine(WithShards(2))
+	drainEngine(engine)
+
+	// Every order goes to A-USD first and B-USD second, so at any instant
+	// A-USD holds as much as B-USD or one more.
+	const n = 500
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < n; i++ {
+			for _, pair := range []string{"A-USD", "B-USD"} {
+				engine.AddOrder(pair, Order{ID: fmt.Sprintf("%s-%d", pair, i), Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+			}
+		}
+	}()
+
+	qty := func(update DepthUpdate) int64 {
+		if len(update.Bids) == 0 {
+			return 0
+		}
+		return update.Bids[0].Quantity.IntPart()
+	}
+	for {
+		select {
+		case <-done:
+			snap := engine.SnapshotAll(1)
+			if qty(snap["A-USD"]) != n || qty(snap["B-USD"]) != n {
+				t.Errorf("Expected %d resting in both pairs, got %+v", n, snap)
+			}
+			return
+		default:
+		}
+		snap := engine.SnapshotAll(1)
+		a, b := qty(snap["A-USD"]), qty(snap["B-USD"])
+		if a != b && a != b+1 {
+			t.Fatalf("Expected A-USD to be level with or one ahead of B-USD, got %d and %d", a, b)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package engine
+
+// emit records a level-3 event for o if event recording is enabled. The
+// caller must hold ob.mutex.
+func (ob *OrderBook) emit(typ OrderEventType, o Order) {
+	if !ob.recordEvents {
+		return
+	}
+	ob.eventSeq++
+	ob.events = append(ob.events, OrderEvent{
+		Seq:       ob.eventSeq,
+		Type:      typ,
+		Pair:      ob.Pair,
+		Order:     o,
+		Timestamp: ob.clock.Now().Unix(),
+	})
+}
+
+// emitRemoved records a Removed event for each of orders. The caller must hold
+// ob.mutex.
+func (ob *OrderBook) emitRemoved(orders []Order) {
+	for _, o := range orders {
+		ob.emit(Removed, o)
+	}
+}
+
+// takeEvents moves the recorded events into res. The caller must hold
+// ob.mutex.
+func (ob *OrderBook) takeEvents(res *MatchResult) {
+	res.Events = append(res.Events, ob.events...)
+	clear(ob.events)
+	ob.events = ob.events[:0]
+}
+
+// flushEvents moves the events recorded by operations outside of matching,
+// such as cancels, into res.
+func (ob *OrderBook) flushEvents(res *MatchResult) {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+	ob.takeEvents(res)
+}
+
+// depthAt returns up to depth levels of each side together with the sequence
+// number of the last level-3 event they reflect, read under one lock.
+func (ob *OrderBook) depthAt(depth int) (bids, asks []DepthLevel, seq uint64) {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+	return ob.bidLevels(depth), ob.askLevels(depth), ob.eventSeq
+}
+
+// SnapshotForSync returns the current depth of the specified trading pair
+// together with the sequence number of the last OrderEvents event it
+// reflects. A client bootstraps by taking the snapshot and then applying the
+// pair's events from the next sequence number on, discarding older ones; a
+// gap in the sequence means it must resynchronize. Returns an empty update
+// and zero if the pair has no book.
+func (e *Engine) SnapshotForSync(pair string, depth int) (DepthUpdate, uint64) {
+	book, exists := e.book(pair)
+	if !exists {
+		return DepthUpdate{Pair: pair, Bids: []DepthLevel{}, Asks: []DepthLevel{}, Timestamp: e.clock.Now().Unix()}, 0
+	}
+
+	bids, asks, seq := book.depthAt(depth)
+	update := DepthUpdate{
+		Pair:      pair,
+		Bids:      bids,
+		Asks:      asks,
+		Timestamp: e.clock.Now().Unix(),
+	}
+	if stats, ok := e.GetTradeStats(pair); ok {
+		update.TradeCount = stats.TradeCount
+	}
+	return update, seq
+}
@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// drainOrderEvents returns the buffered level-3 events
+func drainOrderEvents(engine *Engine) []OrderEvent {
+	var events []OrderEvent
+	for len(engine.OrderEvents) > 0 {
+		events = append(events, <-engine.OrderEvents)
+	}
+	return events
+}
+
+// TestOrderEventsAddRemove tests that a fully filled resting order produces an add then a remove event
+func TestOrderEventsAddRemove(t *testing.T) {
+	engine := NewEngine()
+	pair := "BTC-USD"
+
+	engine.AddOrder(pair, Order{ID: "sell1", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(2)})
+	engine.AddOrder(pair, Order{ID: "buy1", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	engine.AddOrder(pair, Order{ID: "buy2", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+
+	events := drainOrderEvents(engine)
+	expected := []struct {
+		typ OrderEventType
+		qty int64
+	}{
+		{Added, 2},
+		{Reduced, 1},
+		{Removed, 0},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events, got %+v", len(expected), events)
+	}
+	for i, e := range expected {
+		event := events[i]
+		if event.Order.ID != "sell1" || event.Type != e.typ || !event.Order.Qty.Equal(decimal.NewFromInt(e.qty)) {
+			t.Errorf("Expected event %d to be %s sell1 qty %d, got %s %s qty %s", i, e.typ, e.qty, event.Type, event.Order.ID, event.Order.Qty)
+		}
+		if event.Seq != uint64(i+1) {
+			t.Errorf("Expected sequence %d, got %d", i+1, event.Seq)
+		}
+	}
+}
+
+// TestOrderEventsCancel tests that canceled orders produce remove events
+func TestOrderEventsCancel(t *testing.T) {
+	engine := NewEngine()
+	pair := "BTC-USD"
+
+	engine.AddOrder(pair, Order{ID: "buy1", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	engine.AddOrder(pair, Order{ID: "buy2", Side: Buy, Price: decimal.NewFromInt(99), Qty: decimal.NewFromInt(1)})
+	engine.AddOrder(pair, Order{ID: "sell1", Side: Sell, Price: decimal.NewFromInt(105), Qty: decimal.NewFromInt(1)})
+	if err := engine.CancelOrder(pair, "buy1"); err != nil {
+		t.Fatalf("Expected cancel to succeed, got %v", err)
+	}
+	engine.CancelAll(pair)
+
+	events := drainOrderEvents(engine)
+	if len(events) != 6 {
+		t.Fatalf("Expected 3 adds and 3 removes, got %d events", len(events))
+	}
+	removed := map[string]bool{}
+	for i, event := range events {
+		if event.Seq != uint64(i+1) {
+			t.Errorf("Expected gap-free sequence %d, got %d", i+1, event.Seq)
+		}
+		if event.Type == Removed {
+			removed[event.Order.ID] = true
+		}
+	}
+	if events[3].Type != Removed || events[3].Order.ID != "buy1" {
+		t.Errorf("Expected single cancel of buy1 to be reported first, got %+v", events[3])
+	}
+	if len(removed) != 3 {
+		t.Errorf("Expected every order to be removed, got %v", removed)
+	}
+}
+
+// TestSnapshotForSync tests that the snapshot's sequence number lines up with the following events
+func TestSnapshotForSync(t *testing.T) {
+	engine := NewEngine()
+	pair := "BTC-USD"
+	engine.AddOrder(pair, Order{ID: "bid1", Side: Buy, Price: decimal.NewFromInt(99), Qty: decimal.NewFromInt(1)})
+	engine.AddOrder(pair, Order{ID: "ask1", Side: Sell, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(2)})
+
+	snapshot, seq := engine.SnapshotForSync(pair, 10)
+	if seq != 2 {
+		t.Errorf("Expected snapshot at sequence 2, got %d", seq)
+	}
+	if len(snapshot.Bids) != 1 || len(snapshot.Asks) != 1 {
+		t.Fatalf("Expected one level per side, got %+v", snapshot)
+	}
+
+	engine.AddOrder(pair, Order{ID: "buy", Side: Buy, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(1)})
+	engine.AddOrder(pair, Order{ID: "bid2", Side: Buy, Price: decimal.NewFromInt(98), Qty: decimal.NewFromInt(1)})
+
+	// Skipping events covered by the snapshot leaves a gapless delta stream
+	next := seq + 1
+	for _, event := range drainOrderEvents(engine) {
+		if event.Seq <= seq {
+			continue
+		}
+		if event.Seq != next {
+			t.Fatalf("Expected event %d after the snapshot, got %d", next, event.Seq)
+		}
+		next++
+	}
+	if next != seq+3 {
+		t.Errorf("Expected 2 events after the snapshot, got %d", next-seq-1)
+	}
+
+	if _, seq := engine.SnapshotForSync("ETH-USD", 10); seq != 0 {
+		t.Errorf("Expected sequence 0 for an unknown pair, got %d", seq)
+	}
+}
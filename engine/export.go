@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// tradeCSVHeader is the header row written by ExportTrades.
+var tradeCSVHeader = []string{
+	"id", "pair", "buy_order_id", "sell_order_id", "price", "qty", "taker_side", "timestamp",
+}
+
+// fillCSVHeader is the header row written by ExportFills.
+var fillCSVHeader = []string{
+	"order_id", "client_order_id", "pair", "side", "account", "status",
+	"original_qty", "executed_qty", "remaining_qty", "price", "fill_price", "fee",
+	"cumulative_qty", "cumulative_avg_price", "avg_fill_price", "reason", "timestamp",
+}
+
+// ExportTrades writes the retained trade history of the specified trading
+// pair to w as CSV, oldest trade first, under a fixed header row. Decimals are
+// written in their exact string form. A pair that has not traded yields only
+// the header row. History is bounded by WithTradeHistory.
+//
+// Returns ErrPairNotFound if the pair has no order book, or the error
+// writing to w.
+func (e *Engine) ExportTrades(pair string, w io.Writer) error {
+	if _, exists := e.book(pair); !exists {
+		return ErrPairNotFound
+	}
+	trades := e.RecentTrades(pair, e.tradeHistorySize)
+
+	cw := csv.NewWriter(w)
+	_ = cw.Write(tradeCSVHeader)
+	for i := len(trades) - 1; i >= 0; i-- {
+		t := trades[i]
+		_ = cw.Write([]string{
+			t.ID, t.Pair, t.BuyOrderID, t.SellOrderID, t.Price.String(), t.Qty.String(),
+			string(t.TakerSide), strconv.FormatInt(t.Timestamp, 10),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ExportFills writes the retained fill history of the specified trading pair
+// to w as CSV, oldest fill first, under a fixed header row. Decimals are
+// written in their exact string form. A pair without fills yields only the
+// header row. History is bounded by WithFillHistory.
+//
+// Returns ErrPairNotFound if the pair has no order book, or the error
+// writing to w.
+func (e *Engine) ExportFills(pair string, w io.Writer) error {
+	if _, exists := e.book(pair); !exists {
+		return ErrPairNotFound
+	}
+	fills := e.RecentFills(pair, e.fillHistorySize)
+
+	cw := csv.NewWriter(w)
+	_ = cw.Write(fillCSVHeader)
+	for i := len(fills) - 1; i >= 0; i-- {
+		f := fills[i]
+		_ = cw.Write([]string{
+			f.OrderID, f.ClientOrderID, f.Pair, string(f.Side), f.Account, string(f.Status),
+			f.OriginalQty.String(), f.ExecutedQty.String(), f.RemainingQty.String(),
+			f.Price.String(), f.FillPrice.String(), f.Fee.String(),
+			f.CumulativeQty.String(), f.CumulativeAvgPrice.String(), f.AvgFillPrice.String(),
+			string(f.Reason), strconv.FormatInt(f.Timestamp, 10),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// TestExportTrades tests that trades are exported as CSV oldest first with exact decimals
+func TestExportTrades(t *testing.T) {
+	engine := NewEngine()
+	pair := "BTC-USD"
+	var buf bytes.Buffer
+	if err := engine.ExportTrades(pair, &buf); err != ErrPairNotFound {
+		t.Errorf("Expected ErrPairNotFound for an unknown pair, got %v", err)
+	}
+
+	engine.AddOrder(pair, Order{ID: "ask", Side: Sell, Price: decimal.RequireFromString("100.10"), Qty: decimal.RequireFromString("0.3")})
+	buf.Reset()
+	if err := engine.ExportTrades(pair, &buf); err != nil {
+		t.Fatalf("Expected export to succeed, got %v", err)
+	}
+	if got := strings.TrimSpace(buf.String()); got != strings.Join(tradeCSVHeader, ",") {
+		t.Errorf("Expected only the header for a pair without trades, got %q", got)
+	}
+
+	engine.AddOrder(pair, Order{ID: "buy1", Side: Buy, Price: decimal.RequireFromString("100.10"), Qty: decimal.RequireFromString("0.1")})
+	engine.AddOrder(pair, Order{ID: "buy2", Side: Buy, Price: decimal.RequireFromString("100.10"), Qty: decimal.RequireFromString("0.2")})
+	buf.Reset()
+	if err := engine.ExportTrades(pair, &buf); err != nil {
+		t.Fatalf("Expected export to succeed, got %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Expected valid CSV, got %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected a header and 2 trades, got %v", records)
+	}
+	if records[1][2] != "buy1" || records[2][2] != "buy2" {
+		t.Errorf("Expected trades oldest first, got %v", records[1:])
+	}
+	if records[1][4] != "100.1" || records[1][5] != "0.1" || records[2][5] != "0.2" || records[1][6] != string(Buy) {
+		t.Errorf("Expected exact price and quantities, got %v", records[1:])
+	}
+}
+
+// TestExportFills tests that fills are exported as CSV under a stable header
+func TestExportFills(t *testing.T) {
+	engine := NewEngine()
+	pair := "BTC-USD"
+	engine.AddOrder(pair, Order{ID: "ask", ClientOrderID: "c1", Account: "alice", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	engine.AddOrder(pair, Order{ID: "bid", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.RequireFromString("0.25")})
+
+	var buf bytes.Buffer
+	if err := engine.ExportFills(pair, &buf); err != nil {
+		t.Fatalf("Expected export to succeed, got %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Expected valid CSV, got %v", err)
+	}
+	if strings.Join(records[0], ",") != strings.Join(fillCSVHeader, ",") {
+		t.Errorf("Expected the fill header, got %v", records[0])
+	}
+	// New for the ask, New for the bid, then the maker and taker fills
+	if len(records) != 5 {
+		t.Fatalf("Expected a header and 4 fills, got %v", records)
+	}
+	ack := records[1]
+	if ack[0] != "ask" || ack[1] != "c1" || ack[4] != "alice" || ack[5] != string(New) {
+		t.Errorf("Expected the ask's acknowledgment first, got %v", ack)
+	}
+	maker := records[3]
+	if maker[0] != "ask" || maker[5] != string(PartiallyFilled) || maker[7] != "0.25" || maker[8] != "0.75" {
+		t.Errorf("Expected the ask to be partially filled for 0.25, got %v", maker)
+	}
+
+	if err := engine.ExportFills("ETH-USD", &buf); err != ErrPairNotFound {
+		t.Errorf("Expected ErrPairNotFound for an unknown pair, got %v", err)
+	}
+}
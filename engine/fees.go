@@ -0,0 +1,52 @@
+package engine
+
+import (
+	"sync"
+
+	"github.com/shopspring/decimal"
+)
+
+// feeLedger accumulates the fees charged to each account across all pairs.
+// The zero value is an empty ledger. It is safe for concurrent use.
+type feeLedger struct {
+	mutex   sync.Mutex
+	accrued map[string]decimal.Decimal
+}
+
+// add charges fee to account. Fills without an account or fee are ignored.
+func (l *feeLedger) add(account string, fee decimal.Decimal) {
+	if account == "" || fee.IsZero() {
+		return
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.accrued == nil {
+		l.accrued = make(map[string]decimal.Decimal)
+	}
+	l.accrued[account] = l.accrued[account].Add(fee)
+}
+
+// total returns the fees charged to account so far.
+func (l *feeLedger) total(account string) decimal.Decimal {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.accrued[account]
+}
+
+// reset discards every account's total.
+func (l *feeLedger) reset() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	clear(l.accrued)
+}
+
+// AccruedFees returns the sum of the fees charged to account across all pairs
+// since the engine was created or last reset, for settlement. Fees are taken
+// from the Fee of every fill published for the account's orders, so maker
+// rebates reduce the total and may make it negative. Accounts that have not
+// traded accrue zero.
+func (e *Engine) AccruedFees(account string) decimal.Decimal {
+	return e.fees.total(account)
+}
@@ -0,0 +1,43 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// TestAccruedFees tests that maker and taker accounts accrue their fees across pairs
+func TestAccruedFees(t *testing.T) {
+	engine := NewEngine()
+	config := PairConfig{MakerFee: decimal.RequireFromString("-0.001"), TakerFee: decimal.RequireFromString("0.002")}
+	engine.SetPairConfig("BTC-USD", config)
+	engine.SetPairConfig("ETH-USD", config)
+
+	engine.AddOrder("BTC-USD", Order{ID: "maker", Account: "alice", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(10)})
+	engine.AddOrder("BTC-USD", Order{ID: "taker", Account: "bob", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(5)})
+
+	if fee := engine.AccruedFees("alice"); !fee.Equal(decimal.RequireFromString("-0.5")) {
+		t.Errorf("Expected the maker to be credited a 0.5 rebate, got %s", fee)
+	}
+	if fee := engine.AccruedFees("bob"); !fee.Equal(decimal.NewFromInt(1)) {
+		t.Errorf("Expected the taker to be charged 1, got %s", fee)
+	}
+
+	engine.AddOrder("ETH-USD", Order{ID: "maker2", Account: "bob", Side: Buy, Price: decimal.NewFromInt(50), Qty: decimal.NewFromInt(2)})
+	engine.AddOrder("ETH-USD", Order{ID: "taker2", Account: "alice", Side: Sell, Price: decimal.NewFromInt(50), Qty: decimal.NewFromInt(2)})
+
+	if fee := engine.AccruedFees("alice"); !fee.Equal(decimal.RequireFromString("-0.3")) {
+		t.Errorf("Expected alice to net -0.3 across pairs, got %s", fee)
+	}
+	if fee := engine.AccruedFees("bob"); !fee.Equal(decimal.RequireFromString("0.9")) {
+		t.Errorf("Expected bob to net 0.9 across pairs, got %s", fee)
+	}
+	if fee := engine.AccruedFees("carol"); !fee.IsZero() {
+		t.Errorf("Expected no fees for an account that has not traded, got %s", fee)
+	}
+
+	engine.Reset()
+	if fee := engine.AccruedFees("bob"); !fee.IsZero() {
+		t.Errorf("Expected reset to discard accrued fees, got %s", fee)
+	}
+}
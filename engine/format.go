@@ -0,0 +1,104 @@
+package engine
+
+import (
+	"strconv"
+	"strings"
+)
+
+// The String methods below render market-data events as space-separated
+// key=value pairs for logs and line-oriented wire output. Decimals are written
+// in their exact string form, so no precision is lost, and keys always appear
+// in the order of the struct fields so the output can be parsed reliably.
+
+// String formats the trade, e.g.
+// "trade id=BTC-USD-1 pair=BTC-USD buy=b1 sell=s1 price=100.5 qty=2 ts=1700000000 taker=buy".
+func (t Trade) String() string {
+	var b strings.Builder
+	b.WriteString("trade")
+	field(&b, "id", t.ID)
+	field(&b, "pair", t.Pair)
+	field(&b, "buy", t.BuyOrderID)
+	field(&b, "sell", t.SellOrderID)
+	field(&b, "price", t.Price.String())
+	field(&b, "qty", t.Qty.String())
+	field(&b, "ts", strconv.FormatInt(t.Timestamp, 10))
+	field(&b, "taker", string(t.TakerSide))
+	return b.String()
+}
+
+// String formats the fill, e.g.
+// "fill order=b1 pair=BTC-USD side=buy orig=2 exec=1 rem=1 price=100 fill_price=100 status=PARTIALLY_FILLED ts=1700000000".
+// The reason is appended only when set, as for rejections.
+func (f OrderFill) String() string {
+	var b strings.Builder
+	b.WriteString("fill")
+	field(&b, "order", f.OrderID)
+	field(&b, "pair", f.Pair)
+	field(&b, "side", string(f.Side))
+	field(&b, "orig", f.OriginalQty.String())
+	field(&b, "exec", f.ExecutedQty.String())
+	field(&b, "rem", f.RemainingQty.String())
+	field(&b, "price", f.Price.String())
+	field(&b, "fill_price", f.FillPrice.String())
+	field(&b, "status", string(f.Status))
+	field(&b, "ts", strconv.FormatInt(f.Timestamp, 10))
+	if f.Reason != "" {
+		field(&b, "reason", string(f.Reason))
+	}
+	return b.String()
+}
+
+// String formats the price update, e.g.
+// "price pair=BTC-USD bid=99 ask=101 avg=100 bid_qty=3 ask_qty=1 mid=100 ts=1700000000".
+func (p PriceUpdate) String() string {
+	var b strings.Builder
+	b.WriteString("price")
+	field(&b, "pair", p.Pair)
+	field(&b, "bid", p.BestBid.String())
+	field(&b, "ask", p.BestAsk.String())
+	field(&b, "avg", p.AvgPrice.String())
+	field(&b, "bid_qty", p.BidQty.String())
+	field(&b, "ask_qty", p.AskQty.String())
+	field(&b, "mid", p.Mid.String())
+	field(&b, "ts", strconv.FormatInt(p.Timestamp, 10))
+	return b.String()
+}
+
+// String formats the depth update with each level written as quantity@price,
+// best level first, e.g.
+// "depth pair=BTC-USD bids=[3@99,1@98] asks=[1@101] ts=1700000000 trades=12".
+func (d DepthUpdate) String() string {
+	var b strings.Builder
+	b.WriteString("depth")
+	field(&b, "pair", d.Pair)
+	field(&b, "bids", formatLevels(d.Bids))
+	field(&b, "asks", formatLevels(d.Asks))
+	field(&b, "ts", strconv.FormatInt(d.Timestamp, 10))
+	field(&b, "trades", strconv.FormatInt(d.TradeCount, 10))
+	return b.String()
+}
+
+// formatLevels writes levels as a bracketed, comma-separated list of
+// quantity@price entries.
+func formatLevels(levels []DepthLevel) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, level := range levels {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(level.Quantity.String())
+		b.WriteByte('@')
+		b.WriteString(level.Price.String())
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// field appends " key=value" to b.
+func field(b *strings.Builder, key, value string) {
+	b.WriteByte(' ')
+	b.WriteString(key)
+	b.WriteByte('=')
+	b.WriteString(value)
+}
@@ -0,0 +1,87 @@
+package engine
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// TestTradeString tests the formatted output of a trade
+func TestTradeString(t *testing.T) {
+	trade := Trade{
+		ID:          "BTC-USD-1",
+		Pair:        "BTC-USD",
+		BuyOrderID:  "b1",
+		SellOrderID: "s1",
+		Price:       decimal.RequireFromString("50000.123456789"),
+		Qty:         decimal.RequireFromString("0.00000001"),
+		Timestamp:   1700000000,
+		TakerSide:   Buy,
+	}
+	expected := "trade id=BTC-USD-1 pair=BTC-USD buy=b1 sell=s1 price=50000.123456789 qty=0.00000001 ts=1700000000 taker=buy"
+	if got := fmt.Sprint(trade); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+// TestOrderFillString tests the formatted output of fills, with the reason only on rejections
+func TestOrderFillString(t *testing.T) {
+	fill := OrderFill{
+		OrderID:      "b1",
+		Pair:         "BTC-USD",
+		Side:         Buy,
+		OriginalQty:  decimal.NewFromInt(2),
+		ExecutedQty:  decimal.NewFromInt(1),
+		RemainingQty: decimal.NewFromInt(1),
+		Price:        decimal.RequireFromString("100.5"),
+		FillPrice:    decimal.NewFromInt(100),
+		Status:       PartiallyFilled,
+		Timestamp:    1700000000,
+	}
+	expected := "fill order=b1 pair=BTC-USD side=buy orig=2 exec=1 rem=1 price=100.5 fill_price=100 status=PARTIALLY_FILLED ts=1700000000"
+	if got := fill.String(); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	fill.Status, fill.Reason = Rejected, RejectHalted
+	expected = "fill order=b1 pair=BTC-USD side=buy orig=2 exec=1 rem=1 price=100.5 fill_price=100 status=REJECTED ts=1700000000 reason=HALTED"
+	if got := fill.String(); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+// TestPriceUpdateString tests the formatted output of a price update
+func TestPriceUpdateString(t *testing.T) {
+	update := PriceUpdate{
+		Pair:      "BTC-USD",
+		BestBid:   decimal.NewFromInt(99),
+		BestAsk:   decimal.NewFromInt(101),
+		AvgPrice:  decimal.RequireFromString("100.25"),
+		BidQty:    decimal.NewFromInt(3),
+		AskQty:    decimal.RequireFromString("0.5"),
+		Mid:       decimal.NewFromInt(100),
+		Timestamp: 1700000000,
+	}
+	expected := "price pair=BTC-USD bid=99 ask=101 avg=100.25 bid_qty=3 ask_qty=0.5 mid=100 ts=1700000000"
+	if got := update.String(); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+// TestDepthUpdateString tests the formatted output of a depth update, including an empty side
+func TestDepthUpdateString(t *testing.T) {
+	update := DepthUpdate{
+		Pair: "BTC-USD",
+		Bids: []DepthLevel{
+			{Price: decimal.NewFromInt(99), Quantity: decimal.NewFromInt(3)},
+			{Price: decimal.RequireFromString("98.5"), Quantity: decimal.NewFromInt(1)},
+		},
+		Timestamp:  1700000000,
+		TradeCount: 12,
+	}
+	expected := "depth pair=BTC-USD bids=[3@99,1@98.5] asks=[] ts=1700000000 trades=12"
+	if got := update.String(); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
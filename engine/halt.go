@@ -0,0 +1,328 @@
+package engine
+
+import (
+	"container/heap"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// LULDConfig configures a limit-up/limit-down circuit breaker for a pair. When
+// a trade moves the price by more than Percent from any price traded within
+// the preceding Window, the pair is halted for Cooldown. A zero Percent or
+// Window disables the breaker.
+type LULDConfig struct {
+	Percent  decimal.Decimal `json:"percent"`  // Largest allowed move within the window, in percent
+	Window   time.Duration   `json:"window"`   // Span over which price moves are measured
+	Cooldown time.Duration   `json:"cooldown"` // How long the pair stays halted once tripped
+}
+
+// enabled reports whether the breaker is configured.
+func (c LULDConfig) enabled() bool {
+	return c.Percent.IsPositive() && c.Window > 0
+}
+
+// HaltReason explains why trading in a pair was halted.
+type HaltReason string
+
+const (
+	// HaltManual indicates the pair was halted by Halt and stays halted until
+	// Resume is called.
+	HaltManual HaltReason = "MANUAL"
+	// HaltLULD indicates the pair's limit-up/limit-down breaker tripped. The
+	// pair resumes automatically, publishing its resume event, once the
+	// cooldown has passed.
+	HaltLULD HaltReason = "LULD"
+)
+
+// HaltEvent reports that trading in a pair was halted or resumed.
+type HaltEvent struct {
+	Pair      string          `json:"pair"`                // Trading pair identifier
+	Halted    bool            `json:"halted"`              // True when trading stopped, false when it resumed
+	Reason    HaltReason      `json:"reason"`              // Why the pair was halted
+	Price     decimal.Decimal `json:"price"`               // Reference price that tripped the breaker, zero otherwise
+	ResumeAt  int64           `json:"resume_at,omitempty"` // Unix timestamp of the automatic resumption, if any
+	Timestamp int64           `json:"timestamp"`           // Unix timestamp of the change
+}
+
+// haltState is the trading status of a book together with the recent
+// reference prices the LULD breaker measures moves against.
+type haltState struct {
+	halted   bool
+	reason   HaltReason
+	resumeAt int64 // Zero while halted until an explicit resume
+	prices   []pricePoint
+}
+
+// pricePoint is a reference price observed by the LULD breaker.
+type pricePoint struct {
+	price     decimal.Decimal
+	timestamp int64
+}
+
+// Halted reports whether trading in the book is halted. A breaker halt whose
+// cooldown has passed is no longer reported as halted.
+func (ob *OrderBook) Halted() bool {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+	return ob.halted(ob.clock.Now().Unix())
+}
+
+// halted reports whether the book is halted at now. The caller must hold
+// ob.mutex.
+func (ob *OrderBook) halted(now int64) bool {
+	return ob.halt.halted && (ob.halt.resumeAt == 0 || now < ob.halt.resumeAt)
+}
+
+// stop halts the book for reason until resumeAt, or until resumed if resumeAt
+// is zero. It reports false if the book is already halted manually.
+func (ob *OrderBook) stop(reason HaltReason, resumeAt int64) (HaltEvent, bool) {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+	return ob.setHalt(reason, decimal.Zero, resumeAt)
+}
+
+// setHalt is stop for a caller that holds ob.mutex. price records the trade
+// that caused the halt, if any.
+func (ob *OrderBook) setHalt(reason HaltReason, price decimal.Decimal, resumeAt int64) (HaltEvent, bool) {
+	if ob.halt.halted && ob.halt.reason == HaltManual {
+		return HaltEvent{}, false
+	}
+	ob.halt = haltState{halted: true, reason: reason, resumeAt: resumeAt}
+	return HaltEvent{
+		Pair:      ob.Pair,
+		Halted:    true,
+		Reason:    reason,
+		Price:     price,
+		ResumeAt:  resumeAt,
+		Timestamp: ob.clock.Now().Unix(),
+	}, true
+}
+
+// resume lifts the book's halt. With due set only a breaker halt whose
+// cooldown has passed is lifted, and the event is stamped with the end of the
+// cooldown. It reports false if nothing was lifted.
+func (ob *OrderBook) resume(due bool) (HaltEvent, bool) {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	now := ob.clock.Now().Unix()
+	if !ob.halt.halted || due && (ob.halt.resumeAt == 0 || now < ob.halt.resumeAt) {
+		return HaltEvent{}, false
+	}
+	if due {
+		now = ob.halt.resumeAt
+	}
+	reason := ob.halt.reason
+	ob.halt = haltState{}
+	return HaltEvent{Pair: ob.Pair, Reason: reason, Timestamp: now}, true
+}
+
+// checkBreaker feeds the book's reference price to its LULD breaker and halts
+// the book if it moved too far within the configured window. Books following
+// the last trade feed the price of each of trades; others feed their current
+// reference price if it changed. Matching is not interrupted, so the order
+// that trips the breaker completes; orders arriving afterwards are rejected
+// until the cooldown has passed.
+func (ob *OrderBook) checkBreaker(trades []Trade) (HaltEvent, bool) {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	luld := ob.config.LULD
+	if !luld.enabled() {
+		return HaltEvent{}, false
+	}
+
+	window := int64(luld.Window / time.Second)
+	band := luld.Percent.Div(hundred)
+	for _, point := range ob.breakerPoints(trades) {
+		prices := ob.halt.prices
+		for len(prices) > 0 && prices[0].timestamp <= point.timestamp-window {
+			prices = prices[1:]
+		}
+		for _, p := range prices {
+			if point.price.Sub(p.price).Abs().GreaterThan(p.price.Mul(band)) {
+				resumeAt := ob.clock.Now().Add(luld.Cooldown).Unix()
+				return ob.setHalt(HaltLULD, point.price, resumeAt)
+			}
+		}
+		ob.halt.prices = append(prices, point)
+	}
+	return HaltEvent{}, false
+}
+
+// breakerPoints returns the reference prices checkBreaker feeds to the
+// breaker. The caller must hold ob.mutex.
+func (ob *OrderBook) breakerPoints(trades []Trade) []pricePoint {
+	if !ob.config.ReferencePrice.tracksTrades() {
+		price, ok := ob.referencePrice()
+		if prices := ob.halt.prices; !ok || len(prices) > 0 && prices[len(prices)-1].price.Equal(price) {
+			return nil
+		}
+		return []pricePoint{{price: price, timestamp: ob.clock.Now().Unix()}}
+	}
+	points := make([]pricePoint, 0, len(trades))
+	for _, trade := range trades {
+		points = append(points, pricePoint{price: trade.Price, timestamp: trade.Timestamp})
+	}
+	return points
+}
+
+// Uncross matches resting orders that cross each other until the best bid is
+// below the best ask, as can happen after a halt or after orders were loaded
+// with LoadResting or Restore. It returns the generated trades and fills.
+//
+// The pass is deterministic and follows price-time priority: while the book
+// is crossed, the later-arriving of the best bid and best ask is taken off
+// the book and matched as if it had just arrived, trading at the prices of
+// the earlier orders. A remainder rests again under its original sequence
+// number, so it keeps its time priority. The pass stops early if the later
+// order cannot trade, for example because it is all-or-none or the match
+// policy passes over every maker.
+func (ob *OrderBook) Uncross() MatchResult {
+	var res MatchResult
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	ob.uncross(&res)
+	ob.takeEvents(&res)
+	return res
+}
+
+// uncrossInto is Uncross appending its events to res.
+func (ob *OrderBook) uncrossInto(res *MatchResult) {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	ob.uncross(res)
+	ob.takeEvents(res)
+}
+
+// uncross runs the Uncross pass. The caller must hold ob.mutex.
+func (ob *OrderBook) uncross(res *MatchResult) {
+	t := ob.clock.Now()
+	ob.stamp(t)
+	now := t.Unix()
+	start := len(res.Trades)
+	for len(ob.bids.orderHeap) > 0 && len(ob.asks.orderHeap) > 0 {
+		bid, ask := ob.bids.orderHeap[0], ob.asks.orderHeap[0]
+		if comparePrices(bid, ask) < 0 {
+			break
+		}
+		var h heap.Interface = ob.bids
+		if ask.Seq > bid.Seq {
+			h = ob.asks
+		}
+		o := heap.Pop(h).(*Order)
+		ob.addVolume(o.Side, o.Qty.Neg())
+		taker := *o
+		releaseOrder(o)
+
+		qty := taker.Qty
+		if !taker.AON || ob.fillsCompletely(taker, now) {
+			ob.match(res, &taker, now)
+		}
+		if taker.Qty.Equal(qty) {
+			ob.rest(taker)
+			break
+		}
+		if taker.Qty.IsZero() {
+			ob.emit(Removed, taker)
+		} else {
+			ob.rest(taker)
+			ob.emit(Reduced, taker)
+		}
+	}
+	ob.refreshTop()
+	ob.trailStops(res, start)
+}
+
+// WithUncrossOnResume makes the engine run an Uncross pass whenever a pair
+// resumes trading, whether through Resume or at the end of a breaker
+// cooldown, so a resumed book is never left crossed. The resulting trades
+// are journaled and published like any other.
+func WithUncrossOnResume() Option {
+	return func(e *Engine) {
+		e.uncrossOnResume = true
+	}
+}
+
+// Halt stops trading in the specified pair until Resume is called. Orders for
+// a halted pair are rejected with RejectHalted; cancels are still accepted.
+// Returns ErrPairNotFound if the pair has no order book.
+func (e *Engine) Halt(pair string) error {
+	if _, exists := e.book(pair); !exists {
+		return ErrPairNotFound
+	}
+
+	return e.submit(pair, Order{}, func(book *OrderBook, res *MatchResult) error {
+		if event, ok := book.stop(HaltManual, 0); ok {
+			res.Halts = append(res.Halts, event)
+		}
+		return nil
+	})
+}
+
+// Resume lifts a halt on the specified pair, whether it was halted manually
+// or by its LULD breaker. With WithUncrossOnResume, crossed resting orders
+// are then matched against each other. Returns ErrPairNotFound if the pair has
+// no order book.
+func (e *Engine) Resume(pair string) error {
+	if _, exists := e.book(pair); !exists {
+		return ErrPairNotFound
+	}
+
+	return e.submit(pair, Order{}, func(book *OrderBook, res *MatchResult) error {
+		if event, ok := book.resume(false); ok {
+			res.Halts = append(res.Halts, event)
+			e.uncrossResumed(book, res)
+		}
+		return nil
+	})
+}
+
+// uncrossResumed runs the Uncross pass on a book that has just resumed
+// trading if the engine is configured to, and settles the resulting trades.
+func (e *Engine) uncrossResumed(book *OrderBook, res *MatchResult) {
+	if !e.uncrossOnResume {
+		return
+	}
+	book.uncrossInto(res)
+	e.settle(book, res)
+}
+
+// scheduleResume arranges for a breaker halt of pair to be lifted at
+// resumeAt, so the resumption and any uncross are published when the
+// cooldown ends rather than with the next order for the pair. The timer
+// follows the wall clock; whichever of it and the next job reaches the shard
+// first after the cooldown lifts the halt. A book removed or reset in the
+// meantime is left alone and not created again.
+func (e *Engine) scheduleResume(pair string, resumeAt int64) {
+	tripped, exists := e.book(pair)
+	if !exists {
+		return
+	}
+	time.AfterFunc(time.Unix(resumeAt, 0).Sub(e.clock.Now()), func() {
+		_ = e.submitDirect(pair, func(book *OrderBook, res *MatchResult) error {
+			if book != tripped {
+				return nil
+			}
+			if event, ok := book.resume(true); ok {
+				res.Halts = append(res.Halts, event)
+				e.uncrossResumed(book, res)
+				e.publish(res)
+			}
+			return nil
+		})
+	})
+}
+
+// IsHalted reports whether trading in the specified pair is halted. Returns
+// false if the pair has no book.
+func (e *Engine) IsHalted(pair string) bool {
+	book, exists := e.book(pair)
+	if !exists {
+		return false
+	}
+	return book.Halted()
+}
@@ -0,0 +1,254 @@
+package engine
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// TestLULDBreaker tests that a rapid price spike halts the pair until the cooldown passes
+func TestLULDBreaker(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC))
+	engine := NewEngine(WithClock(clock))
+	pair := "BTC-USD"
+	engine.SetPairConfig(pair, PairConfig{LULD: LULDConfig{
+		Percent:  decimal.NewFromInt(10),
+		Window:   time.Minute,
+		Cooldown: 5 * time.Minute,
+	}})
+
+	trade := func(id string, price int64) {
+		engine.AddOrder(pair, Order{ID: id + "-ask", Side: Sell, Price: decimal.NewFromInt(price), Qty: decimal.NewFromInt(1)})
+		engine.AddOrder(pair, Order{ID: id + "-bid", Side: Buy, Price: decimal.NewFromInt(price), Qty: decimal.NewFromInt(1)})
+	}
+
+	trade("t1", 100)
+	clock.Advance(10 * time.Second)
+	trade("t2", 105)
+	if engine.IsHalted(pair) {
+		t.Fatal("Expected a 5% move to stay within the band")
+	}
+
+	clock.Advance(10 * time.Second)
+	trade("t3", 115)
+	if !engine.IsHalted(pair) {
+		t.Fatal("Expected a 15% move to trip the breaker")
+	}
+	event := <-engine.HaltEvents
+	if !event.Halted || event.Reason != HaltLULD || !event.Price.Equal(decimal.NewFromInt(115)) {
+		t.Errorf("Expected LULD halt at 115, got %+v", event)
+	}
+	if expected := clock.Now().Add(5 * time.Minute).Unix(); event.ResumeAt != expected {
+		t.Errorf("Expected resume at %d, got %d", expected, event.ResumeAt)
+	}
+
+	drainFills(engine)
+	engine.AddOrder(pair, Order{ID: "halted", Side: Buy, Price: decimal.NewFromInt(115), Qty: decimal.NewFromInt(1)})
+	if fill := <-engine.FillStream; fill.Status != Rejected || fill.Reason != RejectHalted {
+		t.Errorf("Expected order to be rejected while halted, got %+v", fill)
+	}
+
+	clock.Advance(5 * time.Minute)
+	if engine.IsHalted(pair) {
+		t.Fatal("Expected the pair to resume after the cooldown")
+	}
+	engine.AddOrder(pair, Order{ID: "resumed", Side: Buy, Price: decimal.NewFromInt(110), Qty: decimal.NewFromInt(1)})
+	event = <-engine.HaltEvents
+	if event.Halted || event.Reason != HaltLULD {
+		t.Errorf("Expected LULD reset event, got %+v", event)
+	}
+	if fill := <-engine.FillStream; fill.Status != New {
+		t.Errorf("Expected order to be accepted after resuming, got %+v", fill)
+	}
+}
+
+// TestHaltAndResume tests manual halts, which block orders but not cancels and need an existing pair
+func TestHaltAndResume(t *testing.T) {
+	engine := NewEngine()
+	pair := "BTC-USD"
+	engine.AddOrder(pair, Order{ID: "resting", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+
+	if err := engine.Halt(pair); err != nil {
+		t.Fatalf("Expected halt to succeed, got %v", err)
+	}
+	if event := <-engine.HaltEvents; !event.Halted || event.Reason != HaltManual {
+		t.Errorf("Expected manual halt event, got %+v", event)
+	}
+
+	drainFills(engine)
+	engine.AddOrder(pair, Order{ID: "new", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	if fill := <-engine.FillStream; fill.Reason != RejectHalted {
+		t.Errorf("Expected order to be rejected while halted, got %+v", fill)
+	}
+	if err := engine.CancelOrder(pair, "resting"); err != nil {
+		t.Errorf("Expected cancel to be accepted while halted, got %v", err)
+	}
+
+	if err := engine.Resume(pair); err != nil {
+		t.Fatalf("Expected resume to succeed, got %v", err)
+	}
+	if event := <-engine.HaltEvents; event.Halted {
+		t.Errorf("Expected resume event, got %+v", event)
+	}
+	if engine.IsHalted(pair) {
+		t.Error("Expected pair to be trading after resume")
+	}
+
+	if err := engine.Halt("ETH-USD"); err != ErrPairNotFound {
+		t.Errorf("Expected ErrPairNotFound halting an unknown pair, got %v", err)
+	}
+	if err := engine.Resume("ETH-USD"); err != ErrPairNotFound {
+		t.Errorf("Expected ErrPairNotFound resuming an unknown pair, got %v", err)
+	}
+	if pairs := engine.Pairs(); len(pairs) != 1 {
+		t.Errorf("Expected no book created for the unknown pair, got %v", pairs)
+	}
+}
+
+// TestUncross tests that crossed resting orders match in price-time priority and leave a clean book
+func TestUncross(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	ob.LoadResting([]Order{
+		{ID: "bid1", Side: Buy, Price: decimal.NewFromInt(102), Qty: decimal.NewFromInt(1)},
+		{ID: "ask1", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)},
+		{ID: "ask2", Side: Sell, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(2)},
+		{ID: "bid2", Side: Buy, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(3)},
+		{ID: "bid3", Side: Buy, Price: decimal.NewFromInt(99), Qty: decimal.NewFromInt(1)},
+	})
+
+	res := ob.Uncross()
+	expected := []struct {
+		buy, sell string
+		price     int64
+		qty       int64
+	}{
+		// ask1 arrived after bid1 and takes it at bid1's price
+		{"bid1", "ask1", 102, 1},
+		// bid2 arrived after ask2 and takes it at ask2's price
+		{"bid2", "ask2", 101, 2},
+	}
+	if len(res.Trades) != len(expected) {
+		t.Fatalf("Expected %d trades, got %+v", len(expected), res.Trades)
+	}
+	for i, e := range expected {
+		trade := res.Trades[i]
+		if trade.BuyOrderID != e.buy || trade.SellOrderID != e.sell || !trade.Price.Equal(decimal.NewFromInt(e.price)) || !trade.Qty.Equal(decimal.NewFromInt(e.qty)) {
+			t.Errorf("Expected trade %d to be %d %s/%s @ %d, got %+v", i, e.qty, e.buy, e.sell, e.price, trade)
+		}
+	}
+
+	if err := ob.Validate(); err != nil {
+		t.Errorf("Expected a valid book, got %v", err)
+	}
+	bid, ask, bidQty, _ := ob.TopOfBook()
+	if !bid.Equal(decimal.NewFromInt(101)) || !bidQty.Equal(decimal.NewFromInt(1)) || !ask.IsZero() {
+		t.Errorf("Expected the remaining 1 of bid2 at 101 and no asks, got %s @ %s and ask %s", bidQty, bid, ask)
+	}
+	if again := ob.Uncross(); len(again.Trades) != 0 {
+		t.Errorf("Expected an uncrossed book to stay put, got %+v", again.Trades)
+	}
+}
+
+// TestResumeUncrosses tests that WithUncrossOnResume matches a crossed book when the pair resumes
+func TestResumeUncrosses(t *testing.T) {
+	engine := NewEngine(WithUncrossOnResume())
+	pair := "BTC-USD"
+	engine.AddOrder(pair, Order{ID: "bid", Side: Buy, Price: decimal.NewFromInt(99), Qty: decimal.NewFromInt(1)})
+	if err := engine.Halt(pair); err != nil {
+		t.Fatalf("Expected halt to succeed, got %v", err)
+	}
+	book, _ := engine.book(pair)
+	book.LoadResting([]Order{{ID: "ask", Side: Sell, Price: decimal.NewFromInt(98), Qty: decimal.NewFromInt(2)}})
+	drainFills(engine)
+
+	if err := engine.Resume(pair); err != nil {
+		t.Fatalf("Expected resume to succeed, got %v", err)
+	}
+	trade := <-engine.TradeStream
+	if trade.BuyOrderID != "bid" || trade.SellOrderID != "ask" || !trade.Price.Equal(decimal.NewFromInt(99)) {
+		t.Errorf("Expected the later ask to take the bid at 99, got %+v", trade)
+	}
+	if fill := <-engine.FillStream; fill.OrderID != "bid" || fill.Status != Filled {
+		t.Errorf("Expected the bid to be filled, got %+v", fill)
+	}
+	if fill := <-engine.FillStream; fill.OrderID != "ask" || fill.Status != PartiallyFilled {
+		t.Errorf("Expected the ask to be partially filled, got %+v", fill)
+	}
+	if bids, asks, _ := engine.OpenOrderCount(pair); bids != 0 || asks != 1 {
+		t.Errorf("Expected only the ask remainder to rest, got %d bids and %d asks", bids, asks)
+	}
+}
+
+// TestLULDResumesIdlePair tests that a breaker halt is lifted and the book uncrossed when the cooldown ends, without further orders
+func TestLULDResumesIdlePair(t *testing.T) {
+	engine := NewEngine(WithUncrossOnResume())
+	pair := "BTC-USD"
+	engine.SetPairConfig(pair, PairConfig{LULD: LULDConfig{
+		Percent:  decimal.NewFromInt(10),
+		Window:   time.Minute,
+		Cooldown: time.Second,
+	}})
+	engine.AddOrder(pair, Order{ID: "bid", Side: Buy, Price: decimal.NewFromInt(99), Qty: decimal.NewFromInt(1)})
+	for i, price := range []int64{100, 115} {
+		engine.AddOrder(pair, Order{ID: fmt.Sprintf("ask%d", i), Side: Sell, Price: decimal.NewFromInt(price), Qty: decimal.NewFromInt(1)})
+		engine.AddOrder(pair, Order{ID: fmt.Sprintf("buy%d", i), Side: Buy, Price: decimal.NewFromInt(price), Qty: decimal.NewFromInt(1)})
+	}
+	halt := <-engine.HaltEvents
+	if !halt.Halted || halt.ResumeAt == 0 {
+		t.Fatalf("Expected a LULD halt with a resume time, got %+v", halt)
+	}
+	book, _ := engine.book(pair)
+	book.LoadResting([]Order{{ID: "ask", Side: Sell, Price: decimal.NewFromInt(98), Qty: decimal.NewFromInt(1)}})
+	for len(engine.TradeStream) > 0 {
+		<-engine.TradeStream
+	}
+
+	select {
+	case event := <-engine.HaltEvents:
+		if event.Halted || event.Reason != HaltLULD || event.Timestamp != halt.ResumeAt {
+			t.Errorf("Expected a LULD resume stamped %d, got %+v", halt.ResumeAt, event)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Expected the pair to resume when the cooldown ended")
+	}
+	select {
+	case trade := <-engine.TradeStream:
+		if trade.BuyOrderID != "bid" || trade.SellOrderID != "ask" {
+			t.Errorf("Expected the resume to uncross bid and ask, got %+v", trade)
+		}
+	default:
+		t.Error("Expected the resume to uncross the book")
+	}
+}
+
+// TestScheduledResumeSkipsRemovedBook tests that a breaker cooldown ending after the pair was removed neither recreates it nor publishes a resume
+func TestScheduledResumeSkipsRemovedBook(t *testing.T) {
+	engine := NewEngine()
+	pair := "BTC-USD"
+	engine.SetPairConfig(pair, PairConfig{LULD: LULDConfig{
+		Percent:  decimal.NewFromInt(10),
+		Window:   time.Minute,
+		Cooldown: time.Second,
+	}})
+	for i, price := range []int64{100, 115} {
+		engine.AddOrder(pair, Order{ID: fmt.Sprintf("ask%d", i), Side: Sell, Price: decimal.NewFromInt(price), Qty: decimal.NewFromInt(1)})
+		engine.AddOrder(pair, Order{ID: fmt.Sprintf("buy%d", i), Side: Buy, Price: decimal.NewFromInt(price), Qty: decimal.NewFromInt(1)})
+	}
+	halt := <-engine.HaltEvents
+	if !halt.Halted || halt.ResumeAt == 0 {
+		t.Fatalf("Expected a LULD halt with a resume time, got %+v", halt)
+	}
+	if err := engine.RemoveBook(pair); err != nil {
+		t.Fatalf("Expected the book to be removed, got %v", err)
+	}
+
+	time.Sleep(time.Until(time.Unix(halt.ResumeAt, 0)) + 200*time.Millisecond)
+	if pairs := engine.Pairs(); len(pairs) != 0 {
+		t.Errorf("Expected the removed pair to stay removed, got %v", pairs)
+	}
+	if len(engine.HaltEvents) != 0 {
+		t.Errorf("Expected no resume for the removed pair, got %+v", <-engine.HaltEvents)
+	}
+}
@@ -0,0 +1,121 @@
+package engine
+
+const (
+	// defaultTradeHistorySize is the number of recent trades retained per pair.
+	defaultTradeHistorySize = 1000
+
+	// defaultFillHistorySize is the number of recent fills retained per pair.
+	defaultFillHistorySize = 1000
+)
+
+// ring is a fixed-capacity buffer that keeps the most recently added items,
+// overwriting the oldest once full. It is not safe for concurrent use.
+type ring[T any] struct {
+	buf  []T
+	next int
+	full bool
+}
+
+// newRing creates a ring holding at most capacity items.
+func newRing[T any](capacity int) *ring[T] {
+	return &ring[T]{buf: make([]T, capacity)}
+}
+
+// add appends item, evicting the oldest item when the ring is full.
+func (r *ring[T]) add(item T) {
+	if len(r.buf) == 0 {
+		return
+	}
+	r.buf[r.next] = item
+	r.next = (r.next + 1) % len(r.buf)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// len returns the number of items currently held.
+func (r *ring[T]) len() int {
+	if r.full {
+		return len(r.buf)
+	}
+	return r.next
+}
+
+// recent returns up to n items, newest first.
+func (r *ring[T]) recent(n int) []T {
+	if n > r.len() {
+		n = r.len()
+	}
+	if n <= 0 {
+		return []T{}
+	}
+
+	items := make([]T, n)
+	i := r.next
+	for k := 0; k < n; k++ {
+		i = (i - 1 + len(r.buf)) % len(r.buf)
+		items[k] = r.buf[i]
+	}
+	return items
+}
+
+// WithTradeHistory sets how many recent trades are retained per pair for
+// RecentTrades. A size of zero disables retention.
+func WithTradeHistory(size int) Option {
+	return func(e *Engine) {
+		if size >= 0 {
+			e.tradeHistorySize = size
+		}
+	}
+}
+
+// RecentTrades returns up to n of the most recent trades for the specified
+// trading pair, newest first. Fewer than n trades are returned if the pair has
+// not traded that often or the retention limit is smaller than n.
+func (e *Engine) RecentTrades(pair string, n int) []Trade {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	history := e.tradeHistory[pair]
+	if history == nil {
+		return []Trade{}
+	}
+	return history.recent(n)
+}
+
+// WithFillHistory sets how many recent fill events are retained per pair for
+// RecentFills and ExportFills. A size of zero disables retention.
+func WithFillHistory(size int) Option {
+	return func(e *Engine) {
+		if size >= 0 {
+			e.fillHistorySize = size
+		}
+	}
+}
+
+// recordFill adds a published fill to its pair's history.
+func (e *Engine) recordFill(fill OrderFill) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	history := e.fillHistory[fill.Pair]
+	if history == nil {
+		history = newRing[OrderFill](e.fillHistorySize)
+		e.fillHistory[fill.Pair] = history
+	}
+	history.add(fill)
+}
+
+// RecentFills returns up to n of the most recent fill events for the
+// specified trading pair, newest first, within the retention limit set by
+// WithFillHistory.
+func (e *Engine) RecentFills(pair string, n int) []OrderFill {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	history := e.fillHistory[pair]
+	if history == nil {
+		return []OrderFill{}
+	}
+	return history.recent(n)
+}
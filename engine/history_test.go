@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// TestRecentTrades tests that the most recent trades are returned newest first
+func TestRecentTrades(t *testing.T) {
+	engine := NewEngine(WithTradeHistory(3))
+	pair := "BTC-USD"
+
+	if trades := engine.RecentTrades(pair, 5); len(trades) != 0 {
+		t.Errorf("Expected no trades for unknown pair, got %d", len(trades))
+	}
+
+	for i := 0; i < 5; i++ {
+		engine.AddOrder(pair, Order{ID: fmt.Sprintf("sell%d", i), Side: Sell, Price: decimal.NewFromInt(int64(100 + i)), Qty: decimal.NewFromInt(1)})
+		engine.AddOrder(pair, Order{ID: fmt.Sprintf("buy%d", i), Side: Buy, Price: decimal.NewFromInt(int64(100 + i)), Qty: decimal.NewFromInt(1)})
+	}
+
+	trades := engine.RecentTrades(pair, 10)
+	if len(trades) != 3 {
+		t.Fatalf("Expected retention to cap history at 3 trades, got %d", len(trades))
+	}
+	for i, expected := range []string{"buy4", "buy3", "buy2"} {
+		if trades[i].BuyOrderID != expected {
+			t.Errorf("Expected trade %d to be %s, got %s", i, expected, trades[i].BuyOrderID)
+		}
+	}
+
+	if trades := engine.RecentTrades(pair, 2); len(trades) != 2 || trades[0].BuyOrderID != "buy4" {
+		t.Errorf("Expected the 2 newest trades, got %+v", trades)
+	}
+}
+
+// TestRing tests the ring buffer wrap-around
+func TestRing(t *testing.T) {
+	r := newRing[int](3)
+	if got := r.recent(2); len(got) != 0 {
+		t.Errorf("Expected empty ring, got %v", got)
+	}
+	for i := 1; i <= 4; i++ {
+		r.add(i)
+	}
+	if got := fmt.Sprint(r.recent(5)); got != "[4 3 2]" {
+		t.Errorf("Expected [4 3 2], got %s", got)
+	}
+
+	disabled := newRing[int](0)
+	disabled.add(1)
+	if got := disabled.recent(1); len(got) != 0 {
+		t.Errorf("Expected zero-capacity ring to stay empty, got %v", got)
+	}
+}
@@ -0,0 +1,58 @@
+package engine
+
+import (
+	"fmt"
+	"sync"
+)
+
+// preTradeHooks holds the hooks registered with UsePreTrade.
+type preTradeHooks struct {
+	mutex sync.Mutex
+	fns   []func(*Order) error
+}
+
+// add registers fn. The slice is copied on write so that run can iterate a
+// snapshot without holding the lock.
+func (h *preTradeHooks) add(fn func(*Order) error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.fns = append(h.fns[:len(h.fns):len(h.fns)], fn)
+}
+
+// run passes order through every hook in registration order, stopping at the
+// first that returns an error. A hook that panics rejects the order.
+func (h *preTradeHooks) run(order *Order) (err error) {
+	h.mutex.Lock()
+	fns := h.fns
+	h.mutex.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("engine: pre-trade hook panicked: %v", r)
+		}
+	}()
+	for _, fn := range fns {
+		if err := fn(order); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UsePreTrade registers fn to run on every order before it is rounded,
+// checked against the pair's trading rules and matched, for risk checks or
+// normalization. fn may modify the order; returning an error rejects it with
+// a Rejected fill carrying RejectPreTrade, and it never reaches the book.
+// Hooks run in registration order on the pair's shard, after the halt and
+// rate limit checks, so they should return quickly.
+func (e *Engine) UsePreTrade(fn func(*Order) error) {
+	e.preTrade.add(fn)
+}
+
+// UsePostTrade registers fn to be called for every trade once the order that
+// produced it has been matched and the trade journaled, before the trade is
+// published to TradeStream and OnTrade listeners. Hooks run in registration
+// order on the pair's shard; a hook that panics is recovered.
+func (e *Engine) UsePostTrade(fn func(Trade)) {
+	e.postTrade.add(fn)
+}
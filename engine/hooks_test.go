@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// TestPreTradeHookRejects tests that a pre-trade hook can refuse oversized orders before they match
+func TestPreTradeHookRejects(t *testing.T) {
+	engine := NewEngine()
+	pair := "BTC-USD"
+	maxQty := decimal.NewFromInt(5)
+	engine.UsePreTrade(func(order *Order) error {
+		if order.Qty.GreaterThan(maxQty) {
+			return errors.New("order too large")
+		}
+		return nil
+	})
+
+	engine.AddOrder(pair, Order{ID: "ask", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(10)})
+	if fill := <-engine.FillStream; fill.OrderID != "ask" || fill.Status != Rejected || fill.Reason != RejectPreTrade {
+		t.Errorf("Expected the oversized ask to be rejected by the hook, got %+v", fill)
+	}
+	engine.AddOrder(pair, Order{ID: "ask2", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(5)})
+	engine.AddOrder(pair, Order{ID: "buy", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(6)})
+	drainFills(engine)
+
+	if len(engine.TradeStream) != 0 {
+		t.Errorf("Expected rejected orders never to match, got %d trades", len(engine.TradeStream))
+	}
+	if _, asks, _ := engine.OpenOrderCount(pair); asks != 1 {
+		t.Errorf("Expected only the accepted ask to rest, got %d asks", asks)
+	}
+	if _, err := engine.SubmitOrder(pair, Order{ID: "big", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(6)}); err != ErrOrderRejected {
+		t.Errorf("Expected SubmitOrder to report the rejection, got %v", err)
+	}
+}
+
+// TestTradeHooksRunInOrder tests that pre-trade hooks can modify orders and that hooks run in registration order
+func TestTradeHooksRunInOrder(t *testing.T) {
+	engine := NewEngine()
+	pair := "BTC-USD"
+	var calls []string
+	engine.UsePreTrade(func(order *Order) error {
+		calls = append(calls, "first")
+		order.Account = "desk-" + order.Account
+		return nil
+	})
+	engine.UsePreTrade(func(order *Order) error {
+		calls = append(calls, "second:"+order.Account)
+		return nil
+	})
+	var trades []Trade
+	engine.UsePostTrade(func(trade Trade) {
+		trades = append(trades, trade)
+	})
+
+	engine.AddOrder(pair, Order{ID: "ask", Account: "a", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	engine.AddOrder(pair, Order{ID: "bid", Account: "b", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+
+	if len(calls) != 4 || calls[0] != "first" || calls[1] != "second:desk-a" || calls[3] != "second:desk-b" {
+		t.Errorf("Expected hooks in registration order to see the modified order, got %v", calls)
+	}
+	if len(trades) != 1 || trades[0].SellOrderID != "ask" || trades[0].BuyOrderID != "bid" {
+		t.Errorf("Expected the post-trade hook to see the trade, got %+v", trades)
+	}
+	ack := <-engine.FillStream
+	if ack.Account != "desk-a" {
+		t.Errorf("Expected the modified account to reach the book, got %q", ack.Account)
+	}
+}
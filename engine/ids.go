@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"strconv"
+	"strings"
+)
+
+// orderIDPrefix starts every order ID the engine generates. Trade IDs use a
+// different form, so the two never collide.
+const orderIDPrefix = "O"
+
+// assignID gives order a server-generated ID if it has none. Generated IDs
+// are an engine-wide sequence and never repeat within an engine, including
+// one rebuilt with Replay or Recover.
+func (e *Engine) assignID(order *Order) {
+	if order.ID == "" {
+		order.ID = orderIDPrefix + strconv.FormatUint(e.orderCounter.Add(1), 10)
+	}
+}
+
+// observeID advances the order ID sequence past id if id is one the engine
+// generated, so that recovered orders keep unique IDs.
+func (e *Engine) observeID(id string) {
+	n, err := strconv.ParseUint(strings.TrimPrefix(id, orderIDPrefix), 10, 64)
+	if err != nil || !strings.HasPrefix(id, orderIDPrefix) {
+		return
+	}
+	for {
+		current := e.orderCounter.Load()
+		if current >= n || e.orderCounter.CompareAndSwap(current, n) {
+			return
+		}
+	}
+}
+
+// Order returns a copy of the resting order or pending stop with the given
+// ID. If no order has that ID, id is matched against client order IDs
+// instead, and the earliest order carrying it is returned. It returns false
+// if neither matches.
+func (ob *OrderBook) Order(id string) (Order, bool) {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+	return ob.lookup(id)
+}
+
+// resolve returns the ID of the order that id refers to, by ID or client
+// order ID as Order does.
+func (ob *OrderBook) resolve(id string) (string, bool) {
+	order, ok := ob.Order(id)
+	return order.ID, ok
+}
+
+// lookup is Order for a caller that holds ob.mutex.
+func (ob *OrderBook) lookup(id string) (Order, bool) {
+	if i := ob.bids.indexOf(id); i >= 0 {
+		return *ob.bids.orderHeap[i], true
+	}
+	if i := ob.asks.indexOf(id); i >= 0 {
+		return *ob.asks.orderHeap[i], true
+	}
+	for _, s := range ob.stops {
+		if s.order.ID == id {
+			return s.order, true
+		}
+	}
+
+	var found *Order
+	check := func(o *Order) {
+		if o.ClientOrderID == id && (found == nil || o.Seq < found.Seq) {
+			found = o
+		}
+	}
+	for _, o := range ob.bids.orderHeap {
+		check(o)
+	}
+	for _, o := range ob.asks.orderHeap {
+		check(o)
+	}
+	for _, s := range ob.stops {
+		check(&s.order)
+	}
+	if found == nil {
+		return Order{}, false
+	}
+	return *found, true
+}
+
+// SubmitOrder is like AddOrder but returns the ID the order was entered
+// under. An order without an ID is given a unique server-generated one,
+// while its ClientOrderID is passed through untouched for the caller's own
+// correlation; CancelOrder, ReduceOrder, ReplaceOrder and GetOrder accept
+// either.
+//
+// Returns ErrOrderRejected if the order was refused, in which case the
+// Rejected fill on FillStream carries the reason, or ErrEngineClosed if
+// Close has been called.
+func (e *Engine) SubmitOrder(pair string, order Order) (string, error) {
+	e.assignID(&order)
+	err := e.submit(pair, Order{}, func(book *OrderBook, res *MatchResult) error {
+		if !e.processOrder(book, order, res) {
+			return ErrOrderRejected
+		}
+		return nil
+	})
+	if err == ErrEngineClosed {
+		return "", err
+	}
+	return order.ID, err
+}
+
+// GetOrder returns a copy of the resting order or pending stop in the book
+// of the specified trading pair whose ID or, failing that, client order ID
+// is id. It returns false if the pair has no book or no order matches.
+func (e *Engine) GetOrder(pair, id string) (Order, bool) {
+	book, exists := e.book(pair)
+	if !exists {
+		return Order{}, false
+	}
+	return book.Order(id)
+}
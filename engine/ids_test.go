@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// TestSubmitOrderAssignsID tests that an order without an ID gets a server ID and resolves by either ID
+func TestSubmitOrderAssignsID(t *testing.T) {
+	engine := NewEngine()
+	pair := "BTC-USD"
+
+	id, err := engine.SubmitOrder(pair, Order{ClientOrderID: "my-bid", Side: Buy, Price: decimal.NewFromInt(99), Qty: decimal.NewFromInt(2)})
+	if err != nil || id == "" || id == "my-bid" {
+		t.Fatalf("Expected a server-assigned ID, got %q and %v", id, err)
+	}
+	if fill := <-engine.FillStream; fill.OrderID != id || fill.ClientOrderID != "my-bid" || fill.Status != New {
+		t.Errorf("Expected the acknowledgment to carry both IDs, got %+v", fill)
+	}
+
+	byServer, ok := engine.GetOrder(pair, id)
+	if !ok || byServer.ClientOrderID != "my-bid" {
+		t.Fatalf("Expected to find the order by server ID, got %+v", byServer)
+	}
+	byClient, ok := engine.GetOrder(pair, "my-bid")
+	if !ok || byClient.ID != id {
+		t.Errorf("Expected the client ID to resolve to %s, got %+v", id, byClient)
+	}
+
+	other, err := engine.SubmitOrder(pair, Order{Side: Buy, Price: decimal.NewFromInt(98), Qty: decimal.NewFromInt(1)})
+	if err != nil || other == id {
+		t.Errorf("Expected a distinct server ID, got %q and %v", other, err)
+	}
+	if err := engine.ReduceOrder(pair, "my-bid", decimal.NewFromInt(1)); err != nil {
+		t.Errorf("Expected reduce by client ID to succeed, got %v", err)
+	}
+	if err := engine.CancelOrder(pair, "my-bid"); err != nil {
+		t.Errorf("Expected cancel by client ID to succeed, got %v", err)
+	}
+	if _, ok := engine.GetOrder(pair, id); ok {
+		t.Error("Expected the order to be gone after the cancel")
+	}
+	if bids, _, _ := engine.OpenOrderCount(pair); bids != 1 {
+		t.Errorf("Expected only the other bid to rest, got %d bids", bids)
+	}
+}
+
+// TestSubmitOrderRejected tests that SubmitOrder reports a refused order
+func TestSubmitOrderRejected(t *testing.T) {
+	engine := NewEngine()
+	id, err := engine.SubmitOrder("BTC-USD", Order{ID: "bad", Side: Buy, Price: decimal.NewFromInt(99), Qty: decimal.Zero})
+	if err != ErrOrderRejected || id != "bad" {
+		t.Errorf("Expected ErrOrderRejected for bad, got %q and %v", id, err)
+	}
+}
+
+// TestGeneratedIDsSurviveReplay tests that IDs generated after a journal replay do not repeat replayed ones
+func TestGeneratedIDsSurviveReplay(t *testing.T) {
+	journal, err := NewFileJournal(filepath.Join(t.TempDir(), "engine.journal"))
+	if err != nil {
+		t.Fatalf("Expected journal to open, got %v", err)
+	}
+	defer journal.Close()
+	engine := NewEngine(WithJournal(journal))
+	first, _ := engine.SubmitOrder("BTC-USD", Order{Side: Buy, Price: decimal.NewFromInt(99), Qty: decimal.NewFromInt(1)})
+
+	replayed := NewEngine()
+	if err := replayed.Replay(journal); err != nil {
+		t.Fatalf("Expected replay to succeed, got %v", err)
+	}
+	next, _ := replayed.SubmitOrder("BTC-USD", Order{Side: Buy, Price: decimal.NewFromInt(98), Qty: decimal.NewFromInt(1)})
+	if next == first {
+		t.Errorf("Expected a new ID after replay, got %s twice", next)
+	}
+}
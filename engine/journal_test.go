@@ -0,0 +1,97 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// failingJournal is a Journal whose appends always fail
+type failingJournal struct{ NopJournal }
+
+func (failingJournal) AppendOrder(string, Order) error { return errors.New("disk full") }
+
+// TestJournalReplayReproducesBooks tests that replaying a journal rebuilds identical books
+func TestJournalReplayReproducesBooks(t *testing.T) {
+	journal, err := NewFileJournal(filepath.Join(t.TempDir(), "engine.journal"))
+	if err != nil {
+		t.Fatalf("Failed to open journal: %v", err)
+	}
+	defer journal.Close()
+
+	original := NewEngine(WithJournal(journal))
+	pairs := []string{"BTC-USD", "ETH-USD"}
+	for _, pair := range pairs {
+		for i := 0; i < 10; i++ {
+			side := Buy
+			price := 100 - i
+			if i%2 == 1 {
+				side = Sell
+				price = 95 + i
+			}
+			original.AddOrder(pair, Order{
+				ID:    fmt.Sprintf("%s-%d", pair, i),
+				Side:  side,
+				Price: decimal.NewFromInt(int64(price)),
+				Qty:   decimal.NewFromFloat(0.5 + float64(i)/10),
+			})
+		}
+	}
+	if err := original.CancelOrder("BTC-USD", "BTC-USD-8"); err != nil {
+		t.Fatalf("Failed to cancel order: %v", err)
+	}
+
+	replayed := NewEngine()
+	if err := replayed.Replay(journal); err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+
+	for _, pair := range pairs {
+		want := original.GetOrderBookDepth(pair, 50)
+		got := replayed.GetOrderBookDepth(pair, 50)
+		if got == nil {
+			t.Fatalf("Expected replayed book for %s", pair)
+		}
+		if fmt.Sprint(want.Bids) != fmt.Sprint(got.Bids) {
+			t.Errorf("Bid depth mismatch for %s: want %v, got %v", pair, want.Bids, got.Bids)
+		}
+		if fmt.Sprint(want.Asks) != fmt.Sprint(got.Asks) {
+			t.Errorf("Ask depth mismatch for %s: want %v, got %v", pair, want.Asks, got.Asks)
+		}
+		if want.TradeCount != got.TradeCount {
+			t.Errorf("Trade count mismatch for %s: want %d, got %d", pair, want.TradeCount, got.TradeCount)
+		}
+	}
+
+	trades := 0
+	if err := journal.Read(func(entry JournalEntry) error {
+		if entry.Type == JournalTrade {
+			trades++
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("Failed to read journal: %v", err)
+	}
+	if trades == 0 {
+		t.Error("Expected trades to be journaled")
+	}
+}
+
+// TestJournalFailureRejectsOrder tests that an order is rejected when it cannot be journaled
+func TestJournalFailureRejectsOrder(t *testing.T) {
+	engine := NewEngine(WithJournal(failingJournal{}))
+	pair := "BTC-USD"
+
+	engine.AddOrder(pair, Order{ID: "buy1", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+
+	fill := <-engine.FillStream
+	if fill.Status != Rejected {
+		t.Errorf("Expected status REJECTED, got %s", fill.Status)
+	}
+	if bid, _, _, _, _ := engine.TopOfBook(pair); !bid.IsZero() {
+		t.Errorf("Expected rejected order to stay out of the book, best bid %s", bid.String())
+	}
+}
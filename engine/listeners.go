@@ -0,0 +1,61 @@
+package engine
+
+import "sync"
+
+// listenerSet holds the callbacks registered for one kind of event.
+type listenerSet[T any] struct {
+	mutex sync.Mutex
+	fns   []func(T)
+}
+
+// add registers fn. The slice is copied on write so that notify can iterate
+// a snapshot without holding the lock.
+func (l *listenerSet[T]) add(fn func(T)) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.fns = append(l.fns[:len(l.fns):len(l.fns)], fn)
+}
+
+// notify invokes every listener with event in registration order. A listener
+// that panics is recovered so that the remaining listeners still run.
+func (l *listenerSet[T]) notify(event T) {
+	l.mutex.Lock()
+	fns := l.fns
+	l.mutex.Unlock()
+
+	for _, fn := range fns {
+		invoke(fn, event)
+	}
+}
+
+// invoke calls fn with event, recovering from any panic.
+func invoke[T any](fn func(T), event T) {
+	defer func() { _ = recover() }()
+	fn(event)
+}
+
+// OnTrade registers fn to be called for every executed trade. Listeners run
+// synchronously on the matching goroutine after the trade has been delivered
+// to TradeStream, in registration order, so they should return quickly.
+func (e *Engine) OnTrade(fn func(Trade)) {
+	e.tradeListeners.add(fn)
+}
+
+// OnFill registers fn to be called for every fill event, after it has been
+// delivered to FillStream. Listeners run in registration order.
+func (e *Engine) OnFill(fn func(OrderFill)) {
+	e.fillListeners.add(fn)
+}
+
+// OnDepth registers fn to be called for every depth update produced by the
+// depth streamer, including updates dropped from a full DepthUpdates channel.
+func (e *Engine) OnDepth(fn func(DepthUpdate)) {
+	e.depthListeners.add(fn)
+}
+
+// OnPrice registers fn to be called for every price update produced by the
+// price broadcaster, including updates dropped from a full PriceUpdates
+// channel.
+func (e *Engine) OnPrice(fn func(PriceUpdate)) {
+	e.priceListeners.add(fn)
+}
@@ -0,0 +1,82 @@
+package engine
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// TestTradeListeners tests that every registered trade listener fires per trade in order
+func TestTradeListeners(t *testing.T) {
+	engine := NewEngine()
+	pair := "BTC-USD"
+
+	var mutex sync.Mutex
+	var calls []string
+	engine.OnTrade(func(trade Trade) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		calls = append(calls, "first:"+trade.BuyOrderID)
+	})
+	engine.OnTrade(func(trade Trade) {
+		panic("listener failure")
+	})
+	engine.OnTrade(func(trade Trade) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		calls = append(calls, "second:"+trade.BuyOrderID)
+	})
+
+	engine.AddOrder(pair, Order{ID: "sell1", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(2)})
+	engine.AddOrder(pair, Order{ID: "buy1", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	engine.AddOrder(pair, Order{ID: "buy2", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	expected := []string{"first:buy1", "second:buy1", "first:buy2", "second:buy2"}
+	if len(calls) != len(expected) {
+		t.Fatalf("Expected %d listener calls, got %v", len(expected), calls)
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Errorf("Expected call %d to be %s, got %s", i, expected[i], calls[i])
+		}
+	}
+
+	if len(engine.TradeStream) != 2 {
+		t.Errorf("Expected trades to still reach the channel, got %d", len(engine.TradeStream))
+	}
+}
+
+// TestFillAndDepthListeners tests fill listeners and listeners fed by the depth streamer
+func TestFillAndDepthListeners(t *testing.T) {
+	engine := NewEngine()
+	pair := "BTC-USD"
+
+	fills := make(chan OrderFill, 10)
+	depths := make(chan DepthUpdate, 10)
+	engine.OnFill(func(fill OrderFill) { fills <- fill })
+	engine.OnDepth(func(update DepthUpdate) {
+		select {
+		case depths <- update:
+		default:
+		}
+	})
+
+	engine.AddOrder(pair, Order{ID: "buy1", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	if fill := <-fills; fill.OrderID != "buy1" || fill.Status != New {
+		t.Errorf("Expected New fill for buy1, got %+v", fill)
+	}
+
+	engine.StartDepthStreamer(DepthStreamConfig{Depth: 5})
+	select {
+	case update := <-depths:
+		if update.Pair != pair || len(update.Bids) != 1 {
+			t.Errorf("Expected depth for %s with 1 bid, got %+v", pair, update)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected depth listener to be called")
+	}
+}
@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"sync"
+	"time"
+)
+
+// loop runs a function periodically on a background goroutine. It can be
+// started and stopped repeatedly until it is shut down; starting a running
+// loop has no effect.
+type loop struct {
+	mutex    sync.Mutex
+	stop     chan struct{}
+	done     chan struct{}
+	shutdown bool // Set by close; the loop can no longer be started
+}
+
+// start runs fn immediately and then every interval until halt is called.
+// fn receives a channel that is closed when the loop is halted, so it can
+// abandon blocking work. It returns false without starting anything if the
+// loop is already running or has been closed.
+func (l *loop) start(interval time.Duration, fn func(stop <-chan struct{})) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.stop != nil || l.shutdown {
+		return false
+	}
+	stop, done := make(chan struct{}), make(chan struct{})
+	l.stop, l.done = stop, done
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			fn(stop)
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return true
+}
+
+// halt stops the loop and waits for its goroutine to exit. It has no effect
+// if the loop is not running.
+func (l *loop) halt() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.haltLocked()
+}
+
+// close halts the loop and prevents it from being started again.
+func (l *loop) close() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.haltLocked()
+	l.shutdown = true
+}
+
+// running reports whether the loop is running.
+func (l *loop) running() bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.stop != nil
+}
+
+// haltLocked is halt with l.mutex held.
+func (l *loop) haltLocked() {
+	if l.stop == nil {
+		return
+	}
+	close(l.stop)
+	<-l.done
+	l.stop, l.done = nil, nil
+}
@@ -0,0 +1,132 @@
+package engine
+
+import (
+	"runtime"
+	"sync/atomic"
+)
+
+// EngineMetrics reports operational counters for an engine.
+type EngineMetrics struct {
+	DroppedTrades       uint64 `json:"dropped_trades"`        // Trades discarded from TradeStream
+	DroppedFills        uint64 `json:"dropped_fills"`         // Fills discarded from FillStream
+	DroppedPriceUpdates uint64 `json:"dropped_price_updates"` // Price updates discarded from PriceUpdates
+	DroppedDepthUpdates uint64 `json:"dropped_depth_updates"` // Depth updates discarded from DepthUpdates
+	DroppedOrderEvents  uint64 `json:"dropped_order_events"`  // Level-3 events discarded from OrderEvents
+	DroppedHaltEvents   uint64 `json:"dropped_halt_events"`   // Halt events discarded from HaltEvents
+
+	SelfTradesPrevented uint64 `json:"self_trades_prevented"` // Times self-trade prevention stopped two orders of one account trading
+	CrossedBooks        uint64 `json:"crossed_books"`         // Times WithCrossCheck found a book crossed after a job
+}
+
+// Metrics returns a snapshot of the engine's counters. Drop counters increase
+// whenever a full stream discards an event under its backpressure policy, so
+// operators can alert when market data is being shed.
+func (e *Engine) Metrics() EngineMetrics {
+	return EngineMetrics{
+		DroppedTrades:       e.drops.trades.Load(),
+		DroppedFills:        e.drops.fills.Load(),
+		DroppedPriceUpdates: e.drops.prices.Load(),
+		DroppedDepthUpdates: e.drops.depth.Load(),
+		DroppedOrderEvents:  e.drops.events.Load(),
+		DroppedHaltEvents:   e.drops.halts.Load(),
+
+		SelfTradesPrevented: e.selfTrades.Load(),
+		CrossedBooks:        e.crossedBooks.Load(),
+	}
+}
+
+// throughput counts what the engine has processed since it was created or
+// last reset.
+type throughput struct {
+	orders atomic.Int64
+	trades atomic.Int64
+	fills  atomic.Int64
+}
+
+// Counters returns how many orders, trades and executions the engine has
+// processed since it was created or last reset, across all pairs. orders counts every order
+// submitted, whether or not it was accepted, and fills counts the fill events
+// that record an execution, one for each side of every trade. The counters
+// are updated atomically as orders are matched and can be read at any time.
+func (e *Engine) Counters() (orders, trades, fills int64) {
+	return e.counts.orders.Load(), e.counts.trades.Load(), e.counts.fills.Load()
+}
+
+// reset zeroes the counters.
+func (t *throughput) reset() {
+	t.orders.Store(0)
+	t.trades.Store(0)
+	t.fills.Store(0)
+}
+
+// StreamLevel reports how full an output stream is.
+type StreamLevel struct {
+	Len int `json:"len"` // Events buffered and not yet consumed
+	Cap int `json:"cap"` // Buffer capacity
+}
+
+// streamLevel returns the fill level of ch.
+func streamLevel[T any](ch chan T) StreamLevel {
+	return StreamLevel{Len: len(ch), Cap: cap(ch)}
+}
+
+// HealthStatus is a point-in-time view of an engine's state for health checks
+// and monitoring endpoints.
+type HealthStatus struct {
+	Books        int         `json:"books"`         // Order books currently held
+	RestingBids  int         `json:"resting_bids"`  // Resting buy orders across all pairs
+	RestingAsks  int         `json:"resting_asks"`  // Resting sell orders across all pairs
+	QueuedJobs   int         `json:"queued_jobs"`   // Operations waiting on shard queues
+	Shards       int         `json:"shards"`        // Matching workers
+	Goroutines   int         `json:"goroutines"`    // Goroutines in the process
+	Closed       bool        `json:"closed"`        // Whether Close has been called
+	PriceRunning bool        `json:"price_running"` // Whether the price broadcaster is running
+	DepthRunning bool        `json:"depth_running"` // Whether the depth streamer is running
+	TradeStream  StreamLevel `json:"trade_stream"`
+	FillStream   StreamLevel `json:"fill_stream"`
+	PriceUpdates StreamLevel `json:"price_updates"`
+	DepthUpdates StreamLevel `json:"depth_updates"`
+	OrderEvents  StreamLevel `json:"order_events"`
+	HaltEvents   StreamLevel `json:"halt_events"`
+}
+
+// Health reports the engine's books, resting orders, shard queues, stream
+// fill levels and broadcaster state in a single call. Each value is read
+// under its own lock, one at a time, so Health never waits on the matching
+// shards or the broadcasters; the figures are therefore not one atomic
+// snapshot while orders are being matched.
+func (e *Engine) Health() HealthStatus {
+	e.mutex.RLock()
+	books := make([]*OrderBook, 0, len(e.books))
+	for _, book := range e.books {
+		books = append(books, book)
+	}
+	e.mutex.RUnlock()
+
+	status := HealthStatus{
+		Books:        len(books),
+		Shards:       len(e.shards),
+		Goroutines:   runtime.NumGoroutine(),
+		PriceRunning: e.priceLoop.running(),
+		DepthRunning: e.depthLoop.running(),
+		TradeStream:  streamLevel(e.TradeStream),
+		FillStream:   streamLevel(e.FillStream),
+		PriceUpdates: streamLevel(e.PriceUpdates),
+		DepthUpdates: streamLevel(e.DepthUpdates),
+		OrderEvents:  streamLevel(e.OrderEvents),
+		HaltEvents:   streamLevel(e.HaltEvents),
+	}
+	for _, book := range books {
+		bids, asks := book.OpenOrderCount()
+		status.RestingBids += bids
+		status.RestingAsks += asks
+	}
+	for _, s := range e.shards {
+		status.QueuedJobs += len(s.jobs)
+	}
+
+	e.gate.RLock()
+	status.Closed = e.closed
+	e.gate.RUnlock()
+	return status
+}
@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// TestMetricsDroppedPriceUpdates tests that saturating PriceUpdates increases the drop counter
+func TestMetricsDroppedPriceUpdates(t *testing.T) {
+	engine := NewEngine()
+	for i := 0; i < 10; i++ {
+		pair := fmt.Sprintf("PAIR-%d", i)
+		engine.AddOrder(pair, Order{ID: "buy", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	}
+
+	if m := engine.Metrics(); m.DroppedPriceUpdates != 0 {
+		t.Errorf("Expected no drops before broadcasting, got %d", m.DroppedPriceUpdates)
+	}
+
+	// Each round sends one update per pair into a channel nobody drains
+	rounds := cap(engine.PriceUpdates)/10 + 2
+	for i := 0; i < rounds; i++ {
+		engine.broadcastPrices(0, nil, nil)
+	}
+
+	expected := uint64(rounds*10 - cap(engine.PriceUpdates))
+	if m := engine.Metrics(); m.DroppedPriceUpdates != expected {
+		t.Errorf("Expected %d dropped price updates, got %d", expected, m.DroppedPriceUpdates)
+	}
+	if m := engine.Metrics(); m.DroppedTrades != 0 || m.DroppedFills != 0 || m.DroppedDepthUpdates != 0 {
+		t.Errorf("Expected other counters to stay zero, got %+v", m)
+	}
+}
+
+// TestHealth tests that Health reports the books, resting orders, streams and broadcasters of a known state
+func TestHealth(t *testing.T) {
+	engine := NewEngine(WithShards(2))
+	engine.AddOrder("BTC-USD", Order{ID: "bid1", Side: Buy, Price: decimal.NewFromInt(99), Qty: decimal.NewFromInt(1)})
+	engine.AddOrder("BTC-USD", Order{ID: "bid2", Side: Buy, Price: decimal.NewFromInt(98), Qty: decimal.NewFromInt(1)})
+	engine.AddOrder("BTC-USD", Order{ID: "ask1", Side: Sell, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(1)})
+	engine.AddOrder("ETH-USD", Order{ID: "ask2", Side: Sell, Price: decimal.NewFromInt(10), Qty: decimal.NewFromInt(2)})
+	engine.AddOrder("ETH-USD", Order{ID: "buy", Side: Buy, Price: decimal.NewFromInt(10), Qty: decimal.NewFromInt(1)})
+
+	h := engine.Health()
+	if h.Books != 2 || h.RestingBids != 2 || h.RestingAsks != 2 {
+		t.Errorf("Expected 2 books with 2 bids and 2 asks, got %d books with %d bids and %d asks", h.Books, h.RestingBids, h.RestingAsks)
+	}
+	if h.Shards != 2 || h.QueuedJobs != 0 || h.Closed {
+		t.Errorf("Expected 2 idle shards on an open engine, got %+v", h)
+	}
+	// One New fill per order plus the two fills of the single trade
+	if h.FillStream.Len != 7 || h.FillStream.Cap != cap(engine.FillStream) {
+		t.Errorf("Expected 7 buffered fills, got %+v", h.FillStream)
+	}
+	if h.TradeStream.Len != 1 || h.PriceUpdates.Len != 0 {
+		t.Errorf("Expected 1 buffered trade and no price updates, got %+v and %+v", h.TradeStream, h.PriceUpdates)
+	}
+	if h.PriceRunning || h.DepthRunning {
+		t.Errorf("Expected no broadcasters running, got %+v", h)
+	}
+
+	engine.StartPriceBroadcaster(PriceBroadcastConfig{})
+	if h := engine.Health(); !h.PriceRunning || h.DepthRunning {
+		t.Errorf("Expected only the price broadcaster running, got %+v", h)
+	}
+	engine.StopPriceBroadcaster()
+	if h := engine.Health(); h.PriceRunning {
+		t.Errorf("Expected the price broadcaster stopped, got %+v", h)
+	}
+}
+
+// TestCounters tests that the throughput counters match the output of a known matching session
+func TestCounters(t *testing.T) {
+	engine := NewEngine()
+	pair := "BTC-USD"
+	engine.AddOrder(pair, Order{ID: "sell1", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	engine.AddOrder(pair, Order{ID: "sell2", Side: Sell, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(1)})
+	engine.AddOrder(pair, Order{ID: "buy", Side: Buy, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(3)})
+	engine.AddOrder(pair, Order{ID: "bad", Side: Buy, Price: decimal.NewFromInt(101), Qty: decimal.Zero})
+
+	trades, fills := 0, 0
+	for len(engine.TradeStream) > 0 {
+		<-engine.TradeStream
+		trades++
+	}
+	for len(engine.FillStream) > 0 {
+		if fill := <-engine.FillStream; fill.ExecutedQty.IsPositive() {
+			fills++
+		}
+	}
+
+	o, tr, f := engine.Counters()
+	if o != 4 || tr != int64(trades) || f != int64(fills) {
+		t.Errorf("Expected 4 orders, %d trades and %d fills, got %d, %d and %d", trades, fills, o, tr, f)
+	}
+	if tr != 2 || f != 4 {
+		t.Errorf("Expected 2 trades and 4 fills, got %d and %d", tr, f)
+	}
+}
@@ -5,14 +5,17 @@ package engine
 
 import (
 	"container/heap"
+	"slices"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/shopspring/decimal"
 )
 
-// orderHeap is a slice of Order pointers that implements heap.Interface.
-// It serves as the base type for both bid and ask heaps.
+// orderHeap is a slice of Order pointers ordered as a binary heap. It is the
+// storage shared by bid and ask heaps and is what the book's read paths scan.
 type orderHeap []*Order
 
 // Len returns the number of orders in the heap.
@@ -20,42 +23,115 @@ func (h orderHeap) Len() int {
 	return len(h)
 }
 
+// indexedHeap is an orderHeap that implements the mutating half of
+// heap.Interface while keeping the position of every order by ID, so the book
+// finds a resting order in O(1) and removes it with heap.Remove in O(log n).
+// The index follows every Swap, so heap.Fix and heap.Remove keep it exact.
+// Order IDs must be unique within the heap. Heaps built over copies of the
+// book's orders, for sorting or draining, have a nil index and skip the
+// bookkeeping.
+type indexedHeap struct {
+	orderHeap
+	index map[string]int // Position of each order by ID, nil if not indexed
+}
+
+// newIndexedHeap returns an empty indexed heap with room for hint orders.
+func newIndexedHeap(hint int) indexedHeap {
+	return indexedHeap{orderHeap: make(orderHeap, 0, hint), index: make(map[string]int, hint)}
+}
+
 // Swap exchanges the orders at positions i and j in the heap.
-func (h orderHeap) Swap(i, j int) {
-	h[i], h[j] = h[j], h[i]
+func (h *indexedHeap) Swap(i, j int) {
+	h.orderHeap[i], h.orderHeap[j] = h.orderHeap[j], h.orderHeap[i]
+	if h.index != nil {
+		h.index[h.orderHeap[i].ID] = i
+		h.index[h.orderHeap[j].ID] = j
+	}
 }
 
 // Push adds a new order to the heap. The order must be of type *Order.
-func (h *orderHeap) Push(x interface{}) {
-	*h = append(*h, x.(*Order))
+func (h *indexedHeap) Push(x interface{}) {
+	h.append(x.(*Order))
 }
 
 // Pop removes and returns the last order from the heap.
-func (h *orderHeap) Pop() interface{} {
-	n := len(*h)
-	x := (*h)[n-1]
-	*h = (*h)[:n-1]
+func (h *indexedHeap) Pop() interface{} {
+	n := len(h.orderHeap)
+	x := h.orderHeap[n-1]
+	h.orderHeap[n-1] = nil
+	h.orderHeap = h.orderHeap[:n-1]
+	if h.index != nil {
+		delete(h.index, x.ID)
+	}
 	return x
 }
 
+// append adds o to the end of the heap without restoring the heap invariant.
+func (h *indexedHeap) append(o *Order) {
+	if h.index != nil {
+		h.index[o.ID] = len(h.orderHeap)
+	}
+	h.orderHeap = append(h.orderHeap, o)
+}
+
+// reset empties the heap, keeping its capacity.
+func (h *indexedHeap) reset() {
+	clear(h.orderHeap)
+	h.orderHeap = h.orderHeap[:0]
+	clear(h.index)
+}
+
+// indexOf returns the heap position of the order with the given ID, or -1 if
+// the order is not in the heap.
+func (h *indexedHeap) indexOf(orderID string) int {
+	if i, ok := h.index[orderID]; ok {
+		return i
+	}
+	return -1
+}
+
 // bidHeap implements a max-heap for buy orders, prioritizing higher prices.
-// Orders with higher prices have higher priority in the matching process.
-type bidHeap struct{ orderHeap }
+// Orders with higher prices have higher priority in the matching process;
+// orders at the same price are prioritized by arrival sequence.
+type bidHeap struct{ indexedHeap }
+
+// bidOrders returns an unindexed bid heap over orders, for sorting or
+// draining orders outside the book.
+func bidOrders(orders orderHeap) *bidHeap {
+	return &bidHeap{indexedHeap{orderHeap: orders}}
+}
 
 // Less determines the ordering of buy orders in the heap.
-// Returns true if order i has higher priority than order j (higher price).
-func (h bidHeap) Less(i, j int) bool {
-	return h.orderHeap[i].Price.GreaterThan(h.orderHeap[j].Price)
+// Returns true if order i has higher priority than order j (higher price,
+// or the same price and an earlier sequence number).
+func (h *bidHeap) Less(i, j int) bool {
+	a, b := h.orderHeap[i], h.orderHeap[j]
+	if c := comparePrices(a, b); c != 0 {
+		return c > 0
+	}
+	return a.Seq < b.Seq
 }
 
 // askHeap implements a min-heap for sell orders, prioritizing lower prices.
-// Orders with lower prices have higher priority in the matching process.
-type askHeap struct{ orderHeap }
+// Orders with lower prices have higher priority in the matching process;
+// orders at the same price are prioritized by arrival sequence.
+type askHeap struct{ indexedHeap }
+
+// askOrders returns an unindexed ask heap over orders, for sorting or
+// draining orders outside the book.
+func askOrders(orders orderHeap) *askHeap {
+	return &askHeap{indexedHeap{orderHeap: orders}}
+}
 
 // Less determines the ordering of sell orders in the heap.
-// Returns true if order i has higher priority than order j (lower price).
-func (h askHeap) Less(i, j int) bool {
-	return h.orderHeap[i].Price.LessThan(h.orderHeap[j].Price)
+// Returns true if order i has higher priority than order j (lower price,
+// or the same price and an earlier sequence number).
+func (h *askHeap) Less(i, j int) bool {
+	a, b := h.orderHeap[i], h.orderHeap[j]
+	if c := comparePrices(a, b); c != 0 {
+		return c < 0
+	}
+	return a.Seq < b.Seq
 }
 
 // OrderBook represents a trading pair's order book with separate bid and ask sides.
@@ -66,16 +142,140 @@ type OrderBook struct {
 	bids  *bidHeap   // Buy orders heap (max-heap by price)
 	asks  *askHeap   // Sell orders heap (min-heap by price)
 	mutex sync.Mutex // Protects concurrent access to the order book
+	clock Clock      // Source of time for fill and trade timestamps
+	seq   uint64     // Sequence number assigned to the last incoming order
+
+	stamped time.Time // Last time handed out by stamp, the time of the match in progress
+
+	tradeSeq  uint64          // Sequence number of the last trade, used for trade IDs
+	lastPrice decimal.Decimal // Price of the most recent trade, zero before the first
+	reference decimal.Decimal // Reference price last fed to the stops when not following trades
+	stops     []*trailingStop // Pending trailing stops in arrival order
+	hasPegs   bool            // Whether pegged orders may be resting
+	pegRefs   pegReferences   // Reference prices the resting pegs were last priced from
+	session   Session         // Trading session that Day orders expire with
+	config    PairConfig      // Trading rules checked at order entry
+	halt      haltState       // Trading status and LULD breaker state
+	hasAON    bool            // Whether all-or-none orders may be resting
+	policy    MatchPolicy     // Decides how crossing orders trade
+	stp       STPMode         // Self-trade prevention used when the config sets none
+
+	maxResting int // Most orders allowed to rest at once, unbounded if zero
+	maxLevels  int // Most price levels retained per side, unbounded if zero
+
+	checkInvariants bool // Whether to validate the book after every match
+
+	// Level-3 events not yet collected by takeEvents. Recording is enabled
+	// by the engine; a standalone book does not record events.
+	recordEvents bool
+	eventSeq     uint64
+	events       []OrderEvent
+
+	// Running totals of resting quantity per side, maintained on every
+	// mutation so RestingVolume is O(1). Guarded by mutex.
+	bidVol decimal.Decimal
+	askVol decimal.Decimal
+
+	// Cached top-of-book prices, nil when the side is empty. They are written
+	// under mutex after every mutation and read without locking.
+	bestBid atomic.Pointer[decimal.Decimal]
+	bestAsk atomic.Pointer[decimal.Decimal]
+
+	// Cached top-of-book prices the engine last sampled the spread at. They
+	// are only used by the shard that owns the book.
+	sampledBid *decimal.Decimal
+	sampledAsk *decimal.Decimal
+
+	// Set whenever the resting orders change and cleared by takeChanged, so
+	// the depth streamer can skip books that have not changed.
+	dirty atomic.Bool
+
+	// Match publishes its events after releasing mutex. Each call takes a
+	// ticket while matching and publishes only once every earlier ticket
+	// has, so events leave in matching order.
+	publishMu   sync.Mutex
+	published   sync.Cond
+	nextTicket  uint64 // Guarded by mutex
+	publishTurn uint64 // Guarded by publishMu
 }
 
+// resultPool recycles the event buffers Match collects into before
+// publishing.
+var resultPool = sync.Pool{
+	New: func() interface{} { return new(MatchResult) },
+}
+
+// defaultBookCapacity is the number of orders per side NewOrderBook reserves
+// room for.
+const defaultBookCapacity = 64
+
 // NewOrderBook creates and initializes a new order book for the specified trading pair.
 // The returned order book has empty bid and ask heaps ready for order processing.
 func NewOrderBook(pair string) *OrderBook {
-	b := &bidHeap{}
-	a := &askHeap{}
+	return NewOrderBookWithCapacity(pair, defaultBookCapacity)
+}
+
+// NewOrderBookWithCapacity is like NewOrderBook but reserves room for hint
+// resting orders on each side, so books expected to hold many orders do not
+// repeatedly reallocate their heaps as they grow. A hint below zero is
+// treated as zero.
+func NewOrderBookWithCapacity(pair string, hint int) *OrderBook {
+	hint = max(hint, 0)
+	b := &bidHeap{newIndexedHeap(hint)}
+	a := &askHeap{newIndexedHeap(hint)}
 	heap.Init(b)
 	heap.Init(a)
-	return &OrderBook{Pair: pair, bids: b, asks: a}
+	ob := &OrderBook{Pair: pair, bids: b, asks: a, clock: realClock{}, policy: PriceTimePolicy{}}
+	ob.published.L = &ob.publishMu
+	ob.dirty.Store(true)
+	return ob
+}
+
+// SetClock replaces the clock used for the book's timestamps.
+func (ob *OrderBook) SetClock(c Clock) {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+	ob.clock = c
+}
+
+// orderPool recycles the order pointers stored in the heaps. A resting order is
+// taken from the pool when it enters the book and returned once it is fully
+// filled, so a busy book does not allocate a new order for every resting entry.
+var orderPool = sync.Pool{
+	New: func() interface{} { return new(Order) },
+}
+
+// acquireOrder returns a pooled copy of order suitable for pushing onto a heap.
+func acquireOrder(order Order) *Order {
+	o := orderPool.Get().(*Order)
+	*o = order
+	return o
+}
+
+// releaseOrder returns an order that has left the book to the pool.
+func releaseOrder(o *Order) {
+	*o = Order{}
+	orderPool.Put(o)
+}
+
+// MatchResult holds the trades and fills produced by matching a single order,
+// in the order they were generated.
+type MatchResult struct {
+	Trades []Trade      // Trades executed against resting orders
+	Fills  []OrderFill  // Fill events for the incoming and matched orders
+	Events []OrderEvent // Level-3 events for resting orders, if recorded
+	Halts  []HaltEvent  // Trading halts and resumptions, set only by the engine
+
+	SelfTrades int // Self-trades prevented while matching
+}
+
+// reset empties the result while keeping the allocated capacity for reuse.
+func (r *MatchResult) reset() {
+	r.Trades = r.Trades[:0]
+	r.Fills = r.Fills[:0]
+	r.Events = r.Events[:0]
+	r.Halts = r.Halts[:0]
+	r.SelfTrades = 0
 }
 
 // Match processes an incoming order against the order book, executing trades when possible.
@@ -86,252 +286,923 @@ func NewOrderBook(pair string) *OrderBook {
 //   - order: The incoming order to match
 //   - tradeCh: Channel to send Trade events when orders are matched
 //   - fillCh: Channel to send OrderFill events for order status updates
-//   - originalQty: The original quantity of the incoming order before any modifications
+//   - originalQty: The original quantity of the incoming order before any modifications. An
+//     order whose Qty differs from it is taken to be the remainder of an order that was already
+//     acknowledged and gets no New fill
 //
 // The method handles both buy and sell orders:
 //   - Buy orders match against ask orders (sells) starting from the lowest price
 //   - Sell orders match against bid orders (buys) starting from the highest price
 //
 // If the order cannot be fully matched, the remaining quantity is added to the appropriate
-// side of the order book. Fill events are sent for both the incoming order and any
-// matched orders to track execution status.
+// side of the order book, unless its TimeInForce is IOC or it is a Market order, in which
+// case the remainder is canceled; a Market order's Canceled fill carries the reason
+// LiquidityExhausted, since it only stops short of its quantity when the opposite side runs
+// out. A limit order that sweeps the opposite side and rests its remainder reports New, then a
+// PartiallyFilled fill per trade with the quantity left to rest, then an Added order event at
+// its limit price. Orders with a non-positive quantity or a negative price are
+// rejected without touching the book. FOK orders that cannot be filled completely are canceled
+// without trading, and expired GTD or Day orders reached while matching are canceled instead of
+// traded. All-or-none orders only trade when they can be filled completely, both as the incoming
+// order and while resting; otherwise they rest untouched and are passed over. Fill events are
+// sent for both the incoming order and any matched orders to track execution status.
+//
+// Every accepted order is acknowledged with exactly one New fill, sent before any fill from
+// matching it, so its events always read New, then its trades' fills in execution order, then a
+// Canceled fill if an immediate-or-cancel remainder is dropped. Rejected orders get a single
+// Rejected fill and no acknowledgment.
+//
+// Events are collected while the book is locked and sent after the lock is released, so a slow
+// consumer never blocks other access to the book. Concurrent calls still send their events in
+// the order the orders were matched.
 func (ob *OrderBook) Match(order Order, tradeCh chan<- Trade, fillCh chan<- OrderFill, originalQty decimal.Decimal) {
+	res := resultPool.Get().(*MatchResult)
+	res.reset()
+
+	ob.mutex.Lock()
+	ob.execute(order, order.Qty.Equal(originalQty), res)
+	ticket := ob.nextTicket
+	ob.nextTicket++
+	ob.mutex.Unlock()
+
+	ob.publishMu.Lock()
+	for ob.publishTurn != ticket {
+		ob.published.Wait()
+	}
+	for _, trade := range res.Trades {
+		tradeCh <- trade
+	}
+	for _, fill := range res.Fills {
+		fillCh <- fill
+	}
+	ob.publishTurn++
+	ob.published.Broadcast()
+	ob.publishMu.Unlock()
+
+	resultPool.Put(res)
+}
+
+// Execute matches an incoming order against the order book synchronously and
+// returns the generated trades and fills instead of sending them on channels.
+// Matching semantics are identical to Match.
+func (ob *OrderBook) Execute(order Order) MatchResult {
+	var res MatchResult
+	ob.executeInto(order, &res)
+	return res
+}
+
+// executeInto matches order and appends the generated events to res. Callers
+// that process many orders reuse res to avoid per-order allocations.
+func (ob *OrderBook) executeInto(order Order, res *MatchResult) {
 	ob.mutex.Lock()
 	defer ob.mutex.Unlock()
 
-	now := time.Now().Unix()
-	incomingExecutedQty := decimal.Zero
+	ob.execute(order, true, res)
+	ob.takeEvents(res)
+}
 
-	if order.Side == Buy {
-		for ob.asks.Len() > 0 && !order.Qty.IsZero() {
-			top := heap.Pop(ob.asks).(*Order)
-			if top.Price.GreaterThan(order.Price) {
-				heap.Push(ob.asks, top)
-				break
-			}
-			qty := min(order.Qty, top.Qty)
-			if qty.IsZero() {
-				continue
-			}
+// execute runs the matching algorithm for order and appends the resulting
+// events to res. An order with a non-positive quantity or a negative price is
+// rejected before it can touch the book, whatever validation the caller did.
+// With ack set, an accepted order is acknowledged with a New fill before it
+// matches; orders re-entering the book, such as released stops, were
+// acknowledged before and pass false. The caller must hold ob.mutex.
+func (ob *OrderBook) execute(order Order, ack bool, res *MatchResult) {
+	t := ob.clock.Now()
+	now := t.Unix()
+	if reason := invalidOrder(order); reason != "" {
+		res.Fills = append(res.Fills, rejectedFill(ob.Pair, order, reason, now))
+		return
+	}
+	ob.seq++
+	order.Seq = ob.seq
+	stamp := ob.stamp(t)
+	if order.ReceivedAt.IsZero() {
+		order.ReceivedAt = stamp
+	}
 
-			// Create trade
-			tradeCh <- Trade{
-				Pair:        ob.Pair,
-				BuyOrderID:  order.ID,
-				SellOrderID: top.ID,
-				Price:       top.Price,
-				Qty:         qty,
-			}
+	if order.Type == TrailingStop || order.Type == MarketIfTouched {
+		ob.addStop(order)
+		if ack {
+			res.Fills = append(res.Fills, newFill(ob.Pair, order, now))
+		}
+		return
+	}
 
-			// Update quantities
-			order.Qty = order.Qty.Sub(qty)
-			top.Qty = top.Qty.Sub(qty)
-			incomingExecutedQty = incomingExecutedQty.Add(qty)
+	if order.Type == Pegged {
+		ob.pegPrice(&order)
+	}
+	ob.setTicks(&order)
 
-			// Create fill event for the matched sell order (top)
-			topStatus := PartiallyFilled
-			if top.Qty.IsZero() {
-				topStatus = Filled
-			}
+	switch order.TimeInForce {
+	case GTD:
+		if order.ExpireAt <= now {
+			res.Fills = append(res.Fills, rejectedFill(ob.Pair, order, RejectExpired, now))
+			return
+		}
+	case Day:
+		order.ExpireAt = ob.session.nextClose(t)
+	}
 
-			orderStatus := PartiallyFilled
-			if order.Qty.IsZero() {
-				orderStatus = Filled
-			}
+	if ob.full() && !order.immediate() && order.TimeInForce != FOK && !ob.marketable(&order) {
+		res.Fills = append(res.Fills, rejectedFill(ob.Pair, order, RejectBookFull, now))
+		return
+	}
 
-			fillCh <- OrderFill{
-				OrderID:      top.ID,
-				Pair:         ob.Pair,
-				Side:         top.Side,
-				OriginalQty:  top.Qty.Add(qty), // Reconstruct original qty
-				ExecutedQty:  qty,
-				RemainingQty: top.Qty,
-				Price:        top.Price,
-				FillPrice:    top.Price,
-				Status:       topStatus,
-				Timestamp:    now,
-			}
+	if ack {
+		res.Fills = append(res.Fills, newFill(ob.Pair, order, now))
+	}
+	if order.TimeInForce == FOK && !ob.fillsCompletely(order, now) {
+		res.Fills = append(res.Fills, canceledFill(ob.Pair, order, now))
+		return
+	}
 
-			fillCh <- OrderFill{
-				OrderID:      order.ID,
-				Pair:         ob.Pair,
-				Side:         order.Side,
-				OriginalQty:  order.Qty.Add(qty), // Reconstruct original qty
-				ExecutedQty:  qty,
-				RemainingQty: order.Qty,
-				Price:        top.Price,
-				FillPrice:    top.Price,
-				Status:       orderStatus,
-				Timestamp:    now,
-			}
+	start := len(res.Trades)
+	if !order.AON || ob.fillsCompletely(order, now) {
+		ob.match(res, &order, now)
+	}
 
-			if !top.Qty.IsZero() {
-				heap.Push(ob.asks, top)
+	if !order.Qty.IsZero() {
+		if order.immediate() {
+			fill := canceledFill(ob.Pair, order, now)
+			fill.AvgFillPrice = order.avgExecutedPrice()
+			if order.Type == Market {
+				fill.Reason = LiquidityExhausted
+			}
+			res.Fills = append(res.Fills, fill)
+		} else if ob.full() {
+			ob.cancelIncoming(res, &order, RejectBookFull, now)
+		} else {
+			ob.rest(order)
+			ob.emit(Added, order)
+			if ob.hasAON {
+				ob.fillRestingAON(res, order.Side, now)
 			}
+			ob.trimLevels(res, order.Side, now)
 		}
+	}
+	ob.refreshTop()
 
-		if !order.Qty.IsZero() {
-			heap.Push(ob.bids, &order)
+	ob.trailStops(res, start)
+
+	if ob.checkInvariants {
+		if err := ob.validate(); err != nil {
+			panic(err)
 		}
+	}
+}
+
+// match trades order against the opposite side of the book in price-time
+// priority until it is filled or no longer crosses, asking the book's
+// MatchPolicy which makers are eligible, how much each one fills and at what
+// price. Makers the policy passes over and resting all-or-none orders that
+// cannot be filled completely keep their priority. The caller must hold
+// ob.mutex.
+func (ob *OrderBook) match(res *MatchResult, order *Order, now int64) {
+	var h heap.Interface
+	var side *orderHeap
+	worse := decimal.Decimal.LessThan
+	if order.Side == Buy {
+		h, side = ob.asks, &ob.asks.orderHeap
+		worse = decimal.Decimal.GreaterThan
 	} else {
-		for ob.bids.Len() > 0 && !order.Qty.IsZero() {
-			top := heap.Pop(ob.bids).(*Order)
-			if top.Price.LessThan(order.Price) {
-				heap.Push(ob.bids, top)
+		h, side = ob.bids, &ob.bids.orderHeap
+	}
+
+	var skipped []*Order
+	var level *MatchLevel
+	var sweep sweep
+	stp := ob.stpMode()
+	for len(*side) > 0 && !order.Qty.IsZero() {
+		top := (*side)[0]
+		if !crosses(order, top) {
+			break
+		}
+		if top.Qty.IsZero() {
+			heap.Pop(h)
+			releaseOrder(top)
+			continue
+		}
+		if top.expired(now) {
+			heap.Pop(h)
+			ob.expire(res, top, now)
+			continue
+		}
+		if selfTrade(stp, order, top) {
+			if ob.preventSelfTrade(res, h, stp, order, top, now) {
 				break
 			}
-			qty := min(order.Qty, top.Qty)
-			if qty.IsZero() {
-				continue
-			}
+			continue
+		}
+		if level == nil || !level.Price.Equal(top.Price) {
+			level = &MatchLevel{Price: top.Price, TakerQty: order.Qty, orders: *side, worse: worse}
+		}
 
-			// Create trade
-			tradeCh <- Trade{
-				Pair:        ob.Pair,
-				BuyOrderID:  top.ID,
-				SellOrderID: order.ID,
-				Price:       top.Price,
-				Qty:         qty,
-			}
+		qty := decimal.Zero
+		if ob.policy.Eligible(order, top) {
+			qty = min(ob.policy.Allocate(order, top, level), min(order.Qty, top.Qty))
+		}
+		if !qty.IsPositive() || (top.AON && !qty.Equal(top.Qty)) {
+			skipped = append(skipped, heap.Pop(h).(*Order))
+			continue
+		}
+		if !sweep.allows(order, top.Price) {
+			ob.cancelIncoming(res, order, SlippageLimit, now)
+			break
+		}
+		sweep.fill(top.Price)
 
-			// Update quantities
-			order.Qty = order.Qty.Sub(qty)
-			top.Qty = top.Qty.Sub(qty)
-			incomingExecutedQty = incomingExecutedQty.Add(qty)
+		price := ob.policy.ExecutionPrice(order, top, ob.config)
+		trade := Trade{
+			ID:          ob.nextTradeID(),
+			Pair:        ob.Pair,
+			BuyOrderID:  order.ID,
+			SellOrderID: top.ID,
+			Price:       price,
+			Qty:         qty,
+			Timestamp:   now,
+			TakerSide:   order.Side,
+			MatchedAt:   ob.stamped,
+		}
+		if order.Side == Sell {
+			trade.BuyOrderID, trade.SellOrderID = top.ID, order.ID
+		}
+		ob.appendFills(res, order, top, qty, price, now)
+		trade.MakerRemaining = top.Qty
+		res.Trades = append(res.Trades, trade)
 
-			// Create fill event for the matched buy order (top)
-			topStatus := PartiallyFilled
-			if top.Qty.IsZero() {
-				topStatus = Filled
-			}
+		if top.Qty.IsZero() {
+			heap.Pop(h)
+			releaseOrder(top)
+		} else if !order.Qty.IsZero() {
+			skipped = append(skipped, heap.Pop(h).(*Order))
+		}
+	}
 
-			orderStatus := PartiallyFilled
-			if order.Qty.IsZero() {
-				orderStatus = Filled
-			}
+	for _, o := range skipped {
+		heap.Push(h, o)
+	}
+}
 
-			fillCh <- OrderFill{
-				OrderID:      top.ID,
-				Pair:         ob.Pair,
-				Side:         top.Side,
-				OriginalQty:  top.Qty.Add(qty),
-				ExecutedQty:  qty,
-				RemainingQty: top.Qty,
-				Price:        top.Price,
-				FillPrice:    top.Price,
-				Status:       topStatus,
-				Timestamp:    now,
-			}
+// crosses reports whether order accepts the price of the resting order top
+// on the opposite side. Market orders accept any price.
+func crosses(order, top *Order) bool {
+	if order.Type == Market {
+		return true
+	}
+	if order.Side == Buy {
+		return comparePrices(top, order) <= 0
+	}
+	return comparePrices(top, order) >= 0
+}
 
-			fillCh <- OrderFill{
-				OrderID:      order.ID,
-				Pair:         ob.Pair,
-				Side:         order.Side,
-				OriginalQty:  order.Qty.Add(qty), // Reconstruct original qty
-				ExecutedQty:  qty,
-				RemainingQty: order.Qty,
-				Price:        top.Price,
-				FillPrice:    top.Price,
-				Status:       orderStatus,
-				Timestamp:    now,
-			}
+// expire reports an expired resting order that matching has popped off its
+// heap as canceled and releases it. The caller must hold ob.mutex.
+func (ob *OrderBook) expire(res *MatchResult, o *Order, now int64) {
+	ob.addVolume(o.Side, o.Qty.Neg())
+	res.Fills = append(res.Fills, canceledFill(ob.Pair, *o, now))
+	ob.emit(Removed, *o)
+	releaseOrder(o)
+}
 
-			if !top.Qty.IsZero() {
-				heap.Push(ob.bids, top)
-			}
-		}
-		if !order.Qty.IsZero() {
-			heap.Push(ob.asks, &order)
-		}
+// cancelIncoming cancels the remainder of the incoming order for reason, so
+// it neither matches further nor rests. The caller must hold ob.mutex.
+func (ob *OrderBook) cancelIncoming(res *MatchResult, order *Order, reason RejectReason, now int64) {
+	fill := canceledFill(ob.Pair, *order, now)
+	fill.AvgFillPrice = order.avgExecutedPrice()
+	fill.Reason = reason
+	res.Fills = append(res.Fills, fill)
+	order.Qty = decimal.Zero
+}
+
+// rest adds the remaining quantity of order to its side of the book. The
+// caller must hold ob.mutex and refresh the cached top of book afterwards.
+func (ob *OrderBook) rest(order Order) {
+	if order.Side == Buy {
+		heap.Push(ob.bids, acquireOrder(order))
+	} else {
+		heap.Push(ob.asks, acquireOrder(order))
 	}
+	ob.track(order)
+}
 
-	if order.Qty.Equal(originalQty) {
-		fillCh <- OrderFill{
-			OrderID:      order.ID,
-			Pair:         ob.Pair,
-			Side:         order.Side,
-			OriginalQty:  originalQty,
-			ExecutedQty:  decimal.Zero,
-			RemainingQty: order.Qty,
-			Price:        order.Price,
-			FillPrice:    decimal.Zero,
-			Status:       New,
-			Timestamp:    now,
-		}
+// appendResting adds order to the end of its side's heap without restoring
+// the heap invariant; the caller must re-initialize the heap once done. The
+// caller must hold ob.mutex.
+func (ob *OrderBook) appendResting(order Order) {
+	ob.setTicks(&order)
+	if order.Side == Buy {
+		ob.bids.append(acquireOrder(order))
+	} else {
+		ob.asks.append(acquireOrder(order))
 	}
+	ob.track(order)
 }
 
-// BestBid returns the highest bid price in the order book.
-// Returns 0 if there are no bid orders.
-func (ob *OrderBook) BestBid() float64 {
+// track accounts for order having been added to its side of the book. The
+// caller must hold ob.mutex.
+func (ob *OrderBook) track(order Order) {
+	ob.addVolume(order.Side, order.Qty)
+	if order.Type == Pegged {
+		ob.hasPegs = true
+	}
+	if order.AON {
+		ob.hasAON = true
+	}
+}
+
+// addVolume adjusts the running resting quantity of a side by delta. The
+// caller must hold ob.mutex.
+func (ob *OrderBook) addVolume(side Side, delta decimal.Decimal) {
+	if side == Buy {
+		ob.bidVol = ob.bidVol.Add(delta)
+	} else {
+		ob.askVol = ob.askVol.Add(delta)
+	}
+}
+
+// OpenOrderCount returns the number of orders resting on each side.
+func (ob *OrderBook) OpenOrderCount() (bids, asks int) {
 	ob.mutex.Lock()
 	defer ob.mutex.Unlock()
+	return ob.bids.Len(), ob.asks.Len()
+}
 
-	if ob.bids.Len() == 0 {
-		return 0
+// RestingVolume returns the total quantity resting on each side. The totals
+// are maintained incrementally, so this is O(1).
+func (ob *OrderBook) RestingVolume() (bidVol, askVol decimal.Decimal) {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+	return ob.bidVol, ob.askVol
+}
+
+// newFill builds the fill event reported for an order accepted without any
+// execution.
+func newFill(pair string, order Order, now int64) OrderFill {
+	return OrderFill{
+		OrderID:       order.ID,
+		Pair:          pair,
+		Side:          order.Side,
+		Account:       order.Account,
+		ClientOrderID: order.ClientOrderID,
+		OriginalQty:   order.Qty,
+		ExecutedQty:   decimal.Zero,
+		RemainingQty:  order.Qty,
+		Price:         order.Price,
+		FillPrice:     decimal.Zero,
+		Status:        New,
+		Timestamp:     now,
+	}
+}
+
+// canceledFill builds the fill event reported when a resting order is removed
+// from the book. OriginalQty carries the quantity that was canceled.
+func canceledFill(pair string, order Order, now int64) OrderFill {
+	return OrderFill{
+		OrderID:       order.ID,
+		Pair:          pair,
+		Side:          order.Side,
+		Account:       order.Account,
+		ClientOrderID: order.ClientOrderID,
+		OriginalQty:   order.Qty,
+		ExecutedQty:   decimal.Zero,
+		RemainingQty:  decimal.Zero,
+		Price:         order.Price,
+		FillPrice:     decimal.Zero,
+		Status:        Canceled,
+		Timestamp:     now,
+
+		CumulativeQty:      order.ExecutedQty,
+		CumulativeAvgPrice: order.avgExecutedPrice(),
+	}
+}
+
+// invalidOrder returns the reason order can never be matched or rested, or an
+// empty reason if its quantity and price are usable.
+func invalidOrder(order Order) RejectReason {
+	if !order.Qty.IsPositive() {
+		return RejectInvalidQty
+	}
+	if order.Price.IsNegative() {
+		return RejectInvalidPrice
 	}
-	return ob.bids.orderHeap[0].Price.InexactFloat64()
+	return ""
+}
+
+// rejectedFill builds the fill event reported for an order that was refused
+// before reaching the book.
+func rejectedFill(pair string, order Order, reason RejectReason, now int64) OrderFill {
+	return OrderFill{
+		OrderID:       order.ID,
+		Pair:          pair,
+		Side:          order.Side,
+		Account:       order.Account,
+		ClientOrderID: order.ClientOrderID,
+		OriginalQty:   order.Qty,
+		ExecutedQty:   decimal.Zero,
+		RemainingQty:  decimal.Zero,
+		Price:         order.Price,
+		FillPrice:     decimal.Zero,
+		Status:        Rejected,
+		Timestamp:     now,
+		Reason:        reason,
+	}
+}
+
+// recordExecution adds an execution of qty at price to the order's progress.
+func (o *Order) recordExecution(qty, price decimal.Decimal) {
+	o.ExecutedQty = o.ExecutedQty.Add(qty)
+	o.ExecutedValue = o.ExecutedValue.Add(qty.Mul(price))
+}
+
+// avgExecutedPrice returns the volume-weighted average price of the order's
+// executions, or zero if nothing has executed.
+func (o *Order) avgExecutedPrice() decimal.Decimal {
+	if o.ExecutedQty.IsZero() {
+		return decimal.Zero
+	}
+	return o.ExecutedValue.Div(o.ExecutedQty)
+}
+
+// appendFills reduces both the incoming order and the matched resting order
+// (top) by qty executed at price and appends a fill event for each, resting
+// order first. Resting orders keep their heap position because a quantity
+// change never affects price priority.
+func (ob *OrderBook) appendFills(res *MatchResult, order, top *Order, qty, price decimal.Decimal, now int64) {
+	topOriginalQty := top.Qty
+	orderOriginalQty := order.Qty
+	top.Qty = top.Qty.Sub(qty)
+	ob.addVolume(top.Side, qty.Neg())
+	order.Qty = order.Qty.Sub(qty)
+	top.recordExecution(qty, price)
+	order.recordExecution(qty, price)
+
+	topStatus := PartiallyFilled
+	if top.Qty.IsZero() {
+		topStatus = Filled
+		ob.emit(Removed, *top)
+	} else {
+		ob.emit(Reduced, *top)
+	}
+
+	orderStatus := PartiallyFilled
+	if order.Qty.IsZero() {
+		orderStatus = Filled
+	}
+
+	res.Fills = append(res.Fills, OrderFill{
+		OrderID:       top.ID,
+		Pair:          ob.Pair,
+		Side:          top.Side,
+		Account:       top.Account,
+		ClientOrderID: top.ClientOrderID,
+		OriginalQty:   topOriginalQty,
+		ExecutedQty:   qty,
+		RemainingQty:  top.Qty,
+		Price:         top.Price,
+		FillPrice:     price,
+		Status:        topStatus,
+		Timestamp:     now,
+		Fee:           ob.config.fee(qty, price, true),
+		MatchedAt:     ob.stamped,
+
+		CumulativeQty:      top.ExecutedQty,
+		CumulativeAvgPrice: top.avgExecutedPrice(),
+	}, OrderFill{
+		OrderID:       order.ID,
+		Pair:          ob.Pair,
+		Side:          order.Side,
+		Account:       order.Account,
+		ClientOrderID: order.ClientOrderID,
+		OriginalQty:   orderOriginalQty,
+		ExecutedQty:   qty,
+		RemainingQty:  order.Qty,
+		Price:         top.Price,
+		FillPrice:     price,
+		Status:        orderStatus,
+		Timestamp:     now,
+		Fee:           ob.config.fee(qty, price, false),
+		MatchedAt:     ob.stamped,
+
+		CumulativeQty:      order.ExecutedQty,
+		CumulativeAvgPrice: order.avgExecutedPrice(),
+		AvgFillPrice:       order.avgExecutedPrice(),
+	})
+}
+
+// BestBid returns the highest bid price in the order book.
+// Returns 0 if there are no bid orders. The price is read from the cached
+// top-of-book and does not contend with matching for the book lock.
+func (ob *OrderBook) BestBid() float64 {
+	if p := ob.bestBid.Load(); p != nil {
+		return p.InexactFloat64()
+	}
+	return 0
 }
 
 // BestAsk returns the lowest ask price in the order book.
-// Returns 0 if there are no ask orders.
+// Returns 0 if there are no ask orders. The price is read from the cached
+// top-of-book and does not contend with matching for the book lock.
 func (ob *OrderBook) BestAsk() float64 {
+	if p := ob.bestAsk.Load(); p != nil {
+		return p.InexactFloat64()
+	}
+	return 0
+}
+
+// Cancel removes the resting order or pending trailing stop with the given ID
+// from the book. It returns a copy of the removed order and true, or false if
+// no such order is in the book.
+func (ob *OrderBook) Cancel(orderID string) (Order, bool) {
 	ob.mutex.Lock()
 	defer ob.mutex.Unlock()
 
-	if ob.asks.Len() == 0 {
-		return 0
+	return ob.cancel(orderID)
+}
+
+// cancel removes a resting order by ID. The caller must hold ob.mutex.
+func (ob *OrderBook) cancel(orderID string) (Order, bool) {
+	if i := ob.bids.indexOf(orderID); i >= 0 {
+		return ob.removeAt(ob.bids, i), true
 	}
-	return ob.asks.orderHeap[0].Price.InexactFloat64()
+	if i := ob.asks.indexOf(orderID); i >= 0 {
+		return ob.removeAt(ob.asks, i), true
+	}
+	return ob.cancelStop(orderID)
 }
 
-// GetBidDepth returns the bid side market depth up to the specified number of price levels.
-// Each DepthLevel contains the aggregated quantity and trade count for orders at that price.
-// The levels are ordered from highest to lowest price (best to worst for buyers).
-//
-// Parameters:
-//   - depth: Maximum number of price levels to return
+// CancelAll removes every resting order from both sides of the book in one
+// step, together with any pending trailing stops, and returns copies of the
+// removed orders: bids first, then asks, then stops.
+func (ob *OrderBook) CancelAll() []Order {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	canceled := drainHeap(&ob.bids.indexedHeap, nil)
+	canceled = drainHeap(&ob.asks.indexedHeap, canceled)
+	ob.emitRemoved(canceled)
+	canceled = ob.drainStops(nil, canceled)
+	ob.bidVol, ob.askVol = decimal.Zero, decimal.Zero
+	ob.refreshTop()
+	return canceled
+}
+
+// CancelSide removes every resting order and pending trailing stop on one side
+// of the book in one step and returns copies of the removed orders.
+func (ob *OrderBook) CancelSide(side Side) []Order {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	var canceled []Order
+	if side == Buy {
+		canceled = drainHeap(&ob.bids.indexedHeap, nil)
+		ob.bidVol = decimal.Zero
+	} else {
+		canceled = drainHeap(&ob.asks.indexedHeap, nil)
+		ob.askVol = decimal.Zero
+	}
+	ob.emitRemoved(canceled)
+	canceled = ob.drainStops(&side, canceled)
+	ob.refreshTop()
+	return canceled
+}
+
+// orderIDs returns the IDs of the resting orders and pending trailing stops
+// on the given side, or on both sides if side is nil.
+func (ob *OrderBook) orderIDs(side *Side) []string {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	var ids []string
+	if side == nil || *side == Buy {
+		for _, o := range ob.bids.orderHeap {
+			ids = append(ids, o.ID)
+		}
+	}
+	if side == nil || *side == Sell {
+		for _, o := range ob.asks.orderHeap {
+			ids = append(ids, o.ID)
+		}
+	}
+	for _, s := range ob.stops {
+		if side == nil || s.order.Side == *side {
+			ids = append(ids, s.order.ID)
+		}
+	}
+	return ids
+}
+
+// drainHeap empties h without per-order heap operations, appending copies of
+// its orders to into. The caller must hold the book mutex and refresh the
+// cached top of book afterwards.
+func drainHeap(h *indexedHeap, into []Order) []Order {
+	for _, o := range h.orderHeap {
+		into = append(into, *o)
+		releaseOrder(o)
+	}
+	h.reset()
+	return into
+}
+
+// removeAt removes the order at index i of h and returns a copy of it. The
+// caller must hold ob.mutex.
+func (ob *OrderBook) removeAt(h heap.Interface, i int) Order {
+	o := heap.Remove(h, i).(*Order)
+	ob.addVolume(o.Side, o.Qty.Neg())
+	ob.emit(Removed, *o)
+	ob.refreshTop()
+	removed := *o
+	releaseOrder(o)
+	return removed
+}
+
+// Snapshot returns a copy of the resting orders on both sides in priority
+// order and of the pending trailing stops. Trade statistics are tracked by the
+// engine and are left empty.
+func (ob *OrderBook) Snapshot() BookSnapshot {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	return BookSnapshot{
+		Pair:      ob.Pair,
+		Bids:      sortedOrders(bidOrders(ob.bids.clone())),
+		Asks:      sortedOrders(askOrders(ob.asks.clone())),
+		Stops:     ob.stopOrders(),
+		Timestamp: ob.clock.Now().Unix(),
+		TradeSeq:  ob.tradeSeq,
+	}
+}
+
+// Restore replaces the contents of the book with the orders in snapshot.
+func (ob *OrderBook) Restore(snapshot BookSnapshot) {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	ob.clear()
+	for _, order := range snapshot.Bids {
+		ob.appendResting(order)
+		ob.seq = max(ob.seq, order.Seq)
+	}
+	for _, order := range snapshot.Asks {
+		ob.appendResting(order)
+		ob.seq = max(ob.seq, order.Seq)
+	}
+	heap.Init(ob.bids)
+	heap.Init(ob.asks)
+	for _, order := range snapshot.Stops {
+		s := &trailingStop{order: order}
+		s.restoreAnchor()
+		ob.stops = append(ob.stops, s)
+		ob.seq = max(ob.seq, order.Seq)
+	}
+	ob.tradeSeq = snapshot.TradeSeq
+	ob.refreshTop()
+}
+
+// LoadResting adds orders to the book as resting orders without matching
+// them. The orders are appended to their sides and each heap is rebuilt once,
+// which costs O(n) instead of the O(n log n) of adding them one at a time, so
+// it suits warm-starting a book or seeding benchmarks.
 //
-// Returns an empty slice if depth <= 0 or there are no bid orders.
-func (ob *OrderBook) GetBidDepth(depth int) []DepthLevel {
+// The orders must not cross each other or the orders already resting; this
+// is not checked. Orders without a sequence number are assigned one in slice
+// order, so earlier orders keep time priority at equal prices.
+func (ob *OrderBook) LoadResting(orders []Order) {
 	ob.mutex.Lock()
 	defer ob.mutex.Unlock()
 
-	if depth <= 0 || ob.bids.Len() == 0 {
-		return []DepthLevel{}
+	ob.bids.orderHeap = slices.Grow(ob.bids.orderHeap, len(orders))
+	ob.asks.orderHeap = slices.Grow(ob.asks.orderHeap, len(orders))
+	for _, order := range orders {
+		if order.Seq == 0 {
+			ob.seq++
+			order.Seq = ob.seq
+		} else {
+			ob.seq = max(ob.seq, order.Seq)
+		}
+		ob.appendResting(order)
+		ob.emit(Added, order)
+	}
+	heap.Init(ob.bids)
+	heap.Init(ob.asks)
+	ob.refreshTop()
+}
+
+// nextTradeID returns the ID for the book's next trade. IDs are the pair
+// followed by a per-pair sequence number, so replaying the same orders yields
+// the same IDs. The caller must hold ob.mutex.
+func (ob *OrderBook) nextTradeID() string {
+	ob.tradeSeq++
+	return ob.Pair + "-" + strconv.FormatUint(ob.tradeSeq, 10)
+}
+
+// Clear drops every resting order and pending trailing stop in one step under
+// the book lock, so it never interleaves with matching. Unlike CancelAll it
+// does not return copies of the dropped orders, which makes it the cheaper way
+// to empty a book that is being reset.
+func (ob *OrderBook) Clear() {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	if ob.recordEvents {
+		for _, o := range ob.bids.orderHeap {
+			ob.emit(Removed, *o)
+		}
+		for _, o := range ob.asks.orderHeap {
+			ob.emit(Removed, *o)
+		}
 	}
+	ob.clear()
+}
 
-	priceMap := make(map[string]decimal.Decimal)
-	countMap := make(map[string]int)
+// clear drops every resting order and pending trailing stop. The caller must
+// hold ob.mutex.
+func (ob *OrderBook) clear() {
+	for _, o := range ob.bids.orderHeap {
+		releaseOrder(o)
+	}
+	for _, o := range ob.asks.orderHeap {
+		releaseOrder(o)
+	}
+	ob.bids.reset()
+	ob.asks.reset()
+	ob.stops = nil
+	ob.hasAON = false
+	ob.bidVol, ob.askVol = decimal.Zero, decimal.Zero
+	ob.refreshTop()
+}
 
-	for _, order := range ob.bids.orderHeap {
-		priceKey := order.Price.String()
-		priceMap[priceKey] = priceMap[priceKey].Add(order.Qty)
-		countMap[priceKey]++
+// sortedOrders drains h and returns copies of its orders in priority order.
+func sortedOrders(h heap.Interface) []Order {
+	orders := make([]Order, 0, h.Len())
+	for h.Len() > 0 {
+		orders = append(orders, *heap.Pop(h).(*Order))
 	}
+	return orders
+}
 
-	var levels []DepthLevel
-	processedPrices := make(map[string]bool)
+// TopOfBook returns the best bid and ask prices together with the aggregate
+// quantity resting at each of those price levels. All four values are read
+// under a single lock acquisition, so they describe the same instant. Values
+// for an empty side are zero.
+func (ob *OrderBook) TopOfBook() (bid, ask, bidQty, askQty decimal.Decimal) {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
 
-	for _, order := range ob.bids.orderHeap {
-		priceKey := order.Price.String()
-		if processedPrices[priceKey] {
+	if ob.bids.Len() > 0 {
+		bid = ob.bids.orderHeap[0].Price
+		bidQty = levelQty(ob.bids.orderHeap, bid, decimal.Decimal.LessThan)
+	}
+	if ob.asks.Len() > 0 {
+		ask = ob.asks.orderHeap[0].Price
+		askQty = levelQty(ob.asks.orderHeap, ask, decimal.Decimal.GreaterThan)
+	}
+	return bid, ask, bidQty, askQty
+}
+
+// MidPrice returns the exact midpoint of the best bid and ask prices. The ok
+// result is false if either side is empty, since a one-sided book has no
+// meaningful mid.
+func (ob *OrderBook) MidPrice() (mid decimal.Decimal, ok bool) {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	if ob.bids.Len() == 0 || ob.asks.Len() == 0 {
+		return decimal.Zero, false
+	}
+	return ob.bids.orderHeap[0].Price.Add(ob.asks.orderHeap[0].Price).Div(decimal.NewFromInt(2)), true
+}
+
+// WouldBeTaker reports whether a limit order on side at price would cross the
+// best opposite price and take liquidity rather than rest as a maker. An order
+// that exactly touches the best opposite price takes. Prices are compared as
+// matching compares them, and resting orders matching would pass over,
+// all-or-none orders and orders that have expired, do not count. It returns
+// false if no such order rests on the opposite side. The book is not
+// modified.
+func (ob *OrderBook) WouldBeTaker(side Side, price decimal.Decimal) bool {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	probe := Order{Side: side, Price: price}
+	ob.setTicks(&probe)
+	h := ob.bids.orderHeap
+	if side == Buy {
+		h = ob.asks.orderHeap
+	}
+	if len(h) == 0 {
+		return false
+	}
+
+	// A subtree whose root does not cross holds only worse prices, so only
+	// the crossing top of the heap is searched for an order that would trade.
+	now := ob.clock.Now().Unix()
+	stack := []int{0}
+	for len(stack) > 0 {
+		i := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		o := h[i]
+		if !crosses(&probe, o) {
 			continue
 		}
+		if !o.AON && !o.expired(now) && o.Qty.IsPositive() {
+			return true
+		}
+		for _, child := range []int{2*i + 1, 2*i + 2} {
+			if child < len(h) {
+				stack = append(stack, child)
+			}
+		}
+	}
+	return false
+}
 
-		levels = append(levels, DepthLevel{
-			Price:      order.Price,
-			Quantity:   priceMap[priceKey],
-			TradeCount: countMap[priceKey],
-		})
-		processedPrices[priceKey] = true
+// QuantityAtPrice returns the aggregate quantity resting on the given side at
+// exactly price, compared by decimal value. Returns zero if no order rests at
+// that price.
+func (ob *OrderBook) QuantityAtPrice(side Side, price decimal.Decimal) decimal.Decimal {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
 
-		if len(levels) >= depth {
-			break
+	if side == Buy {
+		return levelQty(ob.bids.orderHeap, price, decimal.Decimal.LessThan)
+	}
+	return levelQty(ob.asks.orderHeap, price, decimal.Decimal.GreaterThan)
+}
+
+// levelQty sums the quantity of all orders in h priced at price. worse reports
+// whether a price has lower priority than another on this side; a subtree
+// whose root is already worse than price cannot contain it and is skipped.
+func levelQty(h orderHeap, price decimal.Decimal, worse func(a, b decimal.Decimal) bool) decimal.Decimal {
+	total := decimal.Zero
+	if len(h) == 0 {
+		return total
+	}
+
+	stack := []int{0}
+	for len(stack) > 0 {
+		i := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if worse(h[i].Price, price) {
+			continue
+		}
+		if h[i].Price.Equal(price) {
+			total = total.Add(h[i].Qty)
+		}
+		for _, child := range []int{2*i + 1, 2*i + 2} {
+			if child < len(h) {
+				stack = append(stack, child)
+			}
 		}
 	}
+	return total
+}
 
-	return levels
+// refreshTop re-prices any resting pegged orders and updates the cached
+// top-of-book prices from the heaps. It must be called with ob.mutex held after
+// any change to the resting orders.
+func (ob *OrderBook) refreshTop() {
+	if ob.hasPegs {
+		ob.repricePegs()
+	}
+	storeTop(&ob.bestBid, ob.bids.orderHeap)
+	storeTop(&ob.bestAsk, ob.asks.orderHeap)
+	ob.dirty.Store(true)
+}
+
+// takeChanged reports whether the resting orders changed since the last call,
+// or since the book was created, and clears the flag.
+func (ob *OrderBook) takeChanged() bool {
+	return ob.dirty.Swap(false)
+}
+
+// storeTop caches the price at the top of h, or nil if h is empty. The cache
+// is only replaced when the top price actually changes.
+func storeTop(cache *atomic.Pointer[decimal.Decimal], h orderHeap) {
+	if len(h) == 0 {
+		cache.Store(nil)
+		return
+	}
+	if cur := cache.Load(); cur != nil && cur.Equal(h[0].Price) {
+		return
+	}
+	price := h[0].Price
+	cache.Store(&price)
+}
+
+// GetBidDepth returns the bid side market depth up to the specified number of price levels.
+// Each DepthLevel contains the aggregated quantity and trade count for orders at that price.
+// The levels are ordered from highest to lowest price (best to worst for buyers).
+//
+// Parameters:
+//   - depth: Maximum number of price levels to return
+//
+// Returns an empty slice if depth <= 0 or there are no bid orders.
+func (ob *OrderBook) GetBidDepth(depth int) []DepthLevel {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	return ob.bidLevels(depth)
 }
 
 // GetAskDepth returns the ask side market depth up to the specified number of price levels.
@@ -346,43 +1217,331 @@ func (ob *OrderBook) GetAskDepth(depth int) []DepthLevel {
 	ob.mutex.Lock()
 	defer ob.mutex.Unlock()
 
+	return ob.askLevels(depth)
+}
+
+// GetBidDepthNotional is like GetBidDepth but expresses each level in notional
+// terms: Quantity is replaced by price * quantity and CumQuantity by the
+// cumulative notional, so orders can be sized against quote-currency
+// liquidity.
+func (ob *OrderBook) GetBidDepthNotional(depth int) []DepthLevel {
+	return inNotional(ob.GetBidDepth(depth))
+}
+
+// GetAskDepthNotional is like GetAskDepth but expresses each level in notional
+// terms, as GetBidDepthNotional does for bids.
+func (ob *OrderBook) GetAskDepthNotional(depth int) []DepthLevel {
+	return inNotional(ob.GetAskDepth(depth))
+}
+
+// GetBidDepthGrouped is like GetBidDepth but buckets the bids into price bands
+// of width grouping, independent of the pair's tick size, for a coarser view
+// of the book. Each band is labelled with its price rounded down to a multiple
+// of grouping, so a band never shows a better price than any bid in it, and
+// aggregates the quantity and order count of every price level that falls in
+// it. CumNotional keeps using the orders' own prices. A grouping that is not
+// positive returns the ungrouped depth.
+func (ob *OrderBook) GetBidDepthGrouped(depth int, grouping decimal.Decimal) []DepthLevel {
+	if !grouping.IsPositive() {
+		return ob.GetBidDepth(depth)
+	}
+
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	if depth <= 0 || ob.bids.Len() == 0 {
+		return []DepthLevel{}
+	}
+	return groupedLevels(bidOrders(ob.bids.clone()), depth, grouping, RoundDown)
+}
+
+// GetAskDepthGrouped is like GetBidDepthGrouped for the asks, whose bands are
+// labelled with their price rounded up to a multiple of grouping.
+func (ob *OrderBook) GetAskDepthGrouped(depth int, grouping decimal.Decimal) []DepthLevel {
+	if !grouping.IsPositive() {
+		return ob.GetAskDepth(depth)
+	}
+
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
 	if depth <= 0 || ob.asks.Len() == 0 {
 		return []DepthLevel{}
 	}
+	return groupedLevels(askOrders(ob.asks.clone()), depth, grouping, RoundUp)
+}
 
-	priceMap := make(map[string]decimal.Decimal)
-	countMap := make(map[string]int)
+// inNotional converts levels in place to notional terms and returns them.
+func inNotional(levels []DepthLevel) []DepthLevel {
+	for i := range levels {
+		levels[i].Quantity = levels[i].Price.Mul(levels[i].Quantity)
+		levels[i].CumQuantity = levels[i].CumNotional
+	}
+	return levels
+}
 
-	for _, order := range ob.asks.orderHeap {
-		priceKey := order.Price.String()
-		priceMap[priceKey] = priceMap[priceKey].Add(order.Qty)
-		countMap[priceKey]++
+// bidLevels aggregates up to depth bid levels. The caller must hold ob.mutex.
+func (ob *OrderBook) bidLevels(depth int) []DepthLevel {
+	if depth <= 0 || ob.bids.Len() == 0 {
+		return []DepthLevel{}
 	}
+	return depthLevels(bidOrders(ob.bids.clone()), depth)
+}
 
-	var levels []DepthLevel
-	processedPrices := make(map[string]bool)
+// askLevels aggregates up to depth ask levels. The caller must hold ob.mutex.
+func (ob *OrderBook) askLevels(depth int) []DepthLevel {
+	if depth <= 0 || ob.asks.Len() == 0 {
+		return []DepthLevel{}
+	}
+	return depthLevels(askOrders(ob.asks.clone()), depth)
+}
 
-	for _, order := range ob.asks.orderHeap {
-		priceKey := order.Price.String()
-		if processedPrices[priceKey] {
-			continue
+// WalkLevels calls fn for each aggregated price level of the given side in
+// priority order, bids from highest to lowest price and asks from lowest to
+// highest, until fn returns false. Levels are aggregated as they are visited,
+// so a consumer that stops early never pays for the rest of the book. fn runs
+// under the book lock and must not call back into the book.
+func (ob *OrderBook) WalkLevels(side Side, fn func(level DepthLevel) bool) {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	if side == Buy {
+		walkLevels(bidOrders(ob.bids.clone()), fn)
+	} else {
+		walkLevels(askOrders(ob.asks.clone()), fn)
+	}
+}
+
+// QueuePosition reports where the resting order with the given ID or client
+// order ID stands in the time queue at its price: the total quantity of the
+// orders at the same price that arrived before it, and its 1-based position
+// among them. It returns false if no such order is resting; pending stops are
+// not in a queue.
+func (ob *OrderBook) QueuePosition(id string) (ahead decimal.Decimal, position int, ok bool) {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	order, ok := ob.lookup(id)
+	if !ok {
+		return decimal.Zero, 0, false
+	}
+	side := &ob.asks.indexedHeap
+	if order.Side == Buy {
+		side = &ob.bids.indexedHeap
+	}
+	if side.indexOf(order.ID) < 0 {
+		return decimal.Zero, 0, false
+	}
+
+	ahead, position = decimal.Zero, 1
+	for _, o := range side.orderHeap {
+		if o.Seq < order.Seq && comparePrices(o, &order) == 0 {
+			ahead = ahead.Add(o.Qty)
+			position++
 		}
+	}
+	return ahead, position, true
+}
 
-		levels = append(levels, DepthLevel{
-			Price:      order.Price,
-			Quantity:   priceMap[priceKey],
-			TradeCount: countMap[priceKey],
-		})
-		processedPrices[priceKey] = true
+// ForEachOrder calls fn with a copy of each resting order on the given side
+// in priority order, best price first and by arrival within a price, until fn
+// returns false. Orders are ranked as they are visited from a copy of the
+// heap's pointers, so the orders themselves are never copied in bulk and a
+// consumer that stops early never pays for sorting the rest of the book. fn
+// runs under the book lock and must not call back into the book.
+func (ob *OrderBook) ForEachOrder(side Side, fn func(order Order) bool) {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
 
-		if len(levels) >= depth {
-			break
+	var h heap.Interface = askOrders(ob.asks.clone())
+	if side == Buy {
+		h = bidOrders(ob.bids.clone())
+	}
+	for h.Len() > 0 {
+		if !fn(*heap.Pop(h).(*Order)) {
+			return
 		}
 	}
+}
+
+// LiquidityWithin returns the quantity resting on each side at prices within
+// percent of the mid price, for example within 1% for a percent of 1. Each
+// side is walked from the best price outward and stops at the first level
+// outside the band. Returns zeros if either side is empty, since there is no
+// mid to measure from.
+func (ob *OrderBook) LiquidityWithin(percent decimal.Decimal) (bidQty, askQty decimal.Decimal) {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	if ob.bids.Len() == 0 || ob.asks.Len() == 0 {
+		return decimal.Zero, decimal.Zero
+	}
+	mid := ob.bids.orderHeap[0].Price.Add(ob.asks.orderHeap[0].Price).Div(decimal.NewFromInt(2))
+	band := mid.Mul(percent).Div(hundred)
+	low, high := mid.Sub(band), mid.Add(band)
+
+	walkLevels(bidOrders(ob.bids.clone()), func(level DepthLevel) bool {
+		if level.Price.LessThan(low) {
+			return false
+		}
+		bidQty = bidQty.Add(level.Quantity)
+		return true
+	})
+	walkLevels(askOrders(ob.asks.clone()), func(level DepthLevel) bool {
+		if level.Price.GreaterThan(high) {
+			return false
+		}
+		askQty = askQty.Add(level.Quantity)
+		return true
+	})
+	return bidQty, askQty
+}
+
+// Imbalance returns the order-flow imbalance over the top levels price levels
+// of each side, computed as (bidVol - askVol) / (bidVol + askVol). The result
+// lies in [-1, 1]: positive when bids dominate, negative when asks dominate.
+// Returns zero when both sides are empty or levels <= 0.
+func (ob *OrderBook) Imbalance(levels int) decimal.Decimal {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	bidVol := sumQuantity(ob.bidLevels(levels))
+	askVol := sumQuantity(ob.askLevels(levels))
+	total := bidVol.Add(askVol)
+	if total.IsZero() {
+		return decimal.Zero
+	}
+	return bidVol.Sub(askVol).Div(total)
+}
+
+// Microprice returns the size-weighted fair value of the top of book,
+// (bidPx*askQty + askPx*bidQty) / (bidQty + askQty). It leans toward the side
+// with less resting quantity, where the next trade is more likely to occur.
+// Returns zero if either side is empty.
+func (ob *OrderBook) Microprice() decimal.Decimal {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	if ob.bids.Len() == 0 || ob.asks.Len() == 0 {
+		return decimal.Zero
+	}
+	bid, ask := ob.bids.orderHeap[0].Price, ob.asks.orderHeap[0].Price
+	bidQty := levelQty(ob.bids.orderHeap, bid, decimal.Decimal.LessThan)
+	askQty := levelQty(ob.asks.orderHeap, ask, decimal.Decimal.GreaterThan)
+	return bid.Mul(askQty).Add(ask.Mul(bidQty)).Div(bidQty.Add(askQty))
+}
+
+// WeightedMid returns the midpoint of the volume-weighted average bid and ask
+// prices over the top levels price levels of each side. With levels = 1 this
+// is the plain mid. Returns zero if either side is empty or levels <= 0.
+func (ob *OrderBook) WeightedMid(levels int) decimal.Decimal {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	bids, asks := ob.bidLevels(levels), ob.askLevels(levels)
+	if len(bids) == 0 || len(asks) == 0 {
+		return decimal.Zero
+	}
+	return weightedPrice(bids).Add(weightedPrice(asks)).Div(decimal.NewFromInt(2))
+}
+
+// weightedPrice returns the volume-weighted average price of levels, which
+// must not be empty.
+func weightedPrice(levels []DepthLevel) decimal.Decimal {
+	value := decimal.Zero
+	for _, level := range levels {
+		value = value.Add(level.Price.Mul(level.Quantity))
+	}
+	return value.Div(sumQuantity(levels))
+}
+
+// sumQuantity returns the total quantity across levels.
+func sumQuantity(levels []DepthLevel) decimal.Decimal {
+	total := decimal.Zero
+	for _, level := range levels {
+		total = total.Add(level.Quantity)
+	}
+	return total
+}
+
+// clone returns a copy of the heap slice. The copy already satisfies the heap
+// invariant, so it can be consumed with heap.Pop without disturbing the book.
+func (h orderHeap) clone() orderHeap {
+	return append(orderHeap(nil), h...)
+}
+
+// depthLevels aggregates the orders in h into at most depth price levels in
+// priority order, with running totals from the best price outward. h is
+// consumed, so callers pass a clone of the book's heap.
+func depthLevels(h heap.Interface, depth int) []DepthLevel {
+	levels := make([]DepthLevel, 0, depth)
+	cumQty, cumNotional := decimal.Zero, decimal.Zero
+	walkLevels(h, func(level DepthLevel) bool {
+		cumQty = cumQty.Add(level.Quantity)
+		cumNotional = cumNotional.Add(level.Price.Mul(level.Quantity))
+		level.CumQuantity, level.CumNotional = cumQty, cumNotional
+		levels = append(levels, level)
+		return len(levels) < depth
+	})
+	return levels
+}
+
+// groupedLevels aggregates the orders in h into at most depth bands of width
+// grouping in priority order, labelling each band with the price of its levels
+// rounded in mode. Running totals are taken from the best price outward. h is
+// consumed, so callers pass a clone of the book's heap.
+func groupedLevels(h heap.Interface, depth int, grouping decimal.Decimal, mode Rounding) []DepthLevel {
+	levels := make([]DepthLevel, 0, depth)
+	cumQty, cumNotional := decimal.Zero, decimal.Zero
+	walkLevels(h, func(level DepthLevel) bool {
+		band := roundTo(level.Price, grouping, mode)
+		n := len(levels)
+		if n == 0 || !levels[n-1].Price.Equal(band) {
+			if n == depth {
+				return false
+			}
+			levels = append(levels, DepthLevel{Price: band})
+			n++
+		}
+		cumQty = cumQty.Add(level.Quantity)
+		cumNotional = cumNotional.Add(level.Price.Mul(level.Quantity))
 
+		last := &levels[n-1]
+		last.Quantity = last.Quantity.Add(level.Quantity)
+		last.TradeCount += level.TradeCount
+		last.CumQuantity, last.CumNotional = cumQty, cumNotional
+		return true
+	})
 	return levels
 }
 
+// walkLevels aggregates the orders in h into price levels, popping orders
+// best-first, and passes each level to fn until fn returns false. Orders whose
+// prices are equal as decimals (e.g. 100 and 100.0) collapse into a single
+// level. h is consumed.
+func walkLevels(h heap.Interface, fn func(DepthLevel) bool) {
+	var level DepthLevel
+	for h.Len() > 0 {
+		order := heap.Pop(h).(*Order)
+		if level.TradeCount > 0 && level.Price.Equal(order.Price) {
+			level.Quantity = level.Quantity.Add(order.Qty)
+			level.TradeCount++
+			continue
+		}
+		if level.TradeCount > 0 && !fn(level) {
+			return
+		}
+		level = DepthLevel{
+			Price:      order.Price,
+			Quantity:   order.Qty,
+			TradeCount: 1,
+		}
+	}
+	if level.TradeCount > 0 {
+		fn(level)
+	}
+}
+
 // min returns the smaller of two decimal values.
 func min(a, b decimal.Decimal) decimal.Decimal {
 	if a.LessThan(b) {
@@ -1,6 +1,8 @@
 package engine
 
 import (
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -25,6 +27,23 @@ func TestNewOrderBook(t *testing.T) {
 	}
 }
 
+// TestNewOrderBookWithCapacity tests that the capacity hint preallocates both sides
+func TestNewOrderBookWithCapacity(t *testing.T) {
+	ob := NewOrderBookWithCapacity("BTC-USDT", 500)
+	if c := cap(ob.bids.orderHeap); c != 500 {
+		t.Errorf("Expected bid capacity 500, got %d", c)
+	}
+	if c := cap(ob.asks.orderHeap); c != 500 {
+		t.Errorf("Expected ask capacity 500, got %d", c)
+	}
+	if bids, asks := ob.OpenOrderCount(); bids != 0 || asks != 0 {
+		t.Errorf("Expected an empty book, got %d bids and %d asks", bids, asks)
+	}
+	if ob := NewOrderBookWithCapacity("BTC-USDT", -1); cap(ob.bids.orderHeap) != 0 {
+		t.Errorf("Expected a negative hint to be treated as zero, got %d", cap(ob.bids.orderHeap))
+	}
+}
+
 // TestOrderBookBestPrices tests the BestBid and BestAsk methods
 func TestOrderBookBestPrices(t *testing.T) {
 	ob := NewOrderBook("BTC-USDT")
@@ -109,14 +128,14 @@ func TestOrderBookMatching(t *testing.T) {
 		t.Error("Expected a trade to be generated")
 	}
 
-	// Check fill events - should have 2 fills (one for each order)
+	// Check fill events - should have the buy's acknowledgment and 2 fills (one for each order)
 	fillCount := 0
 	for len(fillCh) > 0 {
 		<-fillCh
 		fillCount++
 	}
-	if fillCount != 2 {
-		t.Errorf("Expected 2 fill events, got %d", fillCount)
+	if fillCount != 3 {
+		t.Errorf("Expected 3 fill events, got %d", fillCount)
 	}
 }
 
@@ -386,6 +405,48 @@ func TestOrderFillEvents(t *testing.T) {
 	}
 }
 
+// TestOrderAcknowledgment tests that every accepted order gets exactly one NEW fill ahead of its execution fills
+func TestOrderAcknowledgment(t *testing.T) {
+	tests := []struct {
+		name     string
+		qty      int64
+		statuses []FillStatus
+	}{
+		{"unmatched", 1, []FillStatus{New}},
+		{"partially matched", 3, []FillStatus{New, PartiallyFilled}},
+		{"fully matched", 2, []FillStatus{New, Filled}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ob := NewOrderBook("BTC-USD")
+			ob.Execute(Order{ID: "ask", Side: Sell, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(2)})
+
+			price := decimal.NewFromInt(101)
+			if tt.name == "unmatched" {
+				price = decimal.NewFromInt(100)
+			}
+			res := ob.Execute(Order{ID: "buy", Side: Buy, Price: price, Qty: decimal.NewFromInt(tt.qty)})
+
+			var statuses []FillStatus
+			for _, fill := range res.Fills {
+				if fill.OrderID == "buy" {
+					statuses = append(statuses, fill.Status)
+				}
+			}
+			if fmt.Sprint(statuses) != fmt.Sprint(tt.statuses) {
+				t.Errorf("Expected fills %v, got %v", tt.statuses, statuses)
+			}
+		})
+	}
+
+	ob := NewOrderBook("BTC-USD")
+	res := ob.Execute(Order{ID: "bad", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.Zero})
+	if len(res.Fills) != 1 || res.Fills[0].Status != Rejected {
+		t.Errorf("Expected a rejected order to get no acknowledgment, got %+v", res.Fills)
+	}
+}
+
 // TestOrderFillAtTopPriceSell tests that SELL orders are filled at the top BUY price, not SELL order price
 func TestOrderFillAtTopPriceSell(t *testing.T) {
 	ob := NewOrderBook("BTC-USDT")
@@ -416,6 +477,9 @@ func TestOrderFillAtTopPriceSell(t *testing.T) {
 
 	ob.Match(sellOrder, tradeCh, fillCh, sellOrder.Qty)
 
+	// Skip the NEW fill event acknowledging SELL-1
+	<-fillCh
+
 	for i := 0; i < 2; i++ {
 		select {
 		case fill := <-fillCh:
@@ -483,6 +547,9 @@ func TestOrderFillAtTopPriceBuy(t *testing.T) {
 
 	ob.Match(buyOrder, tradeCh, fillCh, buyOrder.Qty)
 
+	// Skip the NEW fill event acknowledging BUY-1
+	<-fillCh
+
 	for i := 0; i < 2; i++ {
 		select {
 		case fill := <-fillCh:
@@ -520,3 +587,1123 @@ func TestOrderFillAtTopPriceBuy(t *testing.T) {
 		}
 	}
 }
+
+// TestExecuteMatchesMatch tests that Execute produces the same events as Match
+func TestExecuteMatchesMatch(t *testing.T) {
+	viaMatch := NewOrderBook("BTC-USDT")
+	viaExecute := NewOrderBook("BTC-USDT")
+	tradeCh := make(chan Trade, 10)
+	fillCh := make(chan OrderFill, 10)
+
+	orders := []Order{
+		{ID: "sell1", Side: Sell, Price: decimal.NewFromFloat(101.0), Qty: decimal.NewFromFloat(1.0)},
+		{ID: "sell2", Side: Sell, Price: decimal.NewFromFloat(102.0), Qty: decimal.NewFromFloat(2.0)},
+		{ID: "buy1", Side: Buy, Price: decimal.NewFromFloat(102.0), Qty: decimal.NewFromFloat(2.5)},
+	}
+
+	for _, order := range orders {
+		viaMatch.Match(order, tradeCh, fillCh, order.Qty)
+		result := viaExecute.Execute(order)
+
+		for _, trade := range result.Trades {
+			expected := <-tradeCh
+			if fmt.Sprintf("%+v", expected) != fmt.Sprintf("%+v", trade) {
+				t.Errorf("Expected trade %+v, got %+v", expected, trade)
+			}
+		}
+		for _, fill := range result.Fills {
+			expected := <-fillCh
+			if fmt.Sprintf("%+v", expected) != fmt.Sprintf("%+v", fill) {
+				t.Errorf("Expected fill %+v, got %+v", expected, fill)
+			}
+		}
+		if len(tradeCh) != 0 || len(fillCh) != 0 {
+			t.Errorf("Execute for %s produced fewer events than Match", order.ID)
+		}
+	}
+
+	if viaExecute.BestAsk() != 102.0 {
+		t.Errorf("Expected remaining ask at 102.0, got %f", viaExecute.BestAsk())
+	}
+}
+
+// TestDepthMergesEquivalentPrices tests that decimal-equal prices aggregate into one level
+func TestDepthMergesEquivalentPrices(t *testing.T) {
+	ob := NewOrderBook("BTC-USDT")
+	tradeCh := make(chan Trade, 10)
+	fillCh := make(chan OrderFill, 10)
+
+	prices := []decimal.Decimal{
+		decimal.NewFromFloat(100),
+		decimal.NewFromInt(100),
+		decimal.RequireFromString("100.00"),
+	}
+	for i, price := range prices {
+		order := Order{
+			ID:    fmt.Sprintf("buy%d", i),
+			Side:  Buy,
+			Price: price,
+			Qty:   decimal.NewFromInt(1),
+			Time:  time.Now().Unix(),
+		}
+		ob.Match(order, tradeCh, fillCh, order.Qty)
+	}
+
+	depth := ob.GetBidDepth(5)
+	if len(depth) != 1 {
+		t.Fatalf("Expected 1 depth level, got %d", len(depth))
+	}
+	if !depth[0].Quantity.Equal(decimal.NewFromInt(3)) {
+		t.Errorf("Expected level quantity 3, got %s", depth[0].Quantity.String())
+	}
+	if depth[0].TradeCount != 3 {
+		t.Errorf("Expected level trade count 3, got %d", depth[0].TradeCount)
+	}
+}
+
+// TestDepthPriceOrdering tests that depth levels are returned best price first
+func TestDepthPriceOrdering(t *testing.T) {
+	ob := NewOrderBook("BTC-USDT")
+	tradeCh := make(chan Trade, 20)
+	fillCh := make(chan OrderFill, 20)
+
+	for i, price := range []float64{97, 101, 99, 100, 98} {
+		buy := Order{ID: fmt.Sprintf("buy%d", i), Side: Buy, Price: decimal.NewFromFloat(price), Qty: decimal.NewFromInt(1)}
+		ob.Match(buy, tradeCh, fillCh, buy.Qty)
+		sell := Order{ID: fmt.Sprintf("sell%d", i), Side: Sell, Price: decimal.NewFromFloat(price + 10), Qty: decimal.NewFromInt(1)}
+		ob.Match(sell, tradeCh, fillCh, sell.Qty)
+	}
+
+	bids := ob.GetBidDepth(3)
+	for i, expected := range []float64{101, 100, 99} {
+		if !bids[i].Price.Equal(decimal.NewFromFloat(expected)) {
+			t.Errorf("Expected bid level %d at %v, got %s", i, expected, bids[i].Price.String())
+		}
+	}
+
+	asks := ob.GetAskDepth(3)
+	for i, expected := range []float64{107, 108, 109} {
+		if !asks[i].Price.Equal(decimal.NewFromFloat(expected)) {
+			t.Errorf("Expected ask level %d at %v, got %s", i, expected, asks[i].Price.String())
+		}
+	}
+}
+
+// TestCachedTopOfBookConsistency tests that the cached best prices track the heaps under concurrent reads
+func TestCachedTopOfBookConsistency(t *testing.T) {
+	ob := NewOrderBook("BTC-USDT")
+	tradeCh := make(chan Trade, 1000)
+	fillCh := make(chan OrderFill, 1000)
+	go func() {
+		for range tradeCh {
+		}
+	}()
+	go func() {
+		for range fillCh {
+		}
+	}()
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					bid, ask := ob.BestBid(), ob.BestAsk()
+					if bid < 0 || ask < 0 {
+						t.Errorf("Unexpected negative best price: bid %f ask %f", bid, ask)
+					}
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 2000; i++ {
+		side := Buy
+		if i%2 == 0 {
+			side = Sell
+		}
+		order := Order{
+			ID:    fmt.Sprintf("O%d", i),
+			Side:  side,
+			Price: decimal.NewFromInt(int64(90 + (i*7)%21)),
+			Qty:   decimal.NewFromInt(int64(1 + i%3)),
+		}
+		ob.Match(order, tradeCh, fillCh, order.Qty)
+
+		ob.mutex.Lock()
+		checkTopCache(t, "bid", ob.bestBid.Load(), ob.bids.orderHeap)
+		checkTopCache(t, "ask", ob.bestAsk.Load(), ob.asks.orderHeap)
+		ob.mutex.Unlock()
+	}
+
+	close(stop)
+	wg.Wait()
+	close(tradeCh)
+	close(fillCh)
+}
+
+// checkTopCache fails the test if the cached price differs from the heap top
+func checkTopCache(t *testing.T, side string, cached *decimal.Decimal, h orderHeap) {
+	t.Helper()
+	if len(h) == 0 {
+		if cached != nil {
+			t.Errorf("Expected empty %s cache, got %s", side, cached.String())
+		}
+		return
+	}
+	if cached == nil || !cached.Equal(h[0].Price) {
+		t.Errorf("Expected cached %s %s, got %v", side, h[0].Price.String(), cached)
+	}
+}
+
+// TestTopOfBook tests the combined best bid/ask snapshot with level quantities
+func TestTopOfBook(t *testing.T) {
+	ob := NewOrderBook("BTC-USDT")
+	tradeCh := make(chan Trade, 10)
+	fillCh := make(chan OrderFill, 10)
+
+	bid, ask, bidQty, askQty := ob.TopOfBook()
+	if !bid.IsZero() || !ask.IsZero() || !bidQty.IsZero() || !askQty.IsZero() {
+		t.Errorf("Expected zero top of book for empty book, got %s/%s %s/%s", bid, ask, bidQty, askQty)
+	}
+
+	orders := []Order{
+		{ID: "buy1", Side: Buy, Price: decimal.NewFromFloat(100.0), Qty: decimal.NewFromFloat(1.0)},
+		{ID: "buy2", Side: Buy, Price: decimal.NewFromFloat(99.0), Qty: decimal.NewFromFloat(5.0)},
+		{ID: "buy3", Side: Buy, Price: decimal.NewFromFloat(100.0), Qty: decimal.NewFromFloat(0.5)},
+		{ID: "sell1", Side: Sell, Price: decimal.NewFromFloat(101.5), Qty: decimal.NewFromFloat(2.0)},
+		{ID: "sell2", Side: Sell, Price: decimal.NewFromFloat(102.0), Qty: decimal.NewFromFloat(3.0)},
+	}
+	for _, order := range orders {
+		ob.Match(order, tradeCh, fillCh, order.Qty)
+		<-fillCh
+	}
+
+	bid, ask, bidQty, askQty = ob.TopOfBook()
+	if !bid.Equal(decimal.NewFromFloat(100.0)) {
+		t.Errorf("Expected best bid 100, got %s", bid.String())
+	}
+	if !ask.Equal(decimal.NewFromFloat(101.5)) {
+		t.Errorf("Expected best ask 101.5, got %s", ask.String())
+	}
+	if !bidQty.Equal(decimal.NewFromFloat(1.5)) {
+		t.Errorf("Expected best bid quantity 1.5, got %s", bidQty.String())
+	}
+	if !askQty.Equal(decimal.NewFromFloat(2.0)) {
+		t.Errorf("Expected best ask quantity 2, got %s", askQty.String())
+	}
+}
+
+// TestCancel tests removing resting orders by ID
+func TestCancel(t *testing.T) {
+	ob := NewOrderBook("BTC-USDT")
+	tradeCh := make(chan Trade, 10)
+	fillCh := make(chan OrderFill, 10)
+
+	for i, price := range []float64{100, 101, 99} {
+		order := Order{ID: fmt.Sprintf("buy%d", i), Side: Buy, Price: decimal.NewFromFloat(price), Qty: decimal.NewFromFloat(1.0)}
+		ob.Match(order, tradeCh, fillCh, order.Qty)
+	}
+
+	removed, ok := ob.Cancel("buy1")
+	if !ok {
+		t.Fatal("Expected buy1 to be canceled")
+	}
+	if !removed.Price.Equal(decimal.NewFromFloat(101)) {
+		t.Errorf("Expected canceled order price 101, got %s", removed.Price.String())
+	}
+	if ob.BestBid() != 100.0 {
+		t.Errorf("Expected best bid 100 after cancel, got %f", ob.BestBid())
+	}
+	if _, ok := ob.Cancel("buy1"); ok {
+		t.Error("Expected second cancel of buy1 to fail")
+	}
+	if depth := ob.GetBidDepth(5); len(depth) != 2 {
+		t.Errorf("Expected 2 bid levels, got %d", len(depth))
+	}
+}
+
+// TestCancelMiddleOfHeap tests that orders deep in the heap are located by ID and removed without corrupting the index
+func TestCancelMiddleOfHeap(t *testing.T) {
+	ob := NewOrderBook("BTC-USDT")
+	orders := make([]Order, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		orders = append(orders, Order{ID: fmt.Sprintf("buy%d", i), Side: Buy, Price: decimal.NewFromInt(int64(1 + (i*37)%500)), Qty: decimal.NewFromInt(1)})
+	}
+	ob.LoadResting(orders)
+
+	i := ob.bids.indexOf("buy500")
+	if i <= 0 || ob.bids.orderHeap[i].ID != "buy500" {
+		t.Fatalf("Expected the index to locate buy500 inside the heap, got position %d", i)
+	}
+	if _, ok := ob.Cancel("buy500"); !ok {
+		t.Fatal("Expected buy500 to be canceled")
+	}
+	if ob.bids.indexOf("buy500") != -1 {
+		t.Error("Expected buy500 to leave the index")
+	}
+	for _, id := range []string{"buy1", "buy999", "buy250"} {
+		if _, ok := ob.Cancel(id); !ok {
+			t.Errorf("Expected %s to be canceled", id)
+		}
+	}
+	if bids, _ := ob.OpenOrderCount(); bids != 996 {
+		t.Errorf("Expected 996 resting bids, got %d", bids)
+	}
+	if err := ob.Validate(); err != nil {
+		t.Errorf("Expected the index to stay consistent, got %v", err)
+	}
+}
+
+// TestHeapIndexConcurrentMatching tests that the ID index stays exact while matching and cancels run concurrently
+func TestHeapIndexConcurrentMatching(t *testing.T) {
+	ob := NewOrderBook("BTC-USDT")
+	ob.SetInvariantChecks(true)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				side := Buy
+				if (i+w)%2 == 0 {
+					side = Sell
+				}
+				id := fmt.Sprintf("w%d-%d", w, i)
+				ob.Execute(Order{ID: id, Side: side, Price: decimal.NewFromInt(int64(95 + (i*7)%11)), Qty: decimal.NewFromInt(int64(1 + i%3))})
+				if i%3 == 0 {
+					ob.Cancel(fmt.Sprintf("w%d-%d", w, i/2))
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if err := ob.Validate(); err != nil {
+		t.Errorf("Expected a consistent book, got %v", err)
+	}
+}
+
+// TestQuantityAtPrice tests aggregate resting quantity lookups by exact price
+func TestQuantityAtPrice(t *testing.T) {
+	ob := NewOrderBook("BTC-USDT")
+	tradeCh := make(chan Trade, 10)
+	fillCh := make(chan OrderFill, 10)
+
+	orders := []Order{
+		{ID: "sell1", Side: Sell, Price: decimal.NewFromFloat(105.0), Qty: decimal.NewFromFloat(1.0)},
+		{ID: "sell2", Side: Sell, Price: decimal.RequireFromString("105.00"), Qty: decimal.NewFromFloat(2.5)},
+		{ID: "sell3", Side: Sell, Price: decimal.NewFromFloat(104.0), Qty: decimal.NewFromFloat(4.0)},
+		{ID: "sell4", Side: Sell, Price: decimal.NewFromFloat(106.0), Qty: decimal.NewFromFloat(0.5)},
+		{ID: "buy1", Side: Buy, Price: decimal.NewFromFloat(100.0), Qty: decimal.NewFromFloat(3.0)},
+	}
+	for _, order := range orders {
+		ob.Match(order, tradeCh, fillCh, order.Qty)
+		<-fillCh
+	}
+
+	if qty := ob.QuantityAtPrice(Sell, decimal.NewFromInt(105)); !qty.Equal(decimal.NewFromFloat(3.5)) {
+		t.Errorf("Expected 3.5 at 105, got %s", qty.String())
+	}
+	if qty := ob.QuantityAtPrice(Sell, decimal.NewFromInt(104)); !qty.Equal(decimal.NewFromFloat(4.0)) {
+		t.Errorf("Expected 4 at 104, got %s", qty.String())
+	}
+	if qty := ob.QuantityAtPrice(Sell, decimal.NewFromInt(103)); !qty.IsZero() {
+		t.Errorf("Expected 0 at 103, got %s", qty.String())
+	}
+	if qty := ob.QuantityAtPrice(Buy, decimal.NewFromInt(105)); !qty.IsZero() {
+		t.Errorf("Expected 0 bids at 105, got %s", qty.String())
+	}
+	if qty := ob.QuantityAtPrice(Buy, decimal.NewFromInt(100)); !qty.Equal(decimal.NewFromFloat(3.0)) {
+		t.Errorf("Expected 3 bids at 100, got %s", qty.String())
+	}
+}
+
+// TestSequenceTieBreak tests that same-price orders with identical timestamps fill in submission order
+func TestSequenceTieBreak(t *testing.T) {
+	ob := NewOrderBook("BTC-USDT")
+	timestamp := time.Now().Unix()
+
+	const count = 50
+	for i := 0; i < count; i++ {
+		ob.Execute(Order{
+			ID:    fmt.Sprintf("sell%d", i),
+			Side:  Sell,
+			Price: decimal.NewFromInt(100),
+			Qty:   decimal.NewFromInt(1),
+			Time:  timestamp,
+		})
+	}
+
+	result := ob.Execute(Order{ID: "buy1", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(count), Time: timestamp})
+	if len(result.Trades) != count {
+		t.Fatalf("Expected %d trades, got %d", count, len(result.Trades))
+	}
+	for i, trade := range result.Trades {
+		if expected := fmt.Sprintf("sell%d", i); trade.SellOrderID != expected {
+			t.Errorf("Expected trade %d against %s, got %s", i, expected, trade.SellOrderID)
+		}
+	}
+}
+
+// TestTimePriorityIgnoresOrderTime tests that equal-price makers fill in arrival order whatever their Time fields say
+func TestTimePriorityIgnoresOrderTime(t *testing.T) {
+	for _, side := range []Side{Buy, Sell} {
+		taker := Sell
+		if side == Sell {
+			taker = Buy
+		}
+		ob := NewOrderBook("BTC-USDT")
+		now := time.Now().Unix()
+		ob.Execute(Order{ID: "first", Side: side, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1), Time: now})
+		ob.Execute(Order{ID: "second", Side: side, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1), Time: now - 60})
+		ob.Execute(Order{ID: "third", Side: side, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+
+		result := ob.Execute(Order{ID: "taker", Side: taker, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(3)})
+		if len(result.Trades) != 3 {
+			t.Fatalf("%s: expected 3 trades, got %d", side, len(result.Trades))
+		}
+		for i, expected := range []string{"first", "second", "third"} {
+			maker := result.Trades[i].BuyOrderID
+			if side == Sell {
+				maker = result.Trades[i].SellOrderID
+			}
+			if maker != expected {
+				t.Errorf("%s: expected trade %d against %s, got %s", side, i, expected, maker)
+			}
+		}
+	}
+}
+
+// TestPriceBeatsTime tests that a later maker at a better price trades ahead of earlier makers
+func TestPriceBeatsTime(t *testing.T) {
+	ob := NewOrderBook("BTC-USDT")
+	ob.Execute(Order{ID: "early", Side: Buy, Price: decimal.NewFromInt(99), Qty: decimal.NewFromInt(1)})
+	ob.Execute(Order{ID: "late", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	ob.Execute(Order{ID: "ask-early", Side: Sell, Price: decimal.NewFromInt(102), Qty: decimal.NewFromInt(1)})
+	ob.Execute(Order{ID: "ask-late", Side: Sell, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(1)})
+
+	result := ob.Execute(Order{ID: "sell", Side: Sell, Price: decimal.NewFromInt(99), Qty: decimal.NewFromInt(1)})
+	if len(result.Trades) != 1 || result.Trades[0].BuyOrderID != "late" || !result.Trades[0].Price.Equal(decimal.NewFromInt(100)) {
+		t.Errorf("Expected the later, higher bid to trade first at 100, got %+v", result.Trades)
+	}
+	result = ob.Execute(Order{ID: "buy", Side: Buy, Price: decimal.NewFromInt(102), Qty: decimal.NewFromInt(1)})
+	if len(result.Trades) != 1 || result.Trades[0].SellOrderID != "ask-late" || !result.Trades[0].Price.Equal(decimal.NewFromInt(101)) {
+		t.Errorf("Expected the later, lower ask to trade first at 101, got %+v", result.Trades)
+	}
+}
+
+// TestPartialFillKeepsPriority tests that a partially filled maker stays at the front of its level
+func TestPartialFillKeepsPriority(t *testing.T) {
+	ob := NewOrderBook("BTC-USDT")
+	ob.Execute(Order{ID: "sell1", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(3)})
+	ob.Execute(Order{ID: "sell2", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(3)})
+
+	ob.Execute(Order{ID: "buy1", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	// An order arriving after the partial fill must still queue behind both
+	ob.Execute(Order{ID: "sell3", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(3)})
+
+	result := ob.Execute(Order{ID: "buy2", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(4)})
+	if len(result.Trades) != 2 {
+		t.Fatalf("Expected 2 trades, got %+v", result.Trades)
+	}
+	if trade := result.Trades[0]; trade.SellOrderID != "sell1" || !trade.Qty.Equal(decimal.NewFromInt(2)) {
+		t.Errorf("Expected the remaining 2 of sell1 to fill first, got %s for %s", trade.Qty, trade.SellOrderID)
+	}
+	if trade := result.Trades[1]; trade.SellOrderID != "sell2" || !trade.Qty.Equal(decimal.NewFromInt(2)) {
+		t.Errorf("Expected sell2 to fill next, got %s for %s", trade.Qty, trade.SellOrderID)
+	}
+
+	result = ob.Execute(Order{ID: "buy3", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(2)})
+	if len(result.Trades) != 2 || result.Trades[0].SellOrderID != "sell2" || result.Trades[1].SellOrderID != "sell3" {
+		t.Errorf("Expected sell2 to keep the front of the level ahead of sell3, got %+v", result.Trades)
+	}
+}
+
+// TestOpenOrderCountAndRestingVolume tests per-side counts and running volume totals
+func TestOpenOrderCountAndRestingVolume(t *testing.T) {
+	ob := NewOrderBook("BTC-USDT")
+
+	check := func(stage string, bids, asks int, bidVol, askVol float64) {
+		t.Helper()
+		gotBids, gotAsks := ob.OpenOrderCount()
+		if gotBids != bids || gotAsks != asks {
+			t.Errorf("%s: expected %d bids and %d asks, got %d and %d", stage, bids, asks, gotBids, gotAsks)
+		}
+		gotBidVol, gotAskVol := ob.RestingVolume()
+		if !gotBidVol.Equal(decimal.NewFromFloat(bidVol)) || !gotAskVol.Equal(decimal.NewFromFloat(askVol)) {
+			t.Errorf("%s: expected volumes %v/%v, got %s/%s", stage, bidVol, askVol, gotBidVol, gotAskVol)
+		}
+	}
+
+	check("empty", 0, 0, 0, 0)
+
+	ob.Execute(Order{ID: "buy1", Side: Buy, Price: decimal.NewFromInt(99), Qty: decimal.NewFromFloat(1.5)})
+	ob.Execute(Order{ID: "buy2", Side: Buy, Price: decimal.NewFromInt(98), Qty: decimal.NewFromFloat(2.0)})
+	ob.Execute(Order{ID: "sell1", Side: Sell, Price: decimal.NewFromInt(101), Qty: decimal.NewFromFloat(3.0)})
+	check("resting", 2, 1, 3.5, 3.0)
+
+	// Partial fill of the best bid
+	ob.Execute(Order{ID: "sell2", Side: Sell, Price: decimal.NewFromInt(99), Qty: decimal.NewFromFloat(1.0)})
+	check("partial fill", 2, 1, 2.5, 3.0)
+
+	// Sweep the rest of the bids and rest the remainder
+	ob.Execute(Order{ID: "sell3", Side: Sell, Price: decimal.NewFromInt(98), Qty: decimal.NewFromFloat(3.0)})
+	check("sweep", 0, 2, 0, 3.5)
+
+	ob.Cancel("sell1")
+	check("cancel", 0, 1, 0, 0.5)
+
+	ob.Execute(Order{ID: "buy3", Side: Buy, Price: decimal.NewFromInt(90), Qty: decimal.NewFromFloat(1.0)})
+	ob.CancelAll()
+	check("cancel all", 0, 0, 0, 0)
+}
+
+// TestClear tests that Clear empties both sides and the pending stops
+func TestClear(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	ob.recordEvents = true
+	ob.Execute(Order{ID: "buy1", Side: Buy, Price: decimal.NewFromInt(99), Qty: decimal.NewFromInt(1)})
+	ob.Execute(Order{ID: "sell1", Side: Sell, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(1)})
+	ob.Execute(Order{ID: "stop", Side: Sell, Qty: decimal.NewFromInt(1), Type: TrailingStop, TrailOffset: decimal.NewFromInt(5)})
+	ob.takeEvents(&MatchResult{})
+
+	ob.Clear()
+	if bids, asks := ob.OpenOrderCount(); bids != 0 || asks != 0 {
+		t.Errorf("Expected empty book, got %d bids and %d asks", bids, asks)
+	}
+	if ob.BestBid() != 0 || ob.BestAsk() != 0 {
+		t.Errorf("Expected no best prices, got %v/%v", ob.BestBid(), ob.BestAsk())
+	}
+	if len(ob.PendingStops()) != 0 {
+		t.Error("Expected pending stops to be dropped")
+	}
+	if bids, asks := ob.GetBidDepth(10), ob.GetAskDepth(10); len(bids) != 0 || len(asks) != 0 {
+		t.Errorf("Expected empty depth, got %v/%v", bids, asks)
+	}
+	var res MatchResult
+	ob.takeEvents(&res)
+	if len(res.Events) != 2 || res.Events[0].Type != Removed {
+		t.Errorf("Expected 2 Removed events, got %+v", res.Events)
+	}
+}
+
+// TestImbalance tests the order-flow imbalance signal
+func TestImbalance(t *testing.T) {
+	tests := []struct {
+		name     string
+		bids     []float64
+		asks     []float64
+		levels   int
+		expected decimal.Decimal
+	}{
+		{name: "empty", levels: 5, expected: decimal.Zero},
+		{name: "bid heavy", bids: []float64{3, 1}, asks: []float64{1}, levels: 5, expected: decimal.NewFromFloat(0.6)},
+		{name: "ask heavy", bids: []float64{1}, asks: []float64{2, 2}, levels: 5, expected: decimal.NewFromFloat(-0.6)},
+		{name: "balanced", bids: []float64{2}, asks: []float64{2}, levels: 5, expected: decimal.Zero},
+		{name: "only top level", bids: []float64{1, 10}, asks: []float64{1, 1}, levels: 1, expected: decimal.Zero},
+		{name: "bids only", bids: []float64{1}, levels: 5, expected: decimal.NewFromInt(1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ob := NewOrderBook("BTC-USDT")
+			for i, qty := range tt.bids {
+				ob.Execute(Order{ID: fmt.Sprintf("b%d", i), Side: Buy, Price: decimal.NewFromInt(int64(100 - i)), Qty: decimal.NewFromFloat(qty)})
+			}
+			for i, qty := range tt.asks {
+				ob.Execute(Order{ID: fmt.Sprintf("a%d", i), Side: Sell, Price: decimal.NewFromInt(int64(101 + i)), Qty: decimal.NewFromFloat(qty)})
+			}
+
+			if got := ob.Imbalance(tt.levels); !got.Equal(tt.expected) {
+				t.Errorf("Expected imbalance %s, got %s", tt.expected.String(), got.String())
+			}
+		})
+	}
+}
+
+// TestMicroprice tests that the microprice leans toward the side with less quantity
+func TestMicroprice(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	if mp := ob.Microprice(); !mp.IsZero() {
+		t.Errorf("Expected zero microprice for empty book, got %s", mp)
+	}
+
+	ob.Execute(Order{ID: "bid1", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	if mp := ob.Microprice(); !mp.IsZero() {
+		t.Errorf("Expected zero microprice with one side empty, got %s", mp)
+	}
+
+	ob.Execute(Order{ID: "ask1", Side: Sell, Price: decimal.NewFromInt(110), Qty: decimal.NewFromInt(1)})
+	if mp := ob.Microprice(); !mp.Equal(decimal.NewFromInt(105)) {
+		t.Errorf("Expected microprice equal to mid with balanced sizes, got %s", mp)
+	}
+
+	// Heavy bids push the fair value toward the ask
+	ob.Execute(Order{ID: "bid2", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(3)})
+	mp := ob.Microprice()
+	if !mp.Equal(decimal.NewFromFloat(108)) {
+		t.Errorf("Expected microprice 108, got %s", mp)
+	}
+	if !mp.GreaterThan(decimal.NewFromInt(105)) {
+		t.Errorf("Expected microprice above the mid, got %s", mp)
+	}
+}
+
+// TestMidPrice tests the exact decimal mid and its one-sided cases
+func TestMidPrice(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	if _, ok := ob.MidPrice(); ok {
+		t.Error("Expected no mid for an empty book")
+	}
+
+	ob.Execute(Order{ID: "bid1", Side: Buy, Price: decimal.RequireFromString("100.1"), Qty: decimal.NewFromInt(1)})
+	if _, ok := ob.MidPrice(); ok {
+		t.Error("Expected no mid with the ask side empty")
+	}
+
+	ob.Execute(Order{ID: "ask1", Side: Sell, Price: decimal.RequireFromString("100.4"), Qty: decimal.NewFromInt(1)})
+	mid, ok := ob.MidPrice()
+	if !ok || !mid.Equal(decimal.RequireFromString("100.25")) {
+		t.Errorf("Expected mid 100.25, got %s (ok=%v)", mid, ok)
+	}
+}
+
+// TestWouldBeTaker tests crossing, touching and passive prices on both sides without changing the book
+func TestWouldBeTaker(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	if ob.WouldBeTaker(Buy, decimal.NewFromInt(1000)) || ob.WouldBeTaker(Sell, decimal.NewFromInt(1)) {
+		t.Error("Expected no taker against an empty book")
+	}
+
+	ob.Execute(Order{ID: "bid1", Side: Buy, Price: decimal.RequireFromString("99.5"), Qty: decimal.NewFromInt(1)})
+	ob.Execute(Order{ID: "ask1", Side: Sell, Price: decimal.RequireFromString("100.5"), Qty: decimal.NewFromInt(1)})
+
+	tests := []struct {
+		side  Side
+		price string
+		taker bool
+	}{
+		{Buy, "101", true},
+		{Buy, "100.50", true},
+		{Buy, "100.49", false},
+		{Sell, "99", true},
+		{Sell, "99.5", true},
+		{Sell, "99.51", false},
+	}
+	for _, tt := range tests {
+		if got := ob.WouldBeTaker(tt.side, decimal.RequireFromString(tt.price)); got != tt.taker {
+			t.Errorf("Expected %s at %s taker=%v, got %v", tt.side, tt.price, tt.taker, got)
+		}
+	}
+
+	if bids, asks := ob.RestingVolume(); !bids.Equal(decimal.NewFromInt(1)) || !asks.Equal(decimal.NewFromInt(1)) {
+		t.Errorf("Expected the book untouched, got %s/%s", bids, asks)
+	}
+
+	// Asks matching would pass over do not make a buy at 100 a taker
+	ob.LoadResting([]Order{
+		{ID: "aon", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(5), AON: true},
+		{ID: "expired", Side: Sell, Price: decimal.NewFromInt(99), Qty: decimal.NewFromInt(1), TimeInForce: GTD, ExpireAt: time.Now().Add(-time.Hour).Unix()},
+	})
+	if ob.WouldBeTaker(Buy, decimal.NewFromInt(100)) {
+		t.Error("Expected all-or-none and expired asks not to count")
+	}
+	if !ob.WouldBeTaker(Buy, decimal.RequireFromString("100.5")) {
+		t.Error("Expected the eligible ask behind them to count")
+	}
+}
+
+// TestLiquidityWithin tests that only levels inside the band around the mid are counted
+func TestLiquidityWithin(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	if bidQty, askQty := ob.LiquidityWithin(decimal.NewFromInt(1)); !bidQty.IsZero() || !askQty.IsZero() {
+		t.Errorf("Expected zeros for an empty book, got %s/%s", bidQty, askQty)
+	}
+
+	// Mid is 100, so a 1% band spans 99 to 101
+	ob.Execute(Order{ID: "bid1", Side: Buy, Price: decimal.NewFromFloat(99.5), Qty: decimal.NewFromInt(1)})
+	if bidQty, askQty := ob.LiquidityWithin(decimal.NewFromInt(1)); !bidQty.IsZero() || !askQty.IsZero() {
+		t.Errorf("Expected zeros for a one-sided book, got %s/%s", bidQty, askQty)
+	}
+	ob.Execute(Order{ID: "bid2", Side: Buy, Price: decimal.NewFromInt(99), Qty: decimal.NewFromInt(2)})
+	ob.Execute(Order{ID: "bid3", Side: Buy, Price: decimal.NewFromFloat(98.9), Qty: decimal.NewFromInt(5)})
+	ob.Execute(Order{ID: "ask1", Side: Sell, Price: decimal.NewFromFloat(100.5), Qty: decimal.NewFromInt(3)})
+	ob.Execute(Order{ID: "ask2", Side: Sell, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(1)})
+	ob.Execute(Order{ID: "ask3", Side: Sell, Price: decimal.NewFromInt(102), Qty: decimal.NewFromInt(7)})
+
+	bidQty, askQty := ob.LiquidityWithin(decimal.NewFromInt(1))
+	if !bidQty.Equal(decimal.NewFromInt(3)) {
+		t.Errorf("Expected 3 bid quantity within 1%%, got %s", bidQty)
+	}
+	if !askQty.Equal(decimal.NewFromInt(4)) {
+		t.Errorf("Expected 4 ask quantity within 1%%, got %s", askQty)
+	}
+}
+
+// TestWeightedMid tests the mid of volume-weighted prices over several levels
+func TestWeightedMid(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	ob.Execute(Order{ID: "bid1", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	ob.Execute(Order{ID: "bid2", Side: Buy, Price: decimal.NewFromInt(96), Qty: decimal.NewFromInt(3)})
+	ob.Execute(Order{ID: "ask1", Side: Sell, Price: decimal.NewFromInt(110), Qty: decimal.NewFromInt(1)})
+	ob.Execute(Order{ID: "ask2", Side: Sell, Price: decimal.NewFromInt(120), Qty: decimal.NewFromInt(1)})
+
+	if mid := ob.WeightedMid(1); !mid.Equal(decimal.NewFromInt(105)) {
+		t.Errorf("Expected plain mid 105 for one level, got %s", mid)
+	}
+	// Bids average 97, asks average 115
+	if mid := ob.WeightedMid(2); !mid.Equal(decimal.NewFromInt(106)) {
+		t.Errorf("Expected weighted mid 106, got %s", mid)
+	}
+	if mid := ob.WeightedMid(0); !mid.IsZero() {
+		t.Errorf("Expected zero for no levels, got %s", mid)
+	}
+}
+
+// TestCumulativeFill tests that a resting order's cumulative quantity and average price accumulate across trades
+func TestCumulativeFill(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	ob.Execute(Order{ID: "ask1", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	ob.Execute(Order{ID: "ask2", Side: Sell, Price: decimal.NewFromInt(104), Qty: decimal.NewFromInt(1)})
+
+	// The bid sweeps both asks and rests with the remainder
+	res := ob.Execute(Order{ID: "bid", Side: Buy, Price: decimal.NewFromInt(104), Qty: decimal.NewFromInt(4)})
+	fill := res.Fills[len(res.Fills)-1]
+	if fill.OrderID != "bid" || fill.Status != PartiallyFilled {
+		t.Fatalf("Expected bid to be partially filled, got %+v", fill)
+	}
+	if !fill.CumulativeQty.Equal(decimal.NewFromInt(2)) || !fill.CumulativeAvgPrice.Equal(decimal.NewFromInt(102)) {
+		t.Errorf("Expected cumulative 2 @ 102, got %s @ %s", fill.CumulativeQty, fill.CumulativeAvgPrice)
+	}
+
+	res = ob.Execute(Order{ID: "seller", Side: Sell, Price: decimal.NewFromInt(104), Qty: decimal.NewFromInt(2)})
+	fill = res.Fills[1]
+	if fill.OrderID != "bid" || fill.Status != Filled {
+		t.Fatalf("Expected resting bid to be filled, got %+v", fill)
+	}
+	if !fill.CumulativeQty.Equal(decimal.NewFromInt(4)) || !fill.CumulativeAvgPrice.Equal(decimal.NewFromInt(103)) {
+		t.Errorf("Expected cumulative 4 @ 103, got %s @ %s", fill.CumulativeQty, fill.CumulativeAvgPrice)
+	}
+	if !fill.ExecutedQty.Equal(decimal.NewFromInt(2)) {
+		t.Errorf("Expected this fill to execute 2, got %s", fill.ExecutedQty)
+	}
+}
+
+// TestWalkLevels tests visiting levels in priority order until the callback stops
+func TestWalkLevels(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	for i, price := range []int64{103, 101, 102, 101, 104} {
+		ob.Execute(Order{ID: fmt.Sprintf("ask%d", i), Side: Sell, Price: decimal.NewFromInt(price), Qty: decimal.NewFromInt(2)})
+	}
+	ob.Execute(Order{ID: "bid1", Side: Buy, Price: decimal.NewFromInt(99), Qty: decimal.NewFromInt(1)})
+	ob.Execute(Order{ID: "bid2", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+
+	// Levels 101 (4), 102 (2) reach the threshold of 5; 103 and 104 are never visited
+	var visited []string
+	total := decimal.Zero
+	ob.WalkLevels(Sell, func(level DepthLevel) bool {
+		visited = append(visited, level.Price.String())
+		total = total.Add(level.Quantity)
+		return total.LessThan(decimal.NewFromInt(5))
+	})
+	if len(visited) != 2 || visited[0] != "101" || visited[1] != "102" {
+		t.Errorf("Expected to visit asks 101 and 102, got %v", visited)
+	}
+	if !total.Equal(decimal.NewFromInt(6)) {
+		t.Errorf("Expected total 6, got %s", total)
+	}
+
+	visited = nil
+	ob.WalkLevels(Buy, func(level DepthLevel) bool {
+		visited = append(visited, level.Price.String())
+		return true
+	})
+	if len(visited) != 2 || visited[0] != "100" || visited[1] != "99" {
+		t.Errorf("Expected bids from highest to lowest, got %v", visited)
+	}
+}
+
+// TestForEachOrder tests that resting orders are visited in priority order on each side
+func TestForEachOrder(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	for i, price := range []int64{103, 101, 102, 101} {
+		ob.Execute(Order{ID: fmt.Sprintf("ask%d", i), Side: Sell, Price: decimal.NewFromInt(price), Qty: decimal.NewFromInt(1)})
+	}
+	ob.Execute(Order{ID: "bid1", Side: Buy, Price: decimal.NewFromInt(99), Qty: decimal.NewFromInt(1)})
+	ob.Execute(Order{ID: "bid2", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+
+	var asks []string
+	ob.ForEachOrder(Sell, func(order Order) bool {
+		asks = append(asks, order.ID)
+		order.Qty = decimal.Zero
+		return true
+	})
+	if fmt.Sprint(asks) != "[ask1 ask3 ask2 ask0]" {
+		t.Errorf("Expected asks in price-time order, got %v", asks)
+	}
+	var bids []string
+	ob.ForEachOrder(Buy, func(order Order) bool {
+		bids = append(bids, order.ID)
+		return true
+	})
+	if fmt.Sprint(bids) != "[bid2 bid1]" {
+		t.Errorf("Expected bids from highest to lowest, got %v", bids)
+	}
+	if _, askVol := ob.RestingVolume(); !askVol.Equal(decimal.NewFromInt(4)) {
+		t.Errorf("Expected the callback's copies not to touch the book, got ask volume %s", askVol)
+	}
+	if err := ob.Validate(); err != nil {
+		t.Errorf("Expected a valid book, got %v", err)
+	}
+}
+
+// TestForEachOrderStopsEarly tests that iteration ends as soon as the callback returns false
+func TestForEachOrderStopsEarly(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	for i := 0; i < 5; i++ {
+		ob.Execute(Order{ID: fmt.Sprintf("bid%d", i), Side: Buy, Price: decimal.NewFromInt(int64(100 - i)), Qty: decimal.NewFromInt(1)})
+	}
+
+	var visited []string
+	ob.ForEachOrder(Buy, func(order Order) bool {
+		visited = append(visited, order.ID)
+		return len(visited) < 2
+	})
+	if fmt.Sprint(visited) != "[bid0 bid1]" {
+		t.Errorf("Expected to stop after two orders, got %v", visited)
+	}
+	ob.ForEachOrder(Sell, func(order Order) bool {
+		t.Errorf("Expected no asks, got %+v", order)
+		return true
+	})
+}
+
+// TestDepthCumulativeColumns tests that each level's cumulative columns sum it and all better levels
+func TestDepthCumulativeColumns(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	ob.Execute(Order{ID: "bid1", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	ob.Execute(Order{ID: "bid2", Side: Buy, Price: decimal.NewFromInt(99), Qty: decimal.NewFromInt(2)})
+	ob.Execute(Order{ID: "bid3", Side: Buy, Price: decimal.NewFromInt(99), Qty: decimal.NewFromInt(1)})
+	ob.Execute(Order{ID: "bid4", Side: Buy, Price: decimal.NewFromInt(98), Qty: decimal.NewFromInt(4)})
+	ob.Execute(Order{ID: "ask1", Side: Sell, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(2)})
+	ob.Execute(Order{ID: "ask2", Side: Sell, Price: decimal.NewFromInt(103), Qty: decimal.NewFromInt(1)})
+
+	for _, levels := range [][]DepthLevel{ob.GetBidDepth(10), ob.GetAskDepth(10)} {
+		qty, notional := decimal.Zero, decimal.Zero
+		for _, level := range levels {
+			qty = qty.Add(level.Quantity)
+			notional = notional.Add(level.Price.Mul(level.Quantity))
+			if !level.CumQuantity.Equal(qty) {
+				t.Errorf("Expected cumulative quantity %s at %s, got %s", qty, level.Price, level.CumQuantity)
+			}
+			if !level.CumNotional.Equal(notional) {
+				t.Errorf("Expected cumulative notional %s at %s, got %s", notional, level.Price, level.CumNotional)
+			}
+		}
+	}
+
+	bids := ob.GetBidDepth(10)
+	if last := bids[len(bids)-1]; !last.CumQuantity.Equal(decimal.NewFromInt(8)) || !last.CumNotional.Equal(decimal.NewFromInt(789)) {
+		t.Errorf("Expected bid totals 8 and 789, got %s and %s", last.CumQuantity, last.CumNotional)
+	}
+}
+
+// TestDepthGrouped tests that depth levels collapse into price bands of the grouping width
+func TestDepthGrouped(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	for i, price := range []string{"100.1", "100.4", "100.6"} {
+		ob.Execute(Order{ID: fmt.Sprintf("bid%d", i), Side: Buy, Price: decimal.RequireFromString(price), Qty: decimal.NewFromInt(int64(i + 1))})
+		ob.Execute(Order{ID: fmt.Sprintf("ask%d", i), Side: Sell, Price: decimal.RequireFromString(price).Add(decimal.NewFromInt(1)), Qty: decimal.NewFromInt(int64(i + 1))})
+	}
+	grouping := decimal.RequireFromString("0.5")
+
+	bids := ob.GetBidDepthGrouped(10, grouping)
+	if len(bids) != 2 {
+		t.Fatalf("Expected 2 bid bands, got %+v", bids)
+	}
+	if !bids[0].Price.Equal(decimal.RequireFromString("100.5")) || !bids[0].Quantity.Equal(decimal.NewFromInt(3)) || bids[0].TradeCount != 1 {
+		t.Errorf("Expected 100.6 alone in the 100.5 band, got %+v", bids[0])
+	}
+	if !bids[1].Price.Equal(decimal.NewFromInt(100)) || !bids[1].Quantity.Equal(decimal.NewFromInt(3)) || bids[1].TradeCount != 2 {
+		t.Errorf("Expected 100.1 and 100.4 to collapse into the 100 band, got %+v", bids[1])
+	}
+	// 100.6 * 3 + 100.4 * 2 + 100.1 * 1 at the orders' own prices
+	if !bids[1].CumQuantity.Equal(decimal.NewFromInt(6)) || !bids[1].CumNotional.Equal(decimal.RequireFromString("602.7")) {
+		t.Errorf("Expected cumulative 6 worth 602.7, got %s worth %s", bids[1].CumQuantity, bids[1].CumNotional)
+	}
+
+	asks := ob.GetAskDepthGrouped(10, grouping)
+	if len(asks) != 2 || !asks[0].Price.Equal(decimal.RequireFromString("101.5")) || !asks[0].Quantity.Equal(decimal.NewFromInt(3)) ||
+		!asks[1].Price.Equal(decimal.NewFromInt(102)) || !asks[1].Quantity.Equal(decimal.NewFromInt(3)) {
+		t.Errorf("Expected ask bands 3@101.5 and 3@102, got %+v", asks)
+	}
+
+	if bands := ob.GetBidDepthGrouped(1, grouping); len(bands) != 1 || !bands[0].Quantity.Equal(decimal.NewFromInt(3)) {
+		t.Errorf("Expected depth to limit the number of bands, got %+v", bands)
+	}
+	if levels := ob.GetBidDepthGrouped(10, decimal.Zero); len(levels) != 3 {
+		t.Errorf("Expected no grouping to return every level, got %+v", levels)
+	}
+}
+
+// TestDepthNotional tests that notional depth levels carry price times the aggregated quantity
+func TestDepthNotional(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	ob.Execute(Order{ID: "bid1", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	ob.Execute(Order{ID: "bid2", Side: Buy, Price: decimal.NewFromInt(99), Qty: decimal.NewFromInt(2)})
+	ob.Execute(Order{ID: "bid3", Side: Buy, Price: decimal.NewFromInt(99), Qty: decimal.NewFromFloat(0.5)})
+	ob.Execute(Order{ID: "ask1", Side: Sell, Price: decimal.NewFromFloat(101.5), Qty: decimal.NewFromInt(2)})
+	ob.Execute(Order{ID: "ask2", Side: Sell, Price: decimal.NewFromInt(103), Qty: decimal.NewFromInt(1)})
+
+	sides := []struct {
+		plain, notional []DepthLevel
+	}{
+		{ob.GetBidDepth(10), ob.GetBidDepthNotional(10)},
+		{ob.GetAskDepth(10), ob.GetAskDepthNotional(10)},
+	}
+	for _, side := range sides {
+		if len(side.plain) != len(side.notional) {
+			t.Fatalf("Expected %d notional levels, got %d", len(side.plain), len(side.notional))
+		}
+		for i, level := range side.notional {
+			expected := side.plain[i].Price.Mul(side.plain[i].Quantity)
+			if !level.Quantity.Equal(expected) {
+				t.Errorf("Expected notional %s at %s, got %s", expected, level.Price, level.Quantity)
+			}
+			if !level.CumQuantity.Equal(side.plain[i].CumNotional) {
+				t.Errorf("Expected cumulative notional %s at %s, got %s", side.plain[i].CumNotional, level.Price, level.CumQuantity)
+			}
+		}
+	}
+
+	if bids := sides[0].notional; !bids[1].Quantity.Equal(decimal.NewFromFloat(247.5)) || !bids[1].CumQuantity.Equal(decimal.NewFromFloat(347.5)) {
+		t.Errorf("Expected the 99 level to hold 247.5 of 347.5 total, got %s of %s", bids[1].Quantity, bids[1].CumQuantity)
+	}
+}
+
+// TestMatchRejectsInvalidOrders tests that Match rejects non-positive quantities and negative prices without touching the heaps
+func TestMatchRejectsInvalidOrders(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	ob.Execute(Order{ID: "ask", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+
+	tests := []struct {
+		name   string
+		order  Order
+		reason RejectReason
+	}{
+		{"zero qty", Order{ID: "zero", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.Zero}, RejectInvalidQty},
+		{"negative qty", Order{ID: "neg", Side: Sell, Price: decimal.NewFromInt(90), Qty: decimal.NewFromInt(-1)}, RejectInvalidQty},
+		{"negative price", Order{ID: "negpx", Side: Buy, Price: decimal.NewFromInt(-5), Qty: decimal.NewFromInt(1)}, RejectInvalidPrice},
+	}
+	for _, tt := range tests {
+		tradeCh := make(chan Trade, 10)
+		fillCh := make(chan OrderFill, 10)
+		ob.Match(tt.order, tradeCh, fillCh, tt.order.Qty)
+
+		if len(tradeCh) != 0 {
+			t.Errorf("%s: Expected no trades, got %d", tt.name, len(tradeCh))
+		}
+		if len(fillCh) != 1 {
+			t.Fatalf("%s: Expected 1 fill, got %d", tt.name, len(fillCh))
+		}
+		if fill := <-fillCh; fill.Status != Rejected || fill.Reason != tt.reason {
+			t.Errorf("%s: Expected rejection with %s, got %+v", tt.name, tt.reason, fill)
+		}
+		if bids, asks := ob.OpenOrderCount(); bids != 0 || asks != 1 {
+			t.Errorf("%s: Expected heaps untouched, got %d bids and %d asks", tt.name, bids, asks)
+		}
+		if bidVol, askVol := ob.RestingVolume(); !bidVol.IsZero() || !askVol.Equal(decimal.NewFromInt(1)) {
+			t.Errorf("%s: Expected volumes untouched, got %s/%s", tt.name, bidVol, askVol)
+		}
+	}
+	if err := ob.Validate(); err != nil {
+		t.Errorf("Expected a valid book, got %v", err)
+	}
+}
+
+// TestMatchSlowConsumers tests that Match does not hold the book lock while a consumer is slow and keeps events in matching order
+func TestMatchSlowConsumers(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	tradeCh := make(chan Trade)
+	fillCh := make(chan OrderFill, 10000)
+
+	for i := 0; i < 200; i++ {
+		ob.Execute(Order{ID: fmt.Sprintf("ask%d", i), Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 25; i++ {
+				order := Order{ID: fmt.Sprintf("bid%d-%d", g, i), Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)}
+				ob.Match(order, tradeCh, fillCh, order.Qty)
+			}
+		}(g)
+	}
+
+	// No one is reading trades yet, so at least one Match is blocked sending;
+	// the book must stay available regardless.
+	done := make(chan struct{})
+	go func() {
+		ob.TopOfBook()
+		ob.GetAskDepth(5)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the book to stay available while a consumer is stalled")
+	}
+
+	for i := 1; i <= 200; i++ {
+		trade := <-tradeCh
+		if expected := fmt.Sprintf("BTC-USD-%d", i); trade.ID != expected {
+			t.Fatalf("Expected trades in matching order, got %s at position %d", trade.ID, i)
+		}
+		if i%20 == 0 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	wg.Wait()
+
+	if n := len(fillCh); n != 600 {
+		t.Errorf("Expected 600 fills, got %d", n)
+	}
+}
+
+// TestLoadResting tests that a batch load rests orders in price-time priority without matching
+func TestLoadResting(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	ob.LoadResting([]Order{
+		{ID: "bid1", Side: Buy, Price: decimal.NewFromInt(98), Qty: decimal.NewFromInt(1)},
+		{ID: "ask1", Side: Sell, Price: decimal.NewFromInt(102), Qty: decimal.NewFromInt(2)},
+		{ID: "bid2", Side: Buy, Price: decimal.NewFromInt(99), Qty: decimal.NewFromInt(3)},
+		{ID: "bid3", Side: Buy, Price: decimal.NewFromInt(99), Qty: decimal.NewFromInt(1)},
+	})
+
+	if err := ob.Validate(); err != nil {
+		t.Fatalf("Expected a valid book, got %v", err)
+	}
+	bid, ask, bidQty, askQty := ob.TopOfBook()
+	if !bid.Equal(decimal.NewFromInt(99)) || !bidQty.Equal(decimal.NewFromInt(4)) || !ask.Equal(decimal.NewFromInt(102)) || !askQty.Equal(decimal.NewFromInt(2)) {
+		t.Errorf("Expected 4 @ 99 / 2 @ 102, got %s @ %s / %s @ %s", bidQty, bid, askQty, ask)
+	}
+
+	res := ob.Execute(Order{ID: "seller", Side: Sell, Price: decimal.NewFromInt(99), Qty: decimal.NewFromInt(1)})
+	if len(res.Trades) != 1 || res.Trades[0].BuyOrderID != "bid2" {
+		t.Errorf("Expected the earlier loaded bid to keep time priority, got %+v", res.Trades)
+	}
+}
+
+// TestAvgFillPrice tests the blended price reported to an aggressor that sweeps several levels
+func TestAvgFillPrice(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	ob.Execute(Order{ID: "ask1", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	ob.Execute(Order{ID: "ask2", Side: Sell, Price: decimal.NewFromInt(106), Qty: decimal.NewFromInt(2)})
+
+	res := ob.Execute(Order{ID: "taker", Side: Buy, Price: decimal.NewFromInt(110), Qty: decimal.NewFromInt(3)})
+	fill, _ := lastFill(res.Fills, "taker")
+	if fill.Status != Filled {
+		t.Fatalf("Expected taker to be filled, got %s", fill.Status)
+	}
+	// (100*1 + 106*2) / 3
+	if !fill.AvgFillPrice.Equal(decimal.NewFromInt(104)) {
+		t.Errorf("Expected blended price 104, got %s", fill.AvgFillPrice)
+	}
+	if maker, _ := lastFill(res.Fills, "ask2"); !maker.AvgFillPrice.IsZero() {
+		t.Errorf("Expected no blended price on a maker fill, got %s", maker.AvgFillPrice)
+	}
+
+	res = ob.Execute(Order{ID: "unfilled", Side: Buy, Price: decimal.NewFromInt(90), Qty: decimal.NewFromInt(1)})
+	if fill, _ := lastFill(res.Fills, "unfilled"); !fill.AvgFillPrice.IsZero() {
+		t.Errorf("Expected zero blended price for an unfilled order, got %s", fill.AvgFillPrice)
+	}
+}
+
+// TestTakerSide tests that trades record the side of the aggressing order
+func TestTakerSide(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	ob.Execute(Order{ID: "sell", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(2)})
+
+	res := ob.Execute(Order{ID: "buy", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	if len(res.Trades) != 1 || res.Trades[0].TakerSide != Buy {
+		t.Errorf("Expected a buy-initiated trade, got %+v", res.Trades)
+	}
+
+	ob.Execute(Order{ID: "bid", Side: Buy, Price: decimal.NewFromInt(99), Qty: decimal.NewFromInt(1)})
+	res = ob.Execute(Order{ID: "seller", Side: Sell, Price: decimal.NewFromInt(99), Qty: decimal.NewFromInt(1)})
+	if len(res.Trades) != 1 || res.Trades[0].TakerSide != Sell {
+		t.Errorf("Expected a sell-initiated trade, got %+v", res.Trades)
+	}
+}
+
+// TestTradeMakerRemaining tests that trades carry what is left of the resting order, zero once it is consumed
+func TestTradeMakerRemaining(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	ob.Execute(Order{ID: "big", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(10)})
+
+	res := ob.Execute(Order{ID: "buy1", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(3)})
+	if len(res.Trades) != 1 || !res.Trades[0].MakerRemaining.Equal(decimal.NewFromInt(7)) {
+		t.Fatalf("Expected 7 of the maker left after the partial fill, got %+v", res.Trades)
+	}
+
+	res = ob.Execute(Order{ID: "buy2", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(8)})
+	if len(res.Trades) != 1 || !res.Trades[0].MakerRemaining.IsZero() {
+		t.Errorf("Expected nothing of the maker left once consumed, got %+v", res.Trades)
+	}
+}
+
+// TestSweepExecutionPrices tests that a taker sweeping three levels trades each slice at its level's price
+func TestSweepExecutionPrices(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	levels := []int64{100, 101, 102}
+	for _, price := range levels {
+		ob.Execute(Order{ID: fmt.Sprintf("ask%d", price), Side: Sell, Price: decimal.NewFromInt(price), Qty: decimal.NewFromInt(1)})
+	}
+
+	res := ob.Execute(Order{ID: "buy", Side: Buy, Price: decimal.NewFromInt(102), Qty: decimal.NewFromInt(3)})
+	if len(res.Trades) != len(levels) {
+		t.Fatalf("Expected %d trades, got %+v", len(levels), res.Trades)
+	}
+	for i, price := range levels {
+		if trade := res.Trades[i]; !trade.Price.Equal(decimal.NewFromInt(price)) || trade.SellOrderID != fmt.Sprintf("ask%d", price) {
+			t.Errorf("Expected trade %d against ask%d at %d, got %+v", i, price, price, trade)
+		}
+	}
+
+	var taker []OrderFill
+	for _, fill := range res.Fills {
+		switch {
+		case fill.Status == New:
+		case fill.OrderID == "buy":
+			taker = append(taker, fill)
+		case !fill.FillPrice.Equal(fill.Price):
+			t.Errorf("Expected maker %s to fill at its level %s, got %s", fill.OrderID, fill.Price, fill.FillPrice)
+		}
+	}
+	if len(taker) != len(levels) {
+		t.Fatalf("Expected %d taker fills, got %+v", len(levels), taker)
+	}
+	for i, price := range levels {
+		if !taker[i].FillPrice.Equal(decimal.NewFromInt(price)) {
+			t.Errorf("Expected taker slice %d to fill at %d, got %s", i, price, taker[i].FillPrice)
+		}
+	}
+	last := taker[len(taker)-1]
+	if !last.AvgFillPrice.Equal(decimal.NewFromInt(101)) || !last.CumulativeAvgPrice.Equal(decimal.NewFromInt(101)) {
+		t.Errorf("Expected the taker to average 101 over the three levels, got %s and %s", last.AvgFillPrice, last.CumulativeAvgPrice)
+	}
+	if !last.CumulativeQty.Equal(decimal.NewFromInt(3)) || last.Status != Filled {
+		t.Errorf("Expected the taker filled for 3, got %+v", last)
+	}
+}
@@ -0,0 +1,135 @@
+package engine
+
+import (
+	"container/heap"
+
+	"github.com/shopspring/decimal"
+)
+
+// pegReferences holds the reference prices available to pegged orders. They
+// are computed from non-pegged orders only, so a peg never tracks itself.
+type pegReferences struct {
+	bid, ask       decimal.Decimal
+	hasBid, hasAsk bool
+}
+
+// price returns the reference price order tracks and whether it is available.
+func (r pegReferences) price(order *Order) (decimal.Decimal, bool) {
+	ref := order.PegReference
+	if ref == "" {
+		ref = PegBestAsk
+		if order.Side == Buy {
+			ref = PegBestBid
+		}
+	}
+
+	switch ref {
+	case PegBestBid:
+		return r.bid, r.hasBid
+	case PegBestAsk:
+		return r.ask, r.hasAsk
+	case PegMid:
+		if r.hasBid && r.hasAsk {
+			return r.bid.Add(r.ask).Div(decimal.NewFromInt(2)), true
+		}
+	}
+	return decimal.Zero, false
+}
+
+// equal reports whether r and other hold the same reference prices.
+func (r pegReferences) equal(other pegReferences) bool {
+	return r.hasBid == other.hasBid && r.hasAsk == other.hasAsk &&
+		r.bid.Equal(other.bid) && r.ask.Equal(other.ask)
+}
+
+// crosses reports whether a resting order on side at price would trade
+// against the opposite non-pegged best price.
+func (r pegReferences) crosses(side Side, price decimal.Decimal) bool {
+	if side == Buy {
+		return r.hasAsk && price.GreaterThanOrEqual(r.ask)
+	}
+	return r.hasBid && price.LessThanOrEqual(r.bid)
+}
+
+// pegReferences scans both sides for the best non-pegged prices and reports
+// whether any pegged order is resting. The caller must hold ob.mutex.
+func (ob *OrderBook) pegReferences() (refs pegReferences, pegged bool) {
+	for _, o := range ob.bids.orderHeap {
+		if o.Type == Pegged {
+			pegged = true
+		} else if !refs.hasBid || o.Price.GreaterThan(refs.bid) {
+			refs.bid, refs.hasBid = o.Price, true
+		}
+	}
+	for _, o := range ob.asks.orderHeap {
+		if o.Type == Pegged {
+			pegged = true
+		} else if !refs.hasAsk || o.Price.LessThan(refs.ask) {
+			refs.ask, refs.hasAsk = o.Price, true
+		}
+	}
+	return refs, pegged
+}
+
+// pegPrice sets the price of an incoming pegged order from the current
+// references. If the reference is unavailable the order keeps its own price.
+// The caller must hold ob.mutex.
+func (ob *OrderBook) pegPrice(order *Order) {
+	refs, _ := ob.pegReferences()
+	if ref, ok := refs.price(order); ok {
+		order.Price = ref.Add(order.PegOffset)
+	}
+}
+
+// repricePegs moves every resting pegged order to its reference price plus
+// offset. The references are gathered in a single pass over the book and each
+// side's heap is rebuilt at most once, so a book move costs O(n) however many
+// pegs are resting. A peg whose new price would cross the opposite side, or is
+// not positive, keeps its previous price. Pegs keep their time priority. The
+// caller must hold ob.mutex.
+func (ob *OrderBook) repricePegs() {
+	refs, pegged := ob.pegReferences()
+	if !pegged {
+		ob.hasPegs = false
+		ob.pegRefs = pegReferences{}
+		return
+	}
+	if refs.equal(ob.pegRefs) {
+		return
+	}
+	ob.pegRefs = refs
+
+	if ob.repriceHeap(ob.bids.orderHeap, refs) {
+		heap.Init(ob.bids)
+	}
+	if ob.repriceHeap(ob.asks.orderHeap, refs) {
+		heap.Init(ob.asks)
+	}
+}
+
+// repriceHeap updates the prices of the pegged orders in h in place and
+// reports whether any changed, in which case the heap must be re-initialized.
+// Each re-priced order is reported as removed at its old price and added at
+// the new one. The caller must hold ob.mutex.
+func (ob *OrderBook) repriceHeap(h orderHeap, refs pegReferences) bool {
+	changed := false
+	for _, o := range h {
+		if o.Type != Pegged {
+			continue
+		}
+		ref, ok := refs.price(o)
+		if !ok {
+			continue
+		}
+		price := ref.Add(o.PegOffset)
+		if !price.IsPositive() || refs.crosses(o.Side, price) || price.Equal(o.Price) {
+			continue
+		}
+		ob.emit(Removed, *o)
+		o.Price = price
+		ob.setTicks(o)
+		ob.emit(Added, *o)
+		changed = true
+	}
+	return changed
+}
@@ -0,0 +1,75 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// TestPeggedOrderTracksBestBid tests that a pegged buy follows the best bid up and down
+func TestPeggedOrderTracksBestBid(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	ob.Execute(Order{ID: "bid1", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	ob.Execute(Order{ID: "ask1", Side: Sell, Price: decimal.NewFromInt(110), Qty: decimal.NewFromInt(1)})
+
+	ob.Execute(Order{ID: "peg", Side: Buy, Qty: decimal.NewFromInt(1), Type: Pegged, PegOffset: decimal.NewFromInt(1)})
+	if price := pegPriceOf(t, ob, "peg"); !price.Equal(decimal.NewFromInt(101)) {
+		t.Errorf("Expected peg to price one above the best bid at 101, got %s", price)
+	}
+	if ob.BestBid() != 101 {
+		t.Errorf("Expected peg to lead the book at 101, got %f", ob.BestBid())
+	}
+
+	ob.Execute(Order{ID: "bid2", Side: Buy, Price: decimal.NewFromInt(105), Qty: decimal.NewFromInt(1)})
+	if price := pegPriceOf(t, ob, "peg"); !price.Equal(decimal.NewFromInt(106)) {
+		t.Errorf("Expected peg to follow the best bid up to 106, got %s", price)
+	}
+
+	ob.Cancel("bid2")
+	if price := pegPriceOf(t, ob, "peg"); !price.Equal(decimal.NewFromInt(101)) {
+		t.Errorf("Expected peg to follow the best bid back down to 101, got %s", price)
+	}
+
+	// A new best bid that would push the peg through the ask leaves it in place
+	ob.Execute(Order{ID: "bid3", Side: Buy, Price: decimal.NewFromInt(109), Qty: decimal.NewFromInt(1)})
+	if price := pegPriceOf(t, ob, "peg"); !price.Equal(decimal.NewFromInt(101)) {
+		t.Errorf("Expected peg not to cross the ask, got %s", price)
+	}
+	checkTopCache(t, "bid", ob.bestBid.Load(), ob.bids.orderHeap)
+	checkTopCache(t, "ask", ob.bestAsk.Load(), ob.asks.orderHeap)
+}
+
+// TestPeggedOrderMid tests pegging to the midpoint and matching a repriced peg
+func TestPeggedOrderMid(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	ob.Execute(Order{ID: "bid1", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	ob.Execute(Order{ID: "ask1", Side: Sell, Price: decimal.NewFromInt(110), Qty: decimal.NewFromInt(1)})
+	ob.Execute(Order{ID: "peg", Side: Sell, Qty: decimal.NewFromInt(1), Type: Pegged, PegReference: PegMid})
+
+	if price := pegPriceOf(t, ob, "peg"); !price.Equal(decimal.NewFromInt(105)) {
+		t.Errorf("Expected peg at the mid of 105, got %s", price)
+	}
+
+	ob.Execute(Order{ID: "ask2", Side: Sell, Price: decimal.NewFromInt(104), Qty: decimal.NewFromInt(1)})
+	if price := pegPriceOf(t, ob, "peg"); !price.Equal(decimal.NewFromInt(102)) {
+		t.Errorf("Expected peg to move to the new mid of 102, got %s", price)
+	}
+
+	res := ob.Execute(Order{ID: "buyer", Side: Buy, Price: decimal.NewFromInt(103), Qty: decimal.NewFromInt(1)})
+	if len(res.Trades) != 1 || res.Trades[0].SellOrderID != "peg" || !res.Trades[0].Price.Equal(decimal.NewFromInt(102)) {
+		t.Errorf("Expected buyer to lift the peg at 102, got %+v", res.Trades)
+	}
+}
+
+// pegPriceOf returns the current price of a resting order
+func pegPriceOf(t *testing.T, ob *OrderBook, id string) decimal.Decimal {
+	t.Helper()
+	snapshot := ob.Snapshot()
+	for _, o := range append(snapshot.Bids, snapshot.Asks...) {
+		if o.ID == id {
+			return o.Price
+		}
+	}
+	t.Fatalf("Order %s is not resting", id)
+	return decimal.Zero
+}
@@ -0,0 +1,108 @@
+package engine
+
+import "github.com/shopspring/decimal"
+
+// MatchPolicy decides how an incoming order trades against the resting orders
+// it crosses. The book walks the crossing orders in price-time priority and
+// consults the policy for each one, so policies can change execution prices,
+// share a price level among its makers or pass makers over without changing
+// the matching loop itself.
+//
+// Implementations must not modify the orders they are given, and a policy
+// shared between books must be safe for concurrent use.
+type MatchPolicy interface {
+	// Eligible reports whether maker may trade with taker. Makers that are not
+	// eligible are passed over and keep their place in the book.
+	Eligible(taker, maker *Order) bool
+
+	// Allocate returns the quantity taker executes against maker, a maker
+	// resting at level. Results are capped at both orders' remaining
+	// quantities; a maker allocated nothing is passed over, and a maker left
+	// with quantity while taker still has some is passed over for the rest of
+	// the match. An all-or-none maker trades only if it is allocated its whole
+	// quantity.
+	Allocate(taker, maker *Order, level *MatchLevel) decimal.Decimal
+
+	// ExecutionPrice returns the price taker trades against maker at, given
+	// the trading rules of the pair.
+	ExecutionPrice(taker, maker *Order, config PairConfig) decimal.Decimal
+}
+
+// MatchLevel describes the price level an incoming order is currently
+// matching against.
+type MatchLevel struct {
+	Price    decimal.Decimal // Price of the makers at the level
+	TakerQty decimal.Decimal // Quantity the incoming order had left on reaching the level
+
+	orders orderHeap                       // Side of the book the level belongs to
+	worse  func(a, b decimal.Decimal) bool // Reports whether price a is worse than b on that side
+	qty    *decimal.Decimal                // Cached result of Quantity
+}
+
+// Quantity returns the total quantity resting at the level, including makers
+// that are not eligible. It is computed on first use, so a policy that needs
+// the quantity the level had when the incoming order reached it should call
+// Quantity for the level's first maker.
+func (l *MatchLevel) Quantity() decimal.Decimal {
+	if l.qty == nil {
+		qty := levelQty(l.orders, l.Price, l.worse)
+		l.qty = &qty
+	}
+	return *l.qty
+}
+
+// PriceTimePolicy is the default MatchPolicy. Every maker is eligible and
+// fills as much of the incoming order as it can in time priority, and trades
+// execute at the maker's price, at the midpoint of both limit prices for pairs
+// configured with ExecuteAtMid, or at the taker's limit price for pairs
+// configured with ExecuteAtTaker.
+type PriceTimePolicy struct{}
+
+// Eligible always returns true.
+func (PriceTimePolicy) Eligible(taker, maker *Order) bool { return true }
+
+// Allocate gives maker as much of taker as it can absorb.
+func (PriceTimePolicy) Allocate(taker, maker *Order, level *MatchLevel) decimal.Decimal {
+	return min(taker.Qty, maker.Qty)
+}
+
+// ExecutionPrice returns the maker's price unless config selects
+// ExecuteAtMid, in which case it returns the midpoint of the two limit prices
+// rounded to the tick size, or ExecuteAtTaker, in which case it returns the
+// taker's limit price. An incoming order without a limit price always trades
+// at the maker's price.
+func (PriceTimePolicy) ExecutionPrice(taker, maker *Order, config PairConfig) decimal.Decimal {
+	if taker.Price.IsZero() {
+		return maker.Price
+	}
+	switch config.ExecutionPrice {
+	case ExecuteAtMid:
+		mid := taker.Price.Add(maker.Price).Div(decimal.NewFromInt(2))
+		return roundTo(mid, config.TickSize, RoundNearest)
+	case ExecuteAtTaker:
+		return taker.Price
+	default:
+		return maker.Price
+	}
+}
+
+// SetMatchPolicy replaces the policy the book matches with. A nil policy
+// restores PriceTimePolicy.
+func (ob *OrderBook) SetMatchPolicy(p MatchPolicy) {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	if p == nil {
+		p = PriceTimePolicy{}
+	}
+	ob.policy = p
+}
+
+// WithMatchPolicy makes every order book of the engine match with p instead of
+// PriceTimePolicy. p is shared between the books, so it must be safe for
+// concurrent use.
+func WithMatchPolicy(p MatchPolicy) Option {
+	return func(e *Engine) {
+		e.policy = p
+	}
+}
@@ -0,0 +1,89 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// proRataPolicy shares each price level among its makers in proportion to
+// their size, for testing policies that allocate across a level.
+type proRataPolicy struct{ PriceTimePolicy }
+
+func (proRataPolicy) Allocate(taker, maker *Order, level *MatchLevel) decimal.Decimal {
+	return level.TakerQty.Mul(maker.Qty).Div(level.Quantity()).Floor()
+}
+
+// accountFilterPolicy refuses to match orders from the same account.
+type accountFilterPolicy struct{ PriceTimePolicy }
+
+func (accountFilterPolicy) Eligible(taker, maker *Order) bool {
+	return taker.Account == "" || taker.Account != maker.Account
+}
+
+// TestPriceTimePolicyDefault tests that books match in price-time priority without a policy being set
+func TestPriceTimePolicyDefault(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	ob.SetMatchPolicy(nil)
+	ob.Execute(Order{ID: "ask1", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(2)})
+	ob.Execute(Order{ID: "ask2", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(2)})
+
+	res := ob.Execute(Order{ID: "buy", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(3)})
+	if len(res.Trades) != 2 || res.Trades[0].SellOrderID != "ask1" || !res.Trades[0].Qty.Equal(decimal.NewFromInt(2)) {
+		t.Fatalf("Expected the first ask to fill completely before the second, got %+v", res.Trades)
+	}
+}
+
+// TestMatchPolicyAllocate tests that a policy can share a price level among its makers
+func TestMatchPolicyAllocate(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	ob.SetMatchPolicy(proRataPolicy{})
+	ob.Execute(Order{ID: "ask1", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(6)})
+	ob.Execute(Order{ID: "ask2", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(2)})
+
+	res := ob.Execute(Order{ID: "buy", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(4)})
+	if len(res.Trades) != 2 {
+		t.Fatalf("Expected the buy to trade with both asks, got %+v", res.Trades)
+	}
+	if !res.Trades[0].Qty.Equal(decimal.NewFromInt(3)) || !res.Trades[1].Qty.Equal(decimal.NewFromInt(1)) {
+		t.Errorf("Expected allocations of 3 and 1, got %s and %s", res.Trades[0].Qty, res.Trades[1].Qty)
+	}
+	if err := ob.Validate(); err != nil {
+		t.Errorf("Expected a valid book, got %v", err)
+	}
+	if vol := ob.QuantityAtPrice(Sell, decimal.NewFromInt(100)); !vol.Equal(decimal.NewFromInt(4)) {
+		t.Errorf("Expected 4 left at 100, got %s", vol)
+	}
+}
+
+// TestMatchPolicyEligible tests that makers a policy deems ineligible keep their priority
+func TestMatchPolicyEligible(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	ob.SetMatchPolicy(accountFilterPolicy{})
+	ob.Execute(Order{ID: "own", Account: "alice", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	ob.Execute(Order{ID: "other", Account: "bob", Side: Sell, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(1)})
+
+	res := ob.Execute(Order{ID: "buy", Account: "alice", Side: Buy, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(1)})
+	if len(res.Trades) != 1 || res.Trades[0].SellOrderID != "other" {
+		t.Fatalf("Expected the buy to pass over its own ask, got %+v", res.Trades)
+	}
+	if bid, ask, _, _ := ob.TopOfBook(); !bid.IsZero() || !ask.Equal(decimal.NewFromInt(100)) {
+		t.Errorf("Expected the skipped ask to stay at the top of the book, got bid %s ask %s", bid, ask)
+	}
+
+	res = ob.Execute(Order{ID: "fok", Account: "alice", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1), TimeInForce: FOK})
+	if len(res.Trades) != 0 {
+		t.Errorf("Expected FOK order not to count ineligible liquidity, got %+v", res.Trades)
+	}
+}
+
+// TestEngineWithMatchPolicy tests that the engine applies its match policy to new books
+func TestEngineWithMatchPolicy(t *testing.T) {
+	engine := NewEngine(WithMatchPolicy(accountFilterPolicy{}))
+	engine.AddOrder("BTC-USD", Order{ID: "ask", Account: "alice", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	engine.AddOrder("BTC-USD", Order{ID: "buy", Account: "alice", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+
+	if bids, asks, _ := engine.OpenOrderCount("BTC-USD"); bids != 1 || asks != 1 {
+		t.Errorf("Expected both orders to rest, got %d bids and %d asks", bids, asks)
+	}
+}
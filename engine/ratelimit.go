@@ -0,0 +1,130 @@
+package engine
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter is a per-account token bucket limiter. Each account's bucket
+// holds up to burst tokens and refills at rate tokens per second; every order
+// consumes one token. Buckets that have refilled are forgotten, as a new
+// bucket starts full, so idle accounts cost nothing. It is safe for
+// concurrent use.
+type rateLimiter struct {
+	rate    float64
+	burst   float64
+	mutex   sync.Mutex
+	buckets map[string]*tokenBucket
+	exempt  map[string]struct{}
+	pruneAt time.Time // When buckets are next checked for being full
+}
+
+// tokenBucket is the state of one account's bucket.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter creates a limiter allowing rate orders per second per account
+// with bursts of up to burst orders.
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	return &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+		exempt:  make(map[string]struct{}),
+	}
+}
+
+// allow reports whether account may place an order at now, consuming a token
+// if so. Orders without an account and exempt accounts are always allowed.
+func (l *rateLimiter) allow(account string, now time.Time) bool {
+	if account == "" {
+		return true
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if _, ok := l.exempt[account]; ok {
+		return true
+	}
+
+	l.prune(now)
+	b, ok := l.buckets[account]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[account] = b
+	}
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens += elapsed * l.rate
+		if b.tokens > l.burst {
+			b.tokens = l.burst
+		}
+		b.last = now
+	}
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// prune forgets the buckets that have refilled to burst by now. Any bucket
+// idle for the time it takes to refill completely is full, so the buckets are
+// swept at most once per refill period, keeping the cost per order constant.
+// A limiter that never refills keeps its buckets. Must be called with
+// l.mutex held.
+func (l *rateLimiter) prune(now time.Time) {
+	if l.rate <= 0 || now.Before(l.pruneAt) {
+		return
+	}
+	for account, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, account)
+		}
+	}
+	l.pruneAt = now.Add(time.Duration(l.burst / l.rate * float64(time.Second)))
+}
+
+// setExempt adds or removes account from the set of exempt accounts.
+func (l *rateLimiter) setExempt(account string, exempt bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if exempt {
+		l.exempt[account] = struct{}{}
+	} else {
+		delete(l.exempt, account)
+	}
+}
+
+// WithRateLimit limits every account to perAccount orders per second, with
+// bursts of up to burst orders. Orders over the limit receive a Rejected fill
+// with reason RejectRateLimited and never reach the book. Orders without an
+// Account are not limited.
+//
+// perAccount is a plain float64 rather than a rate.Limit from
+// golang.org/x/time/rate, so the engine needs no extra dependency; a
+// rate.Limit converts with float64(limit).
+func WithRateLimit(perAccount float64, burst int) Option {
+	return func(e *Engine) {
+		e.limiter = newRateLimiter(perAccount, burst)
+	}
+}
+
+// ExemptAccount exempts account from the configured rate limit, or restores
+// limiting when exempt is false. It has no effect without WithRateLimit.
+func (e *Engine) ExemptAccount(account string, exempt bool) {
+	if e.limiter != nil {
+		e.limiter.setExempt(account, exempt)
+	}
+}
+
+// reset refills every account's bucket by forgetting them. Exemptions are
+// kept.
+func (l *rateLimiter) reset() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	clear(l.buckets)
+}
@@ -0,0 +1,90 @@
+package engine
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// countRejected drains the fill stream and counts rate-limited rejections
+func countRejected(t *testing.T, engine *Engine) int {
+	t.Helper()
+	rejected := 0
+	for len(engine.FillStream) > 0 {
+		if fill := <-engine.FillStream; fill.Status == Rejected {
+			if fill.Reason != RejectRateLimited {
+				t.Errorf("Expected reason %s, got %s", RejectRateLimited, fill.Reason)
+			}
+			rejected++
+		}
+	}
+	return rejected
+}
+
+// TestRateLimit tests that an account flooding past its burst is rejected without affecting others
+func TestRateLimit(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	engine := NewEngine(WithClock(clock), WithRateLimit(1, 3))
+	pair := "BTC-USD"
+
+	order := func(id, account string) Order {
+		return Order{ID: id, Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1), Account: account}
+	}
+
+	for i := 0; i < 5; i++ {
+		engine.AddOrder(pair, order(fmt.Sprintf("flood%d", i), "alice"))
+	}
+	if n := countRejected(t, engine); n != 2 {
+		t.Errorf("Expected 2 orders over the burst to be rejected, got %d", n)
+	}
+
+	engine.AddOrder(pair, order("bob1", "bob"))
+	if n := countRejected(t, engine); n != 0 {
+		t.Errorf("Expected other account to be unaffected, got %d rejected", n)
+	}
+	if bids, _, _ := engine.OpenOrderCount(pair); bids != 4 {
+		t.Errorf("Expected 4 resting bids, got %d", bids)
+	}
+
+	// Tokens refill over time
+	clock.Advance(time.Second)
+	engine.AddOrder(pair, order("alice-later", "alice"))
+	engine.AddOrder(pair, order("alice-again", "alice"))
+	if n := countRejected(t, engine); n != 1 {
+		t.Errorf("Expected one refilled token, got %d rejected", n)
+	}
+
+	engine.ExemptAccount("alice", true)
+	for i := 0; i < 5; i++ {
+		engine.AddOrder(pair, order(fmt.Sprintf("exempt%d", i), "alice"))
+	}
+	if n := countRejected(t, engine); n != 0 {
+		t.Errorf("Expected exempt account not to be limited, got %d rejected", n)
+	}
+}
+
+// TestRateLimiterPrunesFullBuckets tests that buckets refilled to the burst are forgotten without losing the state of draining ones
+func TestRateLimiterPrunesFullBuckets(t *testing.T) {
+	limiter := newRateLimiter(1, 3)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 100; i++ {
+		limiter.allow(fmt.Sprintf("account%d", i), now)
+	}
+
+	// Three seconds refill every idle bucket, while busy has just been drained
+	now = now.Add(3 * time.Second)
+	for i := 0; i < 3; i++ {
+		limiter.allow("busy", now)
+	}
+	if len(limiter.buckets) != 1 {
+		t.Fatalf("Expected only the busy bucket to be kept, got %d", len(limiter.buckets))
+	}
+	if limiter.allow("busy", now) {
+		t.Error("Expected the drained account to stay limited after pruning")
+	}
+	if !limiter.allow("account0", now) {
+		t.Error("Expected a pruned account to start again with a full bucket")
+	}
+}
@@ -0,0 +1,143 @@
+package engine
+
+import (
+	"container/heap"
+	"errors"
+
+	"github.com/shopspring/decimal"
+)
+
+// ErrInvalidReduce is returned when an order is reduced by a quantity that is
+// not positive.
+var ErrInvalidReduce = errors.New("engine: reduce quantity must be positive")
+
+// Reduce lowers the quantity of the resting order with the given ID by by,
+// keeping its place in the queue because a smaller quantity never affects
+// priority. If by covers the remaining quantity the order is removed as by
+// Cancel; otherwise an Amended event is recorded. It returns a copy of the
+// order as it was left, or as it was removed, whether it was removed, and
+// false if no such order is resting.
+func (ob *OrderBook) Reduce(orderID string, by decimal.Decimal) (order Order, removed, ok bool) {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	var h heap.Interface = ob.bids
+	side := &ob.bids.indexedHeap
+	i := side.indexOf(orderID)
+	if i < 0 {
+		h, side = ob.asks, &ob.asks.indexedHeap
+		i = side.indexOf(orderID)
+	}
+	if i < 0 {
+		return Order{}, false, false
+	}
+
+	o := side.orderHeap[i]
+	if by.GreaterThanOrEqual(o.Qty) {
+		return ob.removeAt(h, i), true, true
+	}
+	o.Qty = o.Qty.Sub(by)
+	ob.addVolume(o.Side, by.Neg())
+	ob.emit(Amended, *o)
+	ob.refreshTop()
+	return *o, false, true
+}
+
+// ReduceOrder lowers the quantity of a resting order in the book of the
+// specified trading pair by reduceBy without losing its time priority, and
+// emits a fill carrying the new remaining quantity. A reduction that covers
+// the remaining quantity cancels the order and emits a Canceled fill instead.
+// The reduction is journaled and processed on the pair's shard. orderID may
+// also be the order's client order ID.
+//
+// A reduction that would leave a quantity off the pair's step grid or below
+// its MinQty or MinNotional is refused with a Rejected fill, and the order
+// keeps resting unchanged.
+//
+// Returns ErrInvalidReduce if reduceBy is not positive, ErrPairNotFound if the
+// pair has no order book, ErrOrderNotFound if the order is not resting,
+// ErrOrderRejected if the reduction is refused, or the journal error if the
+// reduction could not be recorded.
+func (e *Engine) ReduceOrder(pair, orderID string, reduceBy decimal.Decimal) error {
+	if !reduceBy.IsPositive() {
+		return ErrInvalidReduce
+	}
+	if _, exists := e.book(pair); !exists {
+		return ErrPairNotFound
+	}
+
+	return e.submit(pair, Order{}, func(book *OrderBook, res *MatchResult) error {
+		if id, ok := book.resolve(orderID); ok {
+			orderID = id
+		}
+		return e.reduce(book, orderID, reduceBy, res)
+	})
+}
+
+// reduce carries out ReduceOrder on the pair's shard once orderID has been
+// resolved.
+func (e *Engine) reduce(book *OrderBook, orderID string, by decimal.Decimal, res *MatchResult) error {
+	if reason := reduceRejected(book, orderID, by); reason != "" {
+		order, _ := book.Order(orderID)
+		res.Fills = append(res.Fills, rejectedFill(book.Pair, order, reason, e.clock.Now().Unix()))
+		return ErrOrderRejected
+	}
+	if err := e.journal.AppendReduce(book.Pair, orderID, by); err != nil {
+		return err
+	}
+	order, removed, ok := book.Reduce(orderID, by)
+	if !ok {
+		return ErrOrderNotFound
+	}
+	now := e.clock.Now().Unix()
+	if removed {
+		res.Fills = append(res.Fills, canceledFill(book.Pair, order, now))
+	} else {
+		res.Fills = append(res.Fills, reducedFill(book.Pair, order, by, now))
+	}
+	return nil
+}
+
+// reduceRejected returns why reducing the resting order orderID by by would
+// break the pair's trading rules, or an empty reason if it would not. A
+// reduction that removes the order is always allowed.
+func reduceRejected(book *OrderBook, orderID string, by decimal.Decimal) RejectReason {
+	order, ok := book.Order(orderID)
+	if !ok || by.GreaterThanOrEqual(order.Qty) {
+		return ""
+	}
+	// Only the quantity changes; the price was placed on the grid at intake.
+	order.Qty = order.Qty.Sub(by)
+	config := book.Config()
+	if !config.StepSize.IsZero() && !order.Qty.Mod(config.StepSize).IsZero() {
+		return RejectOffStep
+	}
+	return config.check(order)
+}
+
+// reducedFill builds the fill event reported when a resting order is reduced
+// by qty. OriginalQty carries the quantity before the reduction and the status
+// reflects what the order has executed so far.
+func reducedFill(pair string, order Order, qty decimal.Decimal, now int64) OrderFill {
+	status := New
+	if order.ExecutedQty.IsPositive() {
+		status = PartiallyFilled
+	}
+	return OrderFill{
+		OrderID:       order.ID,
+		Pair:          pair,
+		Side:          order.Side,
+		Account:       order.Account,
+		ClientOrderID: order.ClientOrderID,
+		OriginalQty:   order.Qty.Add(qty),
+		ExecutedQty:   decimal.Zero,
+		RemainingQty:  order.Qty,
+		Price:         order.Price,
+		FillPrice:     decimal.Zero,
+		Status:        status,
+		Timestamp:     now,
+
+		CumulativeQty:      order.ExecutedQty,
+		CumulativeAvgPrice: order.avgExecutedPrice(),
+	}
+}
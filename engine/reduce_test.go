@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// TestReduceOrderKeepsPriority tests that a reduced order keeps its place among same-price orders
+func TestReduceOrderKeepsPriority(t *testing.T) {
+	engine := NewEngine()
+	pair := "BTC-USD"
+	for _, id := range []string{"a", "b", "c"} {
+		engine.AddOrder(pair, Order{ID: id, Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(5)})
+	}
+	drainFills(engine)
+
+	if err := engine.ReduceOrder(pair, "b", decimal.NewFromInt(3)); err != nil {
+		t.Fatalf("Expected reduce to succeed, got %v", err)
+	}
+	fill := <-engine.FillStream
+	if fill.OrderID != "b" || fill.Status != New || !fill.RemainingQty.Equal(decimal.NewFromInt(2)) || !fill.OriginalQty.Equal(decimal.NewFromInt(5)) {
+		t.Errorf("Expected b reduced from 5 to 2, got %+v", fill)
+	}
+	if _, asks := engine.getOrCreateBook(pair).RestingVolume(); !asks.Equal(decimal.NewFromInt(12)) {
+		t.Errorf("Expected 12 resting, got %s", asks)
+	}
+
+	engine.AddOrder(pair, Order{ID: "buy", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(7)})
+	var sellers []string
+	for len(engine.TradeStream) > 0 {
+		trade := <-engine.TradeStream
+		sellers = append(sellers, trade.SellOrderID+":"+trade.Qty.String())
+	}
+	if len(sellers) != 2 || sellers[0] != "a:5" || sellers[1] != "b:2" {
+		t.Errorf("Expected a:5 then b:2, got %v", sellers)
+	}
+}
+
+// TestReduceOrderFullCancel tests that reducing by the remaining quantity cancels the order
+func TestReduceOrderFullCancel(t *testing.T) {
+	engine := NewEngine()
+	pair := "BTC-USD"
+	engine.AddOrder(pair, Order{ID: "a", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(5)})
+	drainFills(engine)
+
+	if err := engine.ReduceOrder(pair, "a", decimal.NewFromInt(0)); err != ErrInvalidReduce {
+		t.Errorf("Expected ErrInvalidReduce, got %v", err)
+	}
+	if err := engine.ReduceOrder(pair, "missing", decimal.NewFromInt(1)); err != ErrOrderNotFound {
+		t.Errorf("Expected ErrOrderNotFound, got %v", err)
+	}
+	if err := engine.ReduceOrder(pair, "a", decimal.NewFromInt(6)); err != nil {
+		t.Fatalf("Expected reduce to succeed, got %v", err)
+	}
+	if fill := <-engine.FillStream; fill.Status != Canceled {
+		t.Errorf("Expected Canceled fill, got %+v", fill)
+	}
+	if bids, _, _ := engine.OpenOrderCount(pair); bids != 0 {
+		t.Errorf("Expected no resting bids, got %d", bids)
+	}
+}
+
+// TestReduceOrderReplay tests that reductions are journaled and replayed
+func TestReduceOrderReplay(t *testing.T) {
+	journal, err := NewFileJournal(filepath.Join(t.TempDir(), "engine.journal"))
+	if err != nil {
+		t.Fatalf("Failed to open journal: %v", err)
+	}
+	defer journal.Close()
+
+	pair := "BTC-USD"
+	original := NewEngine(WithJournal(journal))
+	original.AddOrder(pair, Order{ID: "a", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(5)})
+	if err := original.ReduceOrder(pair, "a", decimal.NewFromFloat(1.5)); err != nil {
+		t.Fatalf("Expected reduce to succeed, got %v", err)
+	}
+
+	replayed := NewEngine()
+	if err := replayed.Replay(journal); err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if bidVol, _ := replayed.getOrCreateBook(pair).RestingVolume(); !bidVol.Equal(decimal.NewFromFloat(3.5)) {
+		t.Errorf("Expected 3.5 resting after replay, got %s", bidVol)
+	}
+}
+
+// TestReduceOrderOffStep tests that a reduction leaving a quantity off the step grid or below the minimum is rejected
+func TestReduceOrderOffStep(t *testing.T) {
+	engine := NewEngine()
+	pair := "BTC-USD"
+	engine.SetPairConfig(pair, PairConfig{StepSize: decimal.NewFromFloat(0.1), MinQty: decimal.NewFromFloat(0.5)})
+	engine.AddOrder(pair, Order{ID: "buy", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(2)})
+	drainFills(engine)
+
+	tests := []struct {
+		by     string
+		reason RejectReason
+	}{
+		{"0.05", RejectOffStep},
+		{"1.7", RejectBelowMinQty},
+	}
+	for _, tt := range tests {
+		if err := engine.ReduceOrder(pair, "buy", decimal.RequireFromString(tt.by)); err != ErrOrderRejected {
+			t.Errorf("Expected reducing by %s to be rejected, got %v", tt.by, err)
+		}
+		if fill := <-engine.FillStream; fill.Status != Rejected || fill.Reason != tt.reason {
+			t.Errorf("Expected a %s rejection, got %+v", tt.reason, fill)
+		}
+	}
+
+	if err := engine.ReduceOrder(pair, "buy", decimal.RequireFromString("0.5")); err != nil {
+		t.Fatalf("Expected an on-step reduction to succeed, got %v", err)
+	}
+	if _, _, bidQty, _, _ := engine.TopOfBook(pair); !bidQty.Equal(decimal.RequireFromString("1.5")) {
+		t.Errorf("Expected 1.5 left, got %s", bidQty)
+	}
+}
@@ -0,0 +1,101 @@
+package engine
+
+import "github.com/shopspring/decimal"
+
+// ReferencePriceSource selects the price that a pair's price-conditional
+// features follow: trailing stops trail it, market-if-touched orders trigger
+// on it and the LULD breaker measures moves of it.
+type ReferencePriceSource string
+
+const (
+	// ReferenceLastTrade follows the price of every trade. An empty
+	// ReferencePriceSource is treated as ReferenceLastTrade.
+	ReferenceLastTrade ReferencePriceSource = "last_trade"
+	// ReferenceMid follows the midpoint of the best bid and best ask, so
+	// quotes moving trigger orders even when nothing trades. A one-sided book
+	// has no mid, and the last mid stays in force until both sides are back.
+	ReferenceMid ReferencePriceSource = "mid"
+	// ReferenceCustom follows the price returned by the pair config's
+	// CustomReferencePrice, such as a mark price from an external feed. It
+	// is read after every order and operation on the pair, on the pair's
+	// shard and with the book locked, so it must be quick and must not call
+	// back into the engine. A price that is not positive is ignored.
+	ReferenceCustom ReferencePriceSource = "custom"
+)
+
+// tracksTrades reports whether the reference price is the last trade price.
+func (s ReferencePriceSource) tracksTrades() bool {
+	return s == "" || s == ReferenceLastTrade
+}
+
+// referencePrice returns the current reference price of the book, or false if
+// there is none yet. The caller must hold ob.mutex.
+func (ob *OrderBook) referencePrice() (decimal.Decimal, bool) {
+	var price decimal.Decimal
+	switch ob.config.ReferencePrice {
+	case ReferenceMid:
+		bid, ask := ob.bestBid.Load(), ob.bestAsk.Load()
+		if bid == nil || ask == nil {
+			return decimal.Zero, false
+		}
+		price = bid.Add(*ask).Div(decimal.NewFromInt(2))
+	case ReferenceCustom:
+		if ob.config.CustomReferencePrice == nil {
+			return decimal.Zero, false
+		}
+		price = ob.config.CustomReferencePrice()
+	default:
+		price = ob.lastPrice
+	}
+	return price, price.IsPositive()
+}
+
+// referenceMoves returns the reference prices observed since the trades in
+// res from index start onwards were appended: the price of each of those
+// trades when following the last trade, or otherwise the current reference
+// price if it changed since it was last observed. The caller must hold
+// ob.mutex and have refreshed the cached top of book.
+func (ob *OrderBook) referenceMoves(res *MatchResult, start int) []decimal.Decimal {
+	if ob.config.ReferencePrice.tracksTrades() {
+		var prices []decimal.Decimal
+		for _, trade := range res.Trades[start:] {
+			prices = append(prices, trade.Price)
+		}
+		return prices
+	}
+	price, ok := ob.referencePrice()
+	if !ok || price.Equal(ob.reference) {
+		return nil
+	}
+	ob.reference = price
+	return []decimal.Decimal{price}
+}
+
+// followReference feeds a reference price that moved without a trade, as a
+// mid does when a quote is canceled, to the pending stops and releases those
+// it triggers into res. It reports false, doing nothing, for books following
+// the last trade, whose reference only moves on trades, and for halted books.
+func (ob *OrderBook) followReference(res *MatchResult) bool {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	if ob.config.ReferencePrice.tracksTrades() || ob.halted(ob.clock.Now().Unix()) {
+		return false
+	}
+	ob.trailStops(res, len(res.Trades))
+	ob.takeEvents(res)
+	return true
+}
+
+// followReference lets book catch up with a reference price that moved
+// without a trade once a job has been published: released stops are matched
+// and the LULD breaker measures the new price, and the outcome is settled and
+// published like any other.
+func (e *Engine) followReference(book *OrderBook, res *MatchResult) {
+	res.reset()
+	if !book.followReference(res) {
+		return
+	}
+	e.settle(book, res)
+	e.publish(res)
+}
@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// TestReferenceMidTriggersStop tests that a stop following the mid fires when a canceled quote moves the mid, without any trade
+func TestReferenceMidTriggersStop(t *testing.T) {
+	for _, tt := range []struct {
+		source ReferencePriceSource
+		fires  bool
+	}{
+		{ReferenceMid, true},
+		{ReferenceLastTrade, false},
+	} {
+		t.Run(string(tt.source), func(t *testing.T) {
+			engine := NewEngine()
+			pair := "BTC-USD"
+			engine.SetPairConfig(pair, PairConfig{ReferencePrice: tt.source})
+			engine.AddOrder(pair, Order{ID: "bid1", Side: Buy, Price: decimal.NewFromInt(99), Qty: decimal.NewFromInt(1)})
+			engine.AddOrder(pair, Order{ID: "ask", Side: Sell, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(1)})
+
+			// The mid is 100, so the stop trails it at 98
+			engine.AddOrder(pair, Order{ID: "stop", Side: Sell, Qty: decimal.NewFromInt(1), Type: TrailingStop, TrailOffset: decimal.NewFromInt(2)})
+			book := engine.getOrCreateBook(pair)
+			if tt.fires {
+				if stops := book.PendingStops(); len(stops) != 1 || !stops[0].StopPrice.Equal(decimal.NewFromInt(98)) {
+					t.Fatalf("Expected the stop anchored at the mid with level 98, got %+v", stops)
+				}
+			}
+
+			// Pulling the best bid drops the mid to 97
+			engine.AddOrder(pair, Order{ID: "bid2", Side: Buy, Price: decimal.NewFromInt(93), Qty: decimal.NewFromInt(1)})
+			drainOrderEvents(engine)
+			if err := engine.CancelOrder(pair, "bid1"); err != nil {
+				t.Fatalf("Expected the cancel to succeed, got %v", err)
+			}
+
+			if len(engine.TradeStream) != 0 {
+				t.Errorf("Expected no trades, got %d", len(engine.TradeStream))
+			}
+			if fired := len(book.PendingStops()) == 0; fired != tt.fires {
+				t.Fatalf("Expected the stop to fire %v, got %v", tt.fires, fired)
+			}
+			if !tt.fires {
+				return
+			}
+
+			triggered := false
+			for _, event := range drainOrderEvents(engine) {
+				triggered = triggered || (event.Type == Triggered && event.Order.ID == "stop")
+			}
+			if !triggered {
+				t.Error("Expected a Triggered event for the stop")
+			}
+			// Released as a limit sell at its level of 98, above the best bid
+			if order, ok := engine.GetOrder(pair, "stop"); !ok || order.Type != Limit || !order.Price.Equal(decimal.NewFromInt(98)) {
+				t.Errorf("Expected the stop resting as a limit sell at 98, got %+v", order)
+			}
+		})
+	}
+}
+
+// TestReferenceCustomTriggersMIT tests that a market-if-touched order following a custom mark price fires when the mark reaches its trigger
+func TestReferenceCustomTriggersMIT(t *testing.T) {
+	engine := NewEngine()
+	pair := "BTC-USD"
+	mark := decimal.NewFromInt(100)
+	engine.SetPairConfig(pair, PairConfig{
+		ReferencePrice:       ReferenceCustom,
+		CustomReferencePrice: func() decimal.Decimal { return mark },
+	})
+	engine.AddOrder(pair, Order{ID: "ask", Side: Sell, Price: decimal.NewFromInt(95), Qty: decimal.NewFromInt(1)})
+	engine.AddOrder(pair, Order{ID: "mit", Side: Buy, Qty: decimal.NewFromInt(1), Type: MarketIfTouched, TriggerPrice: decimal.NewFromInt(90)})
+
+	engine.AddOrder(pair, Order{ID: "bid1", Side: Buy, Price: decimal.NewFromInt(80), Qty: decimal.NewFromInt(1)})
+	if len(engine.TradeStream) != 0 {
+		t.Fatal("Expected the MIT to wait while the mark is above its trigger")
+	}
+
+	mark = decimal.NewFromInt(89)
+	engine.AddOrder(pair, Order{ID: "bid2", Side: Buy, Price: decimal.NewFromInt(81), Qty: decimal.NewFromInt(1)})
+	if len(engine.TradeStream) != 1 {
+		t.Fatalf("Expected the MIT to trade once the mark touched 89, got %d trades", len(engine.TradeStream))
+	}
+	if trade := <-engine.TradeStream; trade.BuyOrderID != "mit" || !trade.Price.Equal(decimal.NewFromInt(95)) {
+		t.Errorf("Expected the MIT to buy at 95, got %+v", trade)
+	}
+}
+
+// TestReferenceMidBreaker tests that the LULD breaker of a pair following the mid halts on a quote move without a trade
+func TestReferenceMidBreaker(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC))
+	engine := NewEngine(WithClock(clock))
+	pair := "BTC-USD"
+	engine.SetPairConfig(pair, PairConfig{
+		ReferencePrice: ReferenceMid,
+		LULD:           LULDConfig{Percent: decimal.NewFromInt(5), Window: time.Minute, Cooldown: time.Minute},
+	})
+	engine.AddOrder(pair, Order{ID: "bid1", Side: Buy, Price: decimal.NewFromInt(99), Qty: decimal.NewFromInt(1)})
+	engine.AddOrder(pair, Order{ID: "ask", Side: Sell, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(1)})
+	engine.AddOrder(pair, Order{ID: "bid2", Side: Buy, Price: decimal.NewFromInt(79), Qty: decimal.NewFromInt(1)})
+	if engine.IsHalted(pair) {
+		t.Fatal("Expected no halt while the mid holds at 100")
+	}
+
+	if err := engine.CancelOrder(pair, "bid1"); err != nil {
+		t.Fatalf("Expected the cancel to succeed, got %v", err)
+	}
+	if !engine.IsHalted(pair) {
+		t.Fatal("Expected the mid falling to 90 to trip the breaker")
+	}
+	if event := <-engine.HaltEvents; event.Reason != HaltLULD || !event.Price.Equal(decimal.NewFromInt(90)) {
+		t.Errorf("Expected a LULD halt at 90, got %+v", event)
+	}
+}
@@ -0,0 +1,119 @@
+package engine
+
+import "errors"
+
+// ErrOrderRejected is returned when a replacement order is refused before
+// reaching the book. The Rejected fill on FillStream carries the reason.
+var ErrOrderRejected = errors.New("engine: order rejected")
+
+// Replace cancels the resting order or pending stop with ID oldID and enters
+// order in its place under a single lock acquisition, so nothing can match
+// against the book between the two. The new order matches as any incoming
+// order would. The result holds a Canceled fill for the old order followed by
+// the new order's events. If no order with ID oldID is resting the book is
+// left untouched and false is returned.
+func (ob *OrderBook) Replace(oldID string, order Order) (MatchResult, bool) {
+	var res MatchResult
+	ok := ob.replaceInto(oldID, order, &res)
+	return res, ok
+}
+
+// replaceInto is Replace appending its events to res.
+func (ob *OrderBook) replaceInto(oldID string, order Order, res *MatchResult) bool {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	old, ok := ob.cancel(oldID)
+	if !ok {
+		return false
+	}
+	res.Fills = append(res.Fills, canceledFill(ob.Pair, old, ob.clock.Now().Unix()))
+	ob.execute(order, true, res)
+	ob.takeEvents(res)
+	return true
+}
+
+// WithReplaceMissing makes ReplaceOrder enter the new order even when the
+// order it replaces is no longer resting, for example because it was filled
+// just before the replace arrived. ReplaceOrder still returns
+// ErrOrderNotFound in that case.
+func WithReplaceMissing() Option {
+	return func(e *Engine) {
+		e.replaceMissing = true
+	}
+}
+
+// ReplaceOrder atomically cancels the resting order oldID in the book of the
+// specified trading pair and enters newOrder in its place, so the trader is
+// never left without an order in between. The new order is checked and
+// matched like any order passed to AddOrder, and trades immediately if it
+// crosses, but a price or quantity off the pair's tick or step grid is
+// rejected rather than rounded. oldID may also be the old order's client
+// order ID. If newOrder has no ID it takes over the old order's ID. Both the
+// cancel and the new order are journaled and processed on the pair's shard.
+//
+// It returns the ID the new order was entered under. Returns ErrPairNotFound
+// if the pair has no order book, ErrOrderRejected if the new order is refused,
+// in which case the old order keeps resting, ErrOrderNotFound if oldID is not
+// resting, in which case the new order is only entered with
+// WithReplaceMissing, or the journal error if the replace could not be
+// recorded.
+func (e *Engine) ReplaceOrder(pair, oldID string, newOrder Order) (string, error) {
+	if _, exists := e.book(pair); !exists {
+		return "", ErrPairNotFound
+	}
+
+	var entered bool
+	err := e.submit(pair, Order{}, func(book *OrderBook, res *MatchResult) error {
+		id, found := book.resolve(oldID)
+		if found {
+			oldID = id
+		}
+		if newOrder.ID == "" {
+			newOrder.ID = oldID
+		}
+		var err error
+		entered, err = e.replace(book, oldID, found, newOrder, res)
+		return err
+	})
+	if !entered {
+		return "", err
+	}
+	return newOrder.ID, err
+}
+
+// replace carries out ReplaceOrder on the pair's shard once oldID has been
+// resolved, found telling whether it is resting. It reports whether
+// newOrder was entered into the book.
+func (e *Engine) replace(book *OrderBook, oldID string, found bool, newOrder Order, res *MatchResult) (bool, error) {
+	order, ok := e.admitAmendment(book, newOrder, res)
+	if !ok {
+		return false, ErrOrderRejected
+	}
+	if !found && !e.replaceMissing {
+		return false, ErrOrderNotFound
+	}
+
+	if found {
+		if err := e.journal.AppendCancel(book.Pair, oldID); err != nil {
+			return false, err
+		}
+	}
+	if err := e.journal.AppendOrder(book.Pair, order); err != nil {
+		// The cancel is on record, so carry it out to stay consistent with
+		// the journal.
+		if old, ok := book.Cancel(oldID); ok {
+			res.Fills = append(res.Fills, canceledFill(book.Pair, old, e.clock.Now().Unix()))
+		}
+		res.Fills = append(res.Fills, rejectedFill(book.Pair, order, RejectJournalFailed, e.clock.Now().Unix()))
+		return false, err
+	}
+
+	if found && book.replaceInto(oldID, order, res) {
+		e.settle(book, res)
+		return true, nil
+	}
+	book.executeInto(order, res)
+	e.settle(book, res)
+	return true, ErrOrderNotFound
+}
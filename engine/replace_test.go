@@ -0,0 +1,123 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// TestReplaceOrderRests tests that a replaced order is gone and its replacement rests in one step
+func TestReplaceOrderRests(t *testing.T) {
+	engine := NewEngine()
+	pair := "BTC-USD"
+	engine.AddOrder(pair, Order{ID: "old", Side: Buy, Price: decimal.NewFromInt(99), Qty: decimal.NewFromInt(2)})
+	drainFills(engine)
+
+	id, err := engine.ReplaceOrder(pair, "old", Order{Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(3)})
+	if err != nil || id != "old" {
+		t.Fatalf("Expected the replacement to take over the old ID, got %q and %v", id, err)
+	}
+	if fill := <-engine.FillStream; fill.OrderID != "old" || fill.Status != Canceled || !fill.Price.Equal(decimal.NewFromInt(99)) {
+		t.Errorf("Expected the old order to be canceled first, got %+v", fill)
+	}
+	if fill := <-engine.FillStream; fill.Status != New || !fill.Price.Equal(decimal.NewFromInt(100)) {
+		t.Errorf("Expected the replacement to be acknowledged, got %+v", fill)
+	}
+
+	bid, _, bidQty, _, _ := engine.TopOfBook(pair)
+	if !bid.Equal(decimal.NewFromInt(100)) || !bidQty.Equal(decimal.NewFromInt(3)) {
+		t.Errorf("Expected only the replacement to rest, got %s @ %s", bidQty, bid)
+	}
+	if bids, _, _ := engine.OpenOrderCount(pair); bids != 1 {
+		t.Errorf("Expected 1 resting bid, got %d", bids)
+	}
+}
+
+// TestReplaceOrderCrosses tests that a replacement that crosses matches immediately
+func TestReplaceOrderCrosses(t *testing.T) {
+	engine := NewEngine()
+	pair := "BTC-USD"
+	engine.AddOrder(pair, Order{ID: "ask", Side: Sell, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(1)})
+	engine.AddOrder(pair, Order{ID: "old", Side: Buy, Price: decimal.NewFromInt(99), Qty: decimal.NewFromInt(1)})
+	drainFills(engine)
+
+	id, err := engine.ReplaceOrder(pair, "old", Order{ID: "new", Side: Buy, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(1)})
+	if err != nil || id != "new" {
+		t.Fatalf("Expected replace to succeed as new, got %q and %v", id, err)
+	}
+	trade := <-engine.TradeStream
+	if trade.BuyOrderID != "new" || trade.SellOrderID != "ask" {
+		t.Errorf("Expected the replacement to trade with the ask, got %+v", trade)
+	}
+	if bids, asks, _ := engine.OpenOrderCount(pair); bids != 0 || asks != 0 {
+		t.Errorf("Expected an empty book, got %d bids and %d asks", bids, asks)
+	}
+}
+
+// TestReplaceOrderMissing tests that replacing an order that is not resting leaves the book untouched
+func TestReplaceOrderMissing(t *testing.T) {
+	engine := NewEngine()
+	pair := "BTC-USD"
+	if _, err := engine.ReplaceOrder(pair, "old", Order{ID: "new", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)}); err != ErrPairNotFound {
+		t.Errorf("Expected ErrPairNotFound, got %v", err)
+	}
+
+	engine.AddOrder(pair, Order{ID: "other", Side: Sell, Price: decimal.NewFromInt(105), Qty: decimal.NewFromInt(1)})
+	drainFills(engine)
+	if id, err := engine.ReplaceOrder(pair, "old", Order{ID: "new", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)}); err != ErrOrderNotFound || id != "" {
+		t.Errorf("Expected ErrOrderNotFound, got %q and %v", id, err)
+	}
+	if bids, _, _ := engine.OpenOrderCount(pair); bids != 0 {
+		t.Errorf("Expected the replacement not to be entered, got %d bids", bids)
+	}
+
+	engine.AddOrder(pair, Order{ID: "old", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	drainFills(engine)
+	if _, err := engine.ReplaceOrder(pair, "old", Order{ID: "new", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.Zero}); err != ErrOrderRejected {
+		t.Errorf("Expected ErrOrderRejected, got %v", err)
+	}
+	if bids, _, _ := engine.OpenOrderCount(pair); bids != 1 {
+		t.Errorf("Expected the old order to keep resting after a rejected replacement, got %d bids", bids)
+	}
+}
+
+// TestReplaceOrderMissingEntersNew tests that WithReplaceMissing enters the replacement anyway
+func TestReplaceOrderMissingEntersNew(t *testing.T) {
+	engine := NewEngine(WithReplaceMissing())
+	pair := "BTC-USD"
+	engine.AddOrder(pair, Order{ID: "other", Side: Sell, Price: decimal.NewFromInt(105), Qty: decimal.NewFromInt(1)})
+
+	id, err := engine.ReplaceOrder(pair, "old", Order{ID: "new", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	if err != ErrOrderNotFound || id != "new" {
+		t.Errorf("Expected the replacement to be entered with ErrOrderNotFound, got %q and %v", id, err)
+	}
+	if bids, _, _ := engine.OpenOrderCount(pair); bids != 1 {
+		t.Errorf("Expected the replacement to rest, got %d bids", bids)
+	}
+}
+
+// TestReplaceOrderOffTick tests that a replacement off the tick grid is rejected while one on it succeeds
+func TestReplaceOrderOffTick(t *testing.T) {
+	engine := NewEngine()
+	pair := "BTC-USD"
+	engine.SetPairConfig(pair, PairConfig{TickSize: decimal.NewFromFloat(0.5)})
+	engine.AddOrder(pair, Order{ID: "old", Side: Buy, Price: decimal.NewFromInt(99), Qty: decimal.NewFromInt(1)})
+	drainFills(engine)
+
+	if _, err := engine.ReplaceOrder(pair, "old", Order{Side: Buy, Price: decimal.NewFromFloat(99.3), Qty: decimal.NewFromInt(1)}); err != ErrOrderRejected {
+		t.Fatalf("Expected ErrOrderRejected, got %v", err)
+	}
+	if fill := <-engine.FillStream; fill.Status != Rejected || fill.Reason != RejectOffTick {
+		t.Errorf("Expected an off-tick rejection, got %+v", fill)
+	}
+	if bid, _, _, _, _ := engine.TopOfBook(pair); !bid.Equal(decimal.NewFromInt(99)) {
+		t.Errorf("Expected the old order to keep resting at 99, got %s", bid)
+	}
+
+	if _, err := engine.ReplaceOrder(pair, "old", Order{Side: Buy, Price: decimal.NewFromFloat(99.5), Qty: decimal.NewFromInt(1)}); err != nil {
+		t.Fatalf("Expected the on-tick replacement to succeed, got %v", err)
+	}
+	if bid, _, _, _, _ := engine.TopOfBook(pair); !bid.Equal(decimal.NewFromFloat(99.5)) {
+		t.Errorf("Expected the replacement to rest at 99.5, got %s", bid)
+	}
+}
@@ -0,0 +1,136 @@
+package engine
+
+import "github.com/shopspring/decimal"
+
+// EventType identifies the kind of a recorded order action passed to
+// ReplayEvents.
+type EventType string
+
+const (
+	// EventAdd enters Event.Order as AddOrder would.
+	EventAdd EventType = "add"
+	// EventCancel cancels the resting order Event.OrderID.
+	EventCancel EventType = "cancel"
+	// EventAmend amends the resting order Event.OrderID to Event.Price and
+	// Event.Qty as AmendOrder would.
+	EventAmend EventType = "amend"
+)
+
+// Event is a recorded order action for ReplayEvents. Type selects which of
+// the other fields apply.
+type Event struct {
+	Type    EventType       `json:"type"`               // Kind of action
+	Pair    string          `json:"pair"`               // Trading pair the action applies to
+	Order   Order           `json:"order"`              // Order entered by an EventAdd
+	OrderID string          `json:"order_id,omitempty"` // Order canceled or amended, by ID or client order ID
+	Price   decimal.Decimal `json:"price"`              // New price of an EventAmend
+	Qty     decimal.Decimal `json:"qty"`                // New quantity of an EventAmend
+}
+
+// ReplayEvents processes events in order and returns the trades they
+// produce, for backtesting against recorded order flow. Unlike Replay, which
+// rebuilds books from a Journal, it takes the events themselves. Each event
+// runs on its pair's shard and completes before the next one starts, so the
+// book evolves exactly as the events dictate whatever the number of shards. Orders are checked
+// against the pair's trading rules and matched as AddOrder would, and stops
+// the events release, breaker halts and resumptions, and uncrosses on resume
+// or after a crossed-book check follow as they would live. None of it is
+// journaled or published to the output streams, order and halt events
+// included; the trades, uncrosses' among them, are returned and recorded in
+// the trade statistics and history, and crossed books are reported to
+// OnCrossedBook listeners. A breaker halt still in force when the replay ends
+// is lifted and published by the first job after its cooldown, as any other. Actions that no longer apply, such as cancels of orders that
+// have since filled, are skipped, as are events of a pair with no book other
+// than adds.
+//
+// Run on a fresh engine created WithClock with a deterministic clock, the
+// same events always produce the same trades, down to their IDs and
+// timestamps.
+func (e *Engine) ReplayEvents(events []Event) []Trade {
+	var trades []Trade
+	for _, event := range events {
+		if event.Type == EventAdd {
+			e.assignID(&event.Order)
+		} else if _, exists := e.book(event.Pair); !exists {
+			continue
+		}
+		// A direct job runs nothing around the op, which takes care of
+		// everything a job is otherwise followed by without publishing.
+		err := e.submitDirect(event.Pair, func(book *OrderBook, _ *MatchResult) error {
+			if book == nil {
+				if event.Type != EventAdd {
+					return nil
+				}
+				book = e.getOrCreateBook(event.Pair)
+			}
+			var res MatchResult
+			e.replay(book, event, &res)
+			for _, trade := range res.Trades {
+				e.recordTrade(trade)
+			}
+			trades = append(trades, res.Trades...)
+			return nil
+		})
+		if err != nil {
+			break
+		}
+	}
+	return trades
+}
+
+// replay applies event to book, appending its outcome, with every order
+// event it causes, to res. Around it, it does what runShard does around a
+// job: a breaker halt whose cooldown has passed is lifted first, and
+// afterwards stops released by a reference price that moved without a trade
+// are matched, the breaker is checked and the cross check runs.
+func (e *Engine) replay(book *OrderBook, event Event, res *MatchResult) {
+	if _, ok := book.resume(true); ok && e.uncrossOnResume {
+		book.uncrossInto(res)
+		book.checkBreaker(res.Trades)
+	}
+	start := len(res.Trades)
+	e.replayAction(book, event, res)
+	book.flushEvents(res)
+	book.checkBreaker(res.Trades[start:])
+
+	start = len(res.Trades)
+	if book.followReference(res) {
+		book.checkBreaker(res.Trades[start:])
+	}
+	if e.reportCross(book) {
+		start = len(res.Trades)
+		book.uncrossInto(res)
+		book.checkBreaker(res.Trades[start:])
+	}
+}
+
+// replayAction carries out the action event records on book.
+func (e *Engine) replayAction(book *OrderBook, event Event, res *MatchResult) {
+	switch event.Type {
+	case EventAdd:
+		order, ok := e.admit(book, event.Order, res)
+		if !ok {
+			return
+		}
+		book.executeInto(order, res)
+	case EventCancel:
+		if id, ok := book.resolve(event.OrderID); ok {
+			book.Cancel(id)
+		}
+	case EventAmend:
+		order, ok := book.Order(event.OrderID)
+		if !ok || order.Type == TrailingStop || order.Type == MarketIfTouched || !event.Qty.IsPositive() {
+			return
+		}
+		by, replacement, replace := amendment(order, event.Price, event.Qty)
+		if !replace {
+			if by.IsPositive() && reduceRejected(book, order.ID, by) == "" {
+				book.Reduce(order.ID, by)
+			}
+			return
+		}
+		if replacement, ok = e.admitAmendment(book, replacement, res); ok {
+			book.replaceInto(order.ID, replacement, res)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// replayEvents is a fixed order flow on two pairs with adds, a cancel and both kinds of amendment
+var replayEvents = []Event{
+	{Type: EventAdd, Pair: "BTC-USD", Order: Order{ID: "s1", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(2)}},
+	{Type: EventAdd, Pair: "BTC-USD", Order: Order{ID: "s2", Side: Sell, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(3)}},
+	{Type: EventAdd, Pair: "BTC-USD", Order: Order{ID: "s3", Side: Sell, Price: decimal.NewFromInt(102), Qty: decimal.NewFromInt(1)}},
+	{Type: EventCancel, Pair: "BTC-USD", OrderID: "s2"},
+	{Type: EventAdd, Pair: "BTC-USD", Order: Order{ID: "b1", Side: Buy, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(3)}},
+	{Type: EventAmend, Pair: "BTC-USD", OrderID: "s3", Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(1)},
+	{Type: EventAdd, Pair: "ETH-USD", Order: Order{ID: "e1", Side: Buy, Price: decimal.NewFromInt(50), Qty: decimal.NewFromInt(4)}},
+	{Type: EventAmend, Pair: "ETH-USD", OrderID: "e1", Price: decimal.NewFromInt(50), Qty: decimal.NewFromInt(2)},
+	{Type: EventAdd, Pair: "ETH-USD", Order: Order{Side: Sell, Type: Market, Qty: decimal.NewFromInt(3)}},
+	{Type: EventCancel, Pair: "SOL-USD", OrderID: "missing"},
+}
+
+// TestReplayEventsDeterministic tests that replaying a fixed event list twice produces byte-identical trades
+func TestReplayEventsDeterministic(t *testing.T) {
+	replay := func() ([]Trade, []byte) {
+		engine := NewEngine(WithClock(newFakeClock(time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC))))
+		trades := engine.ReplayEvents(replayEvents)
+		out, err := json.Marshal(trades)
+		if err != nil {
+			t.Fatalf("Failed to marshal trades: %v", err)
+		}
+		if len(engine.TradeStream) != 0 || len(engine.FillStream) != 0 || len(engine.OrderEvents) != 0 {
+			t.Errorf("Expected nothing published, got %d trades, %d fills and %d order events",
+				len(engine.TradeStream), len(engine.FillStream), len(engine.OrderEvents))
+		}
+		if stats, _ := engine.GetTradeStats("BTC-USD"); stats.TradeCount != 2 {
+			t.Errorf("Expected the replayed trades in the statistics, got %+v", stats)
+		}
+		return trades, out
+	}
+
+	trades, first := replay()
+	_, second := replay()
+	if !bytes.Equal(first, second) {
+		t.Errorf("Expected identical output\nfirst  %s\nsecond %s", first, second)
+	}
+
+	expected := []struct {
+		pair, buy, sell string
+		qty, price      int64
+	}{
+		{"BTC-USD", "b1", "s1", 2, 100},
+		{"BTC-USD", "b1", "s3", 1, 101},
+		{"ETH-USD", "e1", "", 2, 50},
+	}
+	if len(trades) != len(expected) {
+		t.Fatalf("Expected %d trades, got %+v", len(expected), trades)
+	}
+	for i, ex := range expected {
+		trade := trades[i]
+		if trade.Pair != ex.pair || trade.BuyOrderID != ex.buy || (ex.sell != "" && trade.SellOrderID != ex.sell) ||
+			!trade.Qty.Equal(decimal.NewFromInt(ex.qty)) || !trade.Price.Equal(decimal.NewFromInt(ex.price)) {
+			t.Errorf("Expected trade %d to be %s %s/%s %d at %d, got %+v", i, ex.pair, ex.buy, ex.sell, ex.qty, ex.price, trade)
+		}
+	}
+	if trades[2].SellOrderID == "" {
+		t.Error("Expected the market order to be given an ID")
+	}
+}
+
+// TestReplayEventsCrossCheck tests that replaying onto a crossed book reports it once per event
+func TestReplayEventsCrossCheck(t *testing.T) {
+	engine := NewEngine(WithCrossCheck(false))
+	pair := "BTC-USD"
+	reports := 0
+	engine.OnCrossedBook(func(CrossedBook) { reports++ })
+	engine.getOrCreateBook(pair).LoadResting([]Order{
+		{ID: "bid", Side: Buy, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(1)},
+		{ID: "ask", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)},
+	})
+
+	engine.ReplayEvents([]Event{{Type: EventAdd, Pair: pair, Order: Order{ID: "far", Side: Buy, Price: decimal.NewFromInt(50), Qty: decimal.NewFromInt(1)}}})
+	if reports != 1 {
+		t.Errorf("Expected one report for one event, got %d", reports)
+	}
+	if n := engine.Metrics().CrossedBooks; n != 1 {
+		t.Errorf("Expected 1 crossed book counted, got %d", n)
+	}
+}
+
+// TestReplayEventsBreaker tests that a breaker tripping and resuming during a replay publishes nothing and returns the uncross trades
+func TestReplayEventsBreaker(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC))
+	engine := NewEngine(WithClock(clock), WithUncrossOnResume())
+	pair := "BTC-USD"
+	engine.SetPairConfig(pair, PairConfig{LULD: LULDConfig{
+		Percent:  decimal.NewFromInt(10),
+		Window:   time.Minute,
+		Cooldown: time.Minute,
+	}})
+	add := func(id string, side Side, price int64) Event {
+		return Event{Type: EventAdd, Pair: pair, Order: Order{ID: id, Side: side, Price: decimal.NewFromInt(price), Qty: decimal.NewFromInt(1)}}
+	}
+
+	engine.ReplayEvents([]Event{add("bid", Buy, 99), add("ask1", Sell, 100), add("buy1", Buy, 100), add("ask2", Sell, 115), add("buy2", Buy, 115)})
+	if !engine.IsHalted(pair) {
+		t.Fatal("Expected the replayed 15% move to trip the breaker")
+	}
+	engine.getOrCreateBook(pair).LoadResting([]Order{{ID: "ask", Side: Sell, Price: decimal.NewFromInt(98), Qty: decimal.NewFromInt(1)}})
+
+	clock.Advance(2 * time.Minute)
+	trades := engine.ReplayEvents([]Event{add("late", Buy, 50)})
+	if len(trades) != 1 || trades[0].BuyOrderID != "bid" || trades[0].SellOrderID != "ask" {
+		t.Errorf("Expected the resume to uncross bid and ask in the replay's trades, got %+v", trades)
+	}
+	if engine.IsHalted(pair) {
+		t.Error("Expected the replay to lift the halt once the cooldown passed")
+	}
+	if len(engine.HaltEvents) != 0 || len(engine.TradeStream) != 0 || len(engine.FillStream) != 0 || len(engine.OrderEvents) != 0 {
+		t.Errorf("Expected nothing published, got %d halt events, %d trades, %d fills and %d order events",
+			len(engine.HaltEvents), len(engine.TradeStream), len(engine.FillStream), len(engine.OrderEvents))
+	}
+}
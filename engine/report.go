@@ -0,0 +1,162 @@
+package engine
+
+import (
+	"slices"
+
+	"github.com/shopspring/decimal"
+)
+
+// defaultReportHistorySize is the number of finished orders whose execution
+// reports are retained per pair.
+const defaultReportHistorySize = 1000
+
+// Execution is a single fill of an order.
+type Execution struct {
+	Qty       decimal.Decimal `json:"qty"`       // Quantity executed
+	Price     decimal.Decimal `json:"price"`     // Price the quantity executed at
+	Fee       decimal.Decimal `json:"fee"`       // Fee charged for the execution, negative for a rebate
+	Timestamp int64           `json:"timestamp"` // Unix timestamp of the execution
+}
+
+// ExecReport is the consolidated state of an order over its life, built from
+// the fill events the engine published for it.
+type ExecReport struct {
+	OrderID       string          `json:"order_id"`                  // Unique identifier of the order
+	ClientOrderID string          `json:"client_order_id,omitempty"` // Client order ID of the order, if it had one
+	Pair          string          `json:"pair"`                      // Trading pair identifier
+	Side          Side            `json:"side"`                      // Direction of the order (Buy or Sell)
+	Account       string          `json:"account,omitempty"`         // Account that placed the order
+	Price         decimal.Decimal `json:"price"`                     // Order price
+	OriginalQty   decimal.Decimal `json:"original_qty"`              // Quantity when the order was placed
+	ExecutedQty   decimal.Decimal `json:"executed_qty"`              // Total quantity executed
+	AvgPrice      decimal.Decimal `json:"avg_price"`                 // Volume-weighted average price of the executions, zero before the first
+	RemainingQty  decimal.Decimal `json:"remaining_qty"`             // Quantity still open, zero once the order is finished
+	Status        FillStatus      `json:"status"`                    // Status after the latest fill event
+	Reason        RejectReason    `json:"reason,omitempty"`          // Reason of the latest fill event, if any
+	Executions    []Execution     `json:"executions"`                // Executions in the order they happened
+	UpdatedAt     int64           `json:"updated_at"`                // Unix timestamp of the latest fill event
+
+	value decimal.Decimal // Sum of price * quantity over the executions
+}
+
+// finished reports whether no further fills can follow for the order.
+func (r *ExecReport) finished() bool {
+	return r.Status == Filled || r.Status == Canceled || r.Status == Rejected
+}
+
+// apply folds fill into the report.
+func (r *ExecReport) apply(fill OrderFill) {
+	if fill.ExecutedQty.IsPositive() {
+		r.Executions = append(r.Executions, Execution{
+			Qty:       fill.ExecutedQty,
+			Price:     fill.FillPrice,
+			Fee:       fill.Fee,
+			Timestamp: fill.Timestamp,
+		})
+		r.ExecutedQty = r.ExecutedQty.Add(fill.ExecutedQty)
+		r.value = r.value.Add(fill.ExecutedQty.Mul(fill.FillPrice))
+		r.AvgPrice = r.value.Div(r.ExecutedQty)
+	}
+	r.RemainingQty = fill.RemainingQty
+	if fill.Status == Canceled || fill.Status == Rejected {
+		r.RemainingQty = decimal.Zero
+	}
+	r.Status = fill.Status
+	r.Reason = fill.Reason
+	r.UpdatedAt = fill.Timestamp
+}
+
+// orderReports holds the execution reports of one pair: every open order's
+// and those of the most recently finished orders. It is not safe for
+// concurrent use.
+type orderReports struct {
+	byID     map[string]*ExecReport
+	finished []*ExecReport // Finished reports, oldest first
+}
+
+// WithReportHistory sets how many finished orders per pair keep their
+// execution report for ExecutionReport. Reports of open orders are always
+// kept. A size of zero drops a report as soon as its order finishes.
+func WithReportHistory(size int) Option {
+	return func(e *Engine) {
+		if size >= 0 {
+			e.reportHistorySize = size
+		}
+	}
+}
+
+// recordReport folds a published fill into the execution report of its order.
+func (e *Engine) recordReport(fill OrderFill) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	reports := e.reports[fill.Pair]
+	if reports == nil {
+		reports = &orderReports{byID: make(map[string]*ExecReport)}
+		e.reports[fill.Pair] = reports
+	}
+
+	report := reports.byID[fill.OrderID]
+	if report == nil || (fill.Status == New && report.finished()) {
+		// The fill's OriginalQty is what was open before it, so adding back
+		// what had executed earlier recovers the quantity first placed.
+		report = &ExecReport{
+			OrderID:       fill.OrderID,
+			ClientOrderID: fill.ClientOrderID,
+			Pair:          fill.Pair,
+			Side:          fill.Side,
+			Account:       fill.Account,
+			Price:         fill.Price,
+			OriginalQty:   fill.OriginalQty.Add(fill.CumulativeQty).Sub(fill.ExecutedQty),
+		}
+		reports.byID[fill.OrderID] = report
+	}
+	wasFinished := report.finished()
+	report.apply(fill)
+
+	if report.finished() && !wasFinished {
+		reports.finished = append(reports.finished, report)
+		for len(reports.finished) > e.reportHistorySize {
+			oldest := reports.finished[0]
+			reports.finished = reports.finished[1:]
+			if reports.byID[oldest.OrderID] == oldest {
+				delete(reports.byID, oldest.OrderID)
+			}
+		}
+	}
+}
+
+// ExecutionReport returns the consolidated state of an order of the specified
+// trading pair: its original, executed and remaining quantity, average fill
+// price, current status and every execution so far. orderID may also be the
+// client order ID of an order still in the book. Reports of open orders are
+// always available; finished orders keep theirs within the limit set by
+// WithReportHistory.
+//
+// Returns ErrPairNotFound if the pair has no order book, or ErrOrderNotFound
+// if no report is held for the order.
+func (e *Engine) ExecutionReport(pair, orderID string) (ExecReport, error) {
+	book, exists := e.book(pair)
+	if !exists {
+		return ExecReport{}, ErrPairNotFound
+	}
+	if id, ok := book.resolve(orderID); ok {
+		orderID = id
+	}
+
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	reports := e.reports[pair]
+	if reports == nil {
+		return ExecReport{}, ErrOrderNotFound
+	}
+	report := reports.byID[orderID]
+	if report == nil {
+		return ExecReport{}, ErrOrderNotFound
+	}
+
+	out := *report
+	out.Executions = slices.Clone(report.Executions)
+	return out, nil
+}
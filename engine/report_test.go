@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// TestExecutionReport tests that an order filled in two trades reports its cumulative fields and executions
+func TestExecutionReport(t *testing.T) {
+	engine := NewEngine()
+	pair := "BTC-USD"
+	engine.AddOrder(pair, Order{ID: "sell1", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	engine.AddOrder(pair, Order{ID: "buy", Side: Buy, Price: decimal.NewFromInt(102), Qty: decimal.NewFromInt(4), ClientOrderID: "client-buy"})
+
+	report, err := engine.ExecutionReport(pair, "client-buy")
+	if err != nil {
+		t.Fatalf("Expected a report for the resting buy, got %v", err)
+	}
+	if report.Status != PartiallyFilled || !report.RemainingQty.Equal(decimal.NewFromInt(3)) || len(report.Executions) != 1 {
+		t.Errorf("Expected a partial fill with 3 remaining, got %+v", report)
+	}
+
+	engine.AddOrder(pair, Order{ID: "sell2", Side: Sell, Price: decimal.NewFromInt(98), Qty: decimal.NewFromInt(3)})
+	drainFills(engine)
+
+	report, err = engine.ExecutionReport(pair, "buy")
+	if err != nil {
+		t.Fatalf("Expected a report for the filled buy, got %v", err)
+	}
+	if report.Status != Filled {
+		t.Errorf("Expected status %s, got %s", Filled, report.Status)
+	}
+	if !report.OriginalQty.Equal(decimal.NewFromInt(4)) || !report.ExecutedQty.Equal(decimal.NewFromInt(4)) || !report.RemainingQty.IsZero() {
+		t.Errorf("Expected 4 of 4 executed with none remaining, got %+v", report)
+	}
+	if !report.AvgPrice.Equal(decimal.RequireFromString("101.5")) {
+		t.Errorf("Expected average price 101.5, got %s", report.AvgPrice)
+	}
+	expected := []struct{ qty, price int64 }{{1, 100}, {3, 102}}
+	if len(report.Executions) != len(expected) {
+		t.Fatalf("Expected %d executions, got %+v", len(expected), report.Executions)
+	}
+	for i, ex := range expected {
+		if got := report.Executions[i]; !got.Qty.Equal(decimal.NewFromInt(ex.qty)) || !got.Price.Equal(decimal.NewFromInt(ex.price)) {
+			t.Errorf("Expected execution %d of %d at %d, got %+v", i, ex.qty, ex.price, got)
+		}
+	}
+
+	if _, err := engine.ExecutionReport(pair, "missing"); !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("Expected ErrOrderNotFound, got %v", err)
+	}
+	if _, err := engine.ExecutionReport("ETH-USD", "buy"); !errors.Is(err, ErrPairNotFound) {
+		t.Errorf("Expected ErrPairNotFound, got %v", err)
+	}
+}
+
+// TestReportHistoryEvictsFinished tests that only the most recent finished orders keep their reports
+func TestReportHistoryEvictsFinished(t *testing.T) {
+	engine := NewEngine(WithReportHistory(1))
+	pair := "BTC-USD"
+	engine.AddOrder(pair, Order{ID: "rest", Side: Buy, Price: decimal.NewFromInt(90), Qty: decimal.NewFromInt(1)})
+	engine.AddOrder(pair, Order{ID: "ioc1", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1), TimeInForce: IOC})
+	engine.AddOrder(pair, Order{ID: "ioc2", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1), TimeInForce: IOC})
+	drainFills(engine)
+
+	if _, err := engine.ExecutionReport(pair, "ioc1"); !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("Expected the older finished report to be evicted, got %v", err)
+	}
+	if report, err := engine.ExecutionReport(pair, "ioc2"); err != nil || report.Status != Canceled {
+		t.Errorf("Expected the newest finished report to be canceled, got %+v, %v", report, err)
+	}
+	if report, err := engine.ExecutionReport(pair, "rest"); err != nil || report.Status != New {
+		t.Errorf("Expected the open order to keep its report, got %+v, %v", report, err)
+	}
+}
@@ -0,0 +1,187 @@
+package engine
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// rollingWindow is the span covered by Get24hStats.
+	rollingWindow = 24 * time.Hour
+
+	// rollingBucket is the granularity at which trades are aggregated for the
+	// rolling window; stale data expires one bucket at a time.
+	rollingBucket = time.Minute
+)
+
+// Stats24h holds rolling 24-hour ticker statistics for a trading pair.
+type Stats24h struct {
+	Pair          string          `json:"pair"`           // Trading pair identifier
+	Open          decimal.Decimal `json:"open"`           // Price 24 hours ago, or the first price in the window
+	High          decimal.Decimal `json:"high"`           // Highest trade price in the window
+	Low           decimal.Decimal `json:"low"`            // Lowest trade price in the window
+	Last          decimal.Decimal `json:"last"`           // Most recent trade price
+	Volume        decimal.Decimal `json:"volume"`         // Base quantity traded in the window
+	QuoteVolume   decimal.Decimal `json:"quote_volume"`   // Quote value traded in the window (qty * price)
+	Change        decimal.Decimal `json:"change"`         // Last minus Open
+	ChangePercent decimal.Decimal `json:"change_percent"` // Change as a percentage of Open
+	TradeCount    int64           `json:"trade_count"`    // Number of trades in the window
+	Timestamp     int64           `json:"timestamp"`      // Unix timestamp the stats were computed at
+}
+
+// statsBucket aggregates the trades that fell into a single rollingBucket.
+type statsBucket struct {
+	start       int64 // Unix timestamp of the bucket start
+	open        decimal.Decimal
+	high        decimal.Decimal
+	low         decimal.Decimal
+	close       decimal.Decimal
+	volume      decimal.Decimal
+	quoteVolume decimal.Decimal
+	count       int64
+}
+
+// rollingStats holds the bucketed trade history of one pair. Only buckets
+// containing trades are stored, so sparsely traded pairs stay small. It is not
+// safe for concurrent use.
+type rollingStats struct {
+	buckets     []statsBucket   // Buckets in ascending start order
+	baseline    decimal.Decimal // Close of the most recently expired bucket
+	hasBaseline bool            // Whether any bucket has expired yet
+}
+
+// add folds trade into the bucket covering its timestamp. Trades older than
+// the newest bucket are folded into it rather than reordering history.
+func (r *rollingStats) add(trade Trade) {
+	width := int64(rollingBucket / time.Second)
+	start := trade.Timestamp - trade.Timestamp%width
+	value := trade.Qty.Mul(trade.Price)
+
+	if n := len(r.buckets); n > 0 && r.buckets[n-1].start >= start {
+		b := &r.buckets[n-1]
+		if trade.Price.GreaterThan(b.high) {
+			b.high = trade.Price
+		}
+		if trade.Price.LessThan(b.low) {
+			b.low = trade.Price
+		}
+		b.close = trade.Price
+		b.volume = b.volume.Add(trade.Qty)
+		b.quoteVolume = b.quoteVolume.Add(value)
+		b.count++
+		return
+	}
+
+	r.buckets = append(r.buckets, statsBucket{
+		start:       start,
+		open:        trade.Price,
+		high:        trade.Price,
+		low:         trade.Price,
+		close:       trade.Price,
+		volume:      trade.Qty,
+		quoteVolume: value,
+		count:       1,
+	})
+}
+
+// expire drops buckets that ended at or before cutoff, remembering the last
+// dropped close as the reference price for the window.
+func (r *rollingStats) expire(cutoff int64) {
+	width := int64(rollingBucket / time.Second)
+	n := 0
+	for n < len(r.buckets) && r.buckets[n].start+width <= cutoff {
+		n++
+	}
+	if n == 0 {
+		return
+	}
+	r.baseline = r.buckets[n-1].close
+	r.hasBaseline = true
+	r.buckets = append(r.buckets[:0], r.buckets[n:]...)
+}
+
+// summary computes the rolling statistics as of now, expiring stale buckets.
+func (r *rollingStats) summary(pair string, now int64) Stats24h {
+	r.expire(now - int64(rollingWindow/time.Second))
+
+	stats := Stats24h{Pair: pair, Timestamp: now}
+	if r.hasBaseline {
+		stats.Open = r.baseline
+		stats.Last = r.baseline
+	}
+	if len(r.buckets) == 0 {
+		return stats
+	}
+
+	if !r.hasBaseline {
+		stats.Open = r.buckets[0].open
+	}
+	stats.High = r.buckets[0].high
+	stats.Low = r.buckets[0].low
+	for _, b := range r.buckets {
+		if b.high.GreaterThan(stats.High) {
+			stats.High = b.high
+		}
+		if b.low.LessThan(stats.Low) {
+			stats.Low = b.low
+		}
+		stats.Volume = stats.Volume.Add(b.volume)
+		stats.QuoteVolume = stats.QuoteVolume.Add(b.quoteVolume)
+		stats.TradeCount += b.count
+	}
+	stats.Last = r.buckets[len(r.buckets)-1].close
+	stats.Change = stats.Last.Sub(stats.Open)
+	if !stats.Open.IsZero() {
+		stats.ChangePercent = stats.Change.Div(stats.Open).Mul(decimal.NewFromInt(100))
+	}
+	return stats
+}
+
+// vwap returns the volume-weighted average price of the trades in buckets
+// that end after since, and false if there are none. Buckets are not
+// expired, so it is safe under a read lock.
+func (r *rollingStats) vwap(since int64) (decimal.Decimal, bool) {
+	width := int64(rollingBucket / time.Second)
+	volume, value := decimal.Zero, decimal.Zero
+	for i := len(r.buckets) - 1; i >= 0 && r.buckets[i].start+width > since; i-- {
+		volume = volume.Add(r.buckets[i].volume)
+		value = value.Add(r.buckets[i].quoteVolume)
+	}
+	if volume.IsZero() {
+		return decimal.Zero, false
+	}
+	return value.Div(volume), true
+}
+
+// avgPrice returns the volume-weighted average trade price of pair over the
+// window before now, falling back to the all-time average when window is
+// zero or holds no trades. It returns zero for a pair that never traded. The
+// caller must hold e.mutex, for reading at least.
+func (e *Engine) avgPrice(pair string, window time.Duration, now int64) decimal.Decimal {
+	if rolling := e.rollingStats[pair]; window > 0 && rolling != nil {
+		if avg, ok := rolling.vwap(now - int64(window/time.Second)); ok {
+			return avg
+		}
+	}
+	if stats := e.tradeStats[pair]; stats != nil && !stats.TotalQty.IsZero() {
+		return stats.TotalValue.Div(stats.TotalQty)
+	}
+	return decimal.Zero
+}
+
+// Get24hStats returns rolling 24-hour high, low, volume and price change for
+// the specified trading pair, aggregated in one-minute buckets. A pair with
+// less than 24 hours of history measures change from its first trade; a pair
+// with no trades in the window reports zero volume and the last known price.
+func (e *Engine) Get24hStats(pair string) Stats24h {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	now := e.clock.Now().Unix()
+	rolling := e.rollingStats[pair]
+	if rolling == nil {
+		return Stats24h{Pair: pair, Timestamp: now}
+	}
+	return rolling.summary(pair, now)
+}
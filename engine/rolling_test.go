@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// crossTrade crosses a single unit at price on the given pair
+func crossTrade(engine *Engine, pair, id string, price int64) {
+	engine.AddOrder(pair, Order{ID: id + "-sell", Side: Sell, Price: decimal.NewFromInt(price), Qty: decimal.NewFromInt(1)})
+	engine.AddOrder(pair, Order{ID: id + "-buy", Side: Buy, Price: decimal.NewFromInt(price), Qty: decimal.NewFromInt(1)})
+}
+
+// TestGet24hStats tests that the rolling window excludes trades older than 24 hours
+func TestGet24hStats(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	engine := NewEngine(WithClock(clock))
+	pair := "BTC-USD"
+
+	stats := engine.Get24hStats(pair)
+	if stats.TradeCount != 0 || !stats.Volume.IsZero() {
+		t.Errorf("Expected empty stats for unknown pair, got %+v", stats)
+	}
+
+	crossTrade(engine, pair, "t1", 200)
+	clock.Advance(time.Hour)
+	crossTrade(engine, pair, "t2", 100)
+
+	// Fresh pair: change is measured from the first trade
+	stats = engine.Get24hStats(pair)
+	if stats.TradeCount != 2 {
+		t.Errorf("Expected 2 trades, got %d", stats.TradeCount)
+	}
+	if !stats.Open.Equal(decimal.NewFromInt(200)) || !stats.Last.Equal(decimal.NewFromInt(100)) {
+		t.Errorf("Expected open 200 and last 100, got %s and %s", stats.Open, stats.Last)
+	}
+	if !stats.ChangePercent.Equal(decimal.NewFromInt(-50)) {
+		t.Errorf("Expected change of -50%%, got %s", stats.ChangePercent)
+	}
+
+	clock.Advance(22 * time.Hour)
+	crossTrade(engine, pair, "t3", 150)
+	clock.Advance(2*time.Hour + time.Minute)
+	crossTrade(engine, pair, "t4", 120)
+
+	// t1 and t2 are now more than 24 hours old
+	stats = engine.Get24hStats(pair)
+	if stats.TradeCount != 2 {
+		t.Errorf("Expected 2 trades in window, got %d", stats.TradeCount)
+	}
+	if !stats.High.Equal(decimal.NewFromInt(150)) || !stats.Low.Equal(decimal.NewFromInt(120)) {
+		t.Errorf("Expected high 150 and low 120, got %s and %s", stats.High, stats.Low)
+	}
+	if !stats.Volume.Equal(decimal.NewFromInt(2)) {
+		t.Errorf("Expected volume 2, got %s", stats.Volume)
+	}
+	if !stats.Open.Equal(decimal.NewFromInt(100)) {
+		t.Errorf("Expected open to be the last price before the window (100), got %s", stats.Open)
+	}
+	if !stats.Change.Equal(decimal.NewFromInt(20)) {
+		t.Errorf("Expected change 20, got %s", stats.Change)
+	}
+
+	// A sparse pair with nothing in the window keeps its last price
+	clock.Advance(48 * time.Hour)
+	stats = engine.Get24hStats(pair)
+	if stats.TradeCount != 0 || !stats.Volume.IsZero() {
+		t.Errorf("Expected empty window, got %+v", stats)
+	}
+	if !stats.Last.Equal(decimal.NewFromInt(120)) || !stats.Change.IsZero() {
+		t.Errorf("Expected last 120 with no change, got %s and %s", stats.Last, stats.Change)
+	}
+}
+
+// TestBroadcastRollingVWAP tests that the broadcast average tracks recent trades and falls back to the all-time average
+func TestBroadcastRollingVWAP(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	engine := NewEngine(WithClock(clock))
+	pair := "BTC-USD"
+
+	crossTrade(engine, pair, "old1", 100)
+	crossTrade(engine, pair, "old2", 100)
+	clock.Advance(10 * time.Minute)
+	crossTrade(engine, pair, "new", 130)
+
+	avg := func(window time.Duration) decimal.Decimal {
+		t.Helper()
+		for len(engine.PriceUpdates) > 0 {
+			<-engine.PriceUpdates
+		}
+		engine.broadcastPrices(window, nil, nil)
+		return (<-engine.PriceUpdates).AvgPrice
+	}
+
+	if got := avg(5 * time.Minute); !got.Equal(decimal.NewFromInt(130)) {
+		t.Errorf("Expected a 5 minute VWAP of 130, got %s", got)
+	}
+	if got := avg(0); !got.Equal(decimal.NewFromInt(110)) {
+		t.Errorf("Expected the all-time average of 110 without a window, got %s", got)
+	}
+	if got := avg(time.Hour); !got.Equal(decimal.NewFromInt(110)) {
+		t.Errorf("Expected an hour VWAP covering every trade to be 110, got %s", got)
+	}
+
+	clock.Advance(time.Hour)
+	if got := avg(5 * time.Minute); !got.Equal(decimal.NewFromInt(110)) {
+		t.Errorf("Expected an empty window to fall back to the all-time average of 110, got %s", got)
+	}
+}
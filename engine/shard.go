@@ -0,0 +1,281 @@
+package engine
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+)
+
+// defaultShardQueueSize is the number of pending orders each shard buffers
+// before AddOrder callers start to block.
+const defaultShardQueueSize = 1024
+
+// shard is a single matching worker. Every trading pair is assigned to exactly
+// one shard by hashing the pair, so orders for a pair are processed strictly in
+// submission order while different shards match their pairs concurrently.
+type shard struct {
+	jobs   chan *orderJob // Pending orders routed to this shard
+	result MatchResult    // Reusable event buffer, owned by the shard worker
+}
+
+// orderJob is a unit of work queued on a shard. The submitter blocks on done
+// until the shard has finished processing it. A job either matches order or,
+// when op is set, runs op against the pair's book; both append the events they
+// generate to the shard's result buffer. A direct job runs op alone, see
+// submitDirect.
+type orderJob struct {
+	pair   string
+	order  Order
+	op     func(book *OrderBook, res *MatchResult) error
+	direct bool
+	err    error
+	done   chan struct{}
+}
+
+// jobPool recycles order jobs together with their completion channels so that
+// order intake does not allocate a channel per order.
+var jobPool = sync.Pool{
+	New: func() interface{} { return &orderJob{done: make(chan struct{}, 1)} },
+}
+
+// Option configures optional engine behaviour at construction time.
+type Option func(*Engine)
+
+// WithShards sets the number of matching workers. Pairs are distributed across
+// shards by hash, so more shards allow more pairs to match concurrently.
+// Values below 1 are ignored.
+func WithShards(n int) Option {
+	return func(e *Engine) {
+		if n > 0 {
+			e.shardCount = n
+		}
+	}
+}
+
+// startShards creates the shard workers.
+func (e *Engine) startShards() {
+	e.shards = make([]*shard, e.shardCount)
+	for i := range e.shards {
+		s := &shard{jobs: make(chan *orderJob, defaultShardQueueSize)}
+		e.shards[i] = s
+		e.workers.Add(1)
+		go e.runShard(s)
+	}
+}
+
+// shardFor returns the shard that owns the given trading pair.
+func (e *Engine) shardFor(pair string) *shard {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(pair))
+	return e.shards[h.Sum32()%uint32(len(e.shards))]
+}
+
+// submit queues work for pair on its shard and waits for it to complete. With
+// a nil op the order is matched; otherwise op is run on the shard so that it is
+// serialized with every other operation on the pair.
+func (e *Engine) submit(pair string, order Order, op func(*OrderBook, *MatchResult) error) error {
+	return e.submitCtx(context.Background(), pair, order, op)
+}
+
+// submitCtx is like submit but gives up with the context's error if ctx is
+// done before the job has been queued. Once queued, the job always runs to
+// completion. Returns ErrEngineClosed once Close has been called.
+func (e *Engine) submitCtx(ctx context.Context, pair string, order Order, op func(*OrderBook, *MatchResult) error) error {
+	return e.enqueue(ctx, pair, order, op, false)
+}
+
+// submitDirect runs op on pair's shard like submit, but without anything a
+// job is otherwise wrapped in: the book is not created if missing, so op gets
+// nil instead, a due breaker halt is not lifted beforehand, and nothing is
+// published, followed up or checked afterwards unless op does it itself.
+func (e *Engine) submitDirect(pair string, op func(*OrderBook, *MatchResult) error) error {
+	return e.enqueue(context.Background(), pair, Order{}, op, true)
+}
+
+// enqueue queues a job on pair's shard and waits for it to complete.
+func (e *Engine) enqueue(ctx context.Context, pair string, order Order, op func(*OrderBook, *MatchResult) error, direct bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !e.enter() {
+		return ErrEngineClosed
+	}
+	defer e.inflight.Done()
+
+	job := jobPool.Get().(*orderJob)
+	job.pair = pair
+	job.order = order
+	job.op = op
+	job.direct = direct
+	select {
+	case e.shardFor(pair).jobs <- job:
+	case <-ctx.Done():
+		*job = orderJob{done: job.done}
+		jobPool.Put(job)
+		return ctx.Err()
+	}
+	<-job.done
+
+	err := job.err
+	*job = orderJob{done: job.done}
+	jobPool.Put(job)
+	return err
+}
+
+// runShard processes queued jobs for the pairs owned by s, one at a time.
+// Generated events are published before the submitter is released, so the
+// streams observe each order's events in submission order.
+func (e *Engine) runShard(s *shard) {
+	defer e.workers.Done()
+	for job := range s.jobs {
+		s.result.reset()
+		if job.direct {
+			book, _ := e.book(job.pair)
+			job.err = job.op(book, &s.result)
+			if book, exists := e.book(job.pair); exists {
+				bids, asks := book.OpenOrderCount()
+				e.collector.SetRestingOrders(job.pair, bids, asks)
+			}
+			job.done <- struct{}{}
+			continue
+		}
+
+		book := e.getOrCreateBook(job.pair)
+		if event, ok := book.resume(true); ok {
+			s.result.Halts = append(s.result.Halts, event)
+			// Publish the uncross on its own so the job's events and
+			// trades are settled separately.
+			e.uncrossResumed(book, &s.result)
+			e.publish(&s.result)
+			s.result.reset()
+		}
+		if job.op != nil {
+			job.err = job.op(book, &s.result)
+		} else {
+			e.processOrder(book, job.order, &s.result)
+		}
+		book.flushEvents(&s.result)
+		e.publish(&s.result)
+		e.followReference(book, &s.result)
+		e.checkCross(book, &s.result)
+		e.sampleSpread(book)
+		bids, asks := book.OpenOrderCount()
+		e.collector.SetRestingOrders(job.pair, bids, asks)
+		job.done <- struct{}{}
+	}
+}
+
+// processOrder rounds an incoming order to the pair's precision, journals it
+// and matches it against book. An order for a halted pair, over its account's
+// rate limit, that breaks the pair's trading rules or has an unusable
+// quantity or price, or that cannot be journaled is rejected without touching
+// the book. Trades are then checked against the pair's LULD breaker. It
+// reports whether the order was entered.
+func (e *Engine) processOrder(book *OrderBook, order Order, res *MatchResult) bool {
+	e.collector.IncOrders(book.Pair)
+	e.counts.orders.Add(1)
+	order, ok := e.admit(book, order, res)
+	if !ok {
+		return false
+	}
+	if err := e.journal.AppendOrder(book.Pair, order); err != nil {
+		res.Fills = append(res.Fills, rejectedFill(book.Pair, order, RejectJournalFailed, e.clock.Now().Unix()))
+		return false
+	}
+
+	book.executeInto(order, res)
+	e.settle(book, res)
+	return true
+}
+
+// admit runs the pre-trade hooks on order, rounds it to the pair's precision
+// and checks it may be entered into book. An order for a halted pair, over
+// its account's rate limit, refused by a pre-trade hook, that breaks the
+// pair's trading rules or that the book could never match gets a
+// Rejected fill in res and false is returned.
+func (e *Engine) admit(book *OrderBook, order Order, res *MatchResult) (Order, bool) {
+	if book.Halted() {
+		res.Fills = append(res.Fills, rejectedFill(book.Pair, order, RejectHalted, e.clock.Now().Unix()))
+		return order, false
+	}
+	if e.limiter != nil && !e.limiter.allow(order.Account, e.clock.Now()) {
+		res.Fills = append(res.Fills, rejectedFill(book.Pair, order, RejectRateLimited, e.clock.Now().Unix()))
+		return order, false
+	}
+	if err := e.preTrade.run(&order); err != nil {
+		res.Fills = append(res.Fills, rejectedFill(book.Pair, order, RejectPreTrade, e.clock.Now().Unix()))
+		return order, false
+	}
+	config := book.Config()
+	order = config.round(order)
+	reason := config.check(order)
+	if reason == "" {
+		reason = invalidOrder(order)
+	}
+	if reason != "" {
+		res.Fills = append(res.Fills, rejectedFill(book.Pair, order, reason, e.clock.Now().Unix()))
+		return order, false
+	}
+	return order, true
+}
+
+// admitAmendment is admit for an order that amends a resting one. Unlike a
+// new order, which is rounded onto the pair's tick and step grid, an
+// amendment off the grid is rejected, so amending cannot place an order the
+// trader did not ask for.
+func (e *Engine) admitAmendment(book *OrderBook, order Order, res *MatchResult) (Order, bool) {
+	if reason := book.Config().onGrid(order); reason != "" {
+		res.Fills = append(res.Fills, rejectedFill(book.Pair, order, reason, e.clock.Now().Unix()))
+		return order, false
+	}
+	return e.admit(book, order, res)
+}
+
+// settle checks the trades in res against the pair's LULD breaker, journals
+// them and runs the post-trade hooks.
+func (e *Engine) settle(book *OrderBook, res *MatchResult) {
+	if event, ok := book.checkBreaker(res.Trades); ok {
+		res.Halts = append(res.Halts, event)
+	}
+
+	// Trades are derived from the journaled orders and are reproduced by
+	// replay, so a failure to record one does not invalidate the match.
+	for _, trade := range res.Trades {
+		_ = e.journal.AppendTrade(trade)
+		e.postTrade.notify(trade)
+	}
+}
+
+// publish records and delivers the events in res to the output streams,
+// according to their backpressure policy, and to registered listeners.
+func (e *Engine) publish(res *MatchResult) {
+	e.selfTrades.Add(uint64(res.SelfTrades))
+	e.counts.trades.Add(int64(len(res.Trades)))
+	for _, trade := range res.Trades {
+		e.recordTrade(trade)
+		e.dropped(StreamTrades, deliver(e.TradeStream, trade, e.tradePolicy, &e.drops.trades, nil))
+		e.collector.IncTrades(trade.Pair)
+		e.tradeListeners.notify(trade)
+	}
+	for _, fill := range res.Fills {
+		e.fees.add(fill.Account, fill.Fee)
+		e.recordFill(fill)
+		e.recordReport(fill)
+		if fill.ExecutedQty.IsPositive() {
+			e.counts.fills.Add(1)
+		}
+		e.dropped(StreamFills, deliver(e.FillStream, fill, e.fillPolicy, &e.drops.fills, nil))
+		e.collector.IncFills(fill.Pair)
+		e.fillListeners.notify(fill)
+	}
+	for _, event := range res.Events {
+		// Dropped events show up to consumers as a sequence gap
+		e.dropped(StreamOrderEvents, deliver(e.OrderEvents, event, e.marketDataPolicy, &e.drops.events, nil))
+	}
+	for _, event := range res.Halts {
+		if event.Halted && event.ResumeAt != 0 {
+			e.scheduleResume(event.Pair, event.ResumeAt)
+		}
+		e.dropped(StreamHaltEvents, deliver(e.HaltEvents, event, e.marketDataPolicy, &e.drops.halts, nil))
+	}
+}
@@ -0,0 +1,103 @@
+package engine
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// defaultSpreadHistorySize is the number of spread samples retained per pair.
+const defaultSpreadHistorySize = 10000
+
+// spreadSample records the spread of a book from the moment its top of book
+// changed until the next sample.
+type spreadSample struct {
+	at       time.Time
+	spread   decimal.Decimal
+	twoSided bool // Whether both sides had orders; one-sided samples carry no spread
+}
+
+// WithSpreadHistory sets how many spread samples are retained per pair for
+// AverageSpread. A sample is taken whenever the best bid or best ask changes,
+// so busy pairs cover a shorter span with the same number of samples. A size
+// of zero disables sampling.
+func WithSpreadHistory(size int) Option {
+	return func(e *Engine) {
+		if size >= 0 {
+			e.spreadHistorySize = size
+		}
+	}
+}
+
+// sampleSpread records the spread of book if its best bid or best ask moved
+// since the last sample. The cached top-of-book pointers are only replaced
+// when a price changes, so comparing them is enough. It must only be called
+// from the shard that owns the book.
+func (e *Engine) sampleSpread(book *OrderBook) {
+	bid, ask := book.bestBid.Load(), book.bestAsk.Load()
+	if bid == book.sampledBid && ask == book.sampledAsk {
+		return
+	}
+	book.sampledBid, book.sampledAsk = bid, ask
+
+	sample := spreadSample{at: e.clock.Now()}
+	if bid != nil && ask != nil {
+		sample.spread = ask.Sub(*bid)
+		sample.twoSided = true
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if e.books[book.Pair] != book {
+		return // Removed by the job that just ran
+	}
+	history := e.spreads[book.Pair]
+	if history == nil {
+		history = newRing[spreadSample](e.spreadHistorySize)
+		e.spreads[book.Pair] = history
+	}
+	history.add(sample)
+}
+
+// AverageSpread returns the time-weighted average of the best ask minus the
+// best bid of the specified trading pair over the window before now. Each
+// spread counts for as long as it was in force; stretches when either side of
+// the book was empty are left out. A window that is not positive averages
+// over every retained sample. It returns zero if the pair had no two-sided
+// book within the window, and only the span covered by the samples retained
+// under WithSpreadHistory is considered.
+func (e *Engine) AverageSpread(pair string, window time.Duration) decimal.Decimal {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	history := e.spreads[pair]
+	if history == nil {
+		return decimal.Zero
+	}
+	now := e.clock.Now()
+	var since time.Time
+	if window > 0 {
+		since = now.Add(-window)
+	}
+
+	weighted, total := decimal.Zero, time.Duration(0)
+	end := now
+	for _, s := range history.recent(history.len()) {
+		start := s.at
+		if start.Before(since) {
+			start = since
+		}
+		if d := end.Sub(start); s.twoSided && d > 0 {
+			weighted = weighted.Add(s.spread.Mul(decimal.NewFromInt(int64(d))))
+			total += d
+		}
+		if !s.at.After(since) {
+			break
+		}
+		end = s.at
+	}
+	if total == 0 {
+		return decimal.Zero
+	}
+	return weighted.Div(decimal.NewFromInt(int64(total)))
+}
@@ -0,0 +1,54 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// TestAverageSpread tests that the spread is averaged over time within the window, skipping one-sided stretches
+func TestAverageSpread(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	engine := NewEngine(WithClock(clock))
+	pair := "BTC-USD"
+
+	if avg := engine.AverageSpread(pair, time.Minute); !avg.IsZero() {
+		t.Errorf("Expected zero for a pair without samples, got %s", avg)
+	}
+
+	// One-sided for 10s, then a spread of 4 for 10s and of 1 for 20s
+	engine.AddOrder(pair, Order{ID: "bid", Side: Buy, Price: decimal.NewFromInt(99), Qty: decimal.NewFromInt(1)})
+	clock.Advance(10 * time.Second)
+	engine.AddOrder(pair, Order{ID: "ask1", Side: Sell, Price: decimal.NewFromInt(103), Qty: decimal.NewFromInt(1)})
+	clock.Advance(10 * time.Second)
+	engine.AddOrder(pair, Order{ID: "ask2", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	clock.Advance(20 * time.Second)
+	drainFills(engine)
+
+	tests := []struct {
+		window   time.Duration
+		expected string
+	}{
+		{20 * time.Second, "1"},
+		{25 * time.Second, "1.6"},
+		{40 * time.Second, "2"},
+		{0, "2"},
+	}
+	for _, tt := range tests {
+		if avg := engine.AverageSpread(pair, tt.window); !avg.Equal(decimal.RequireFromString(tt.expected)) {
+			t.Errorf("Expected average spread %s over %v, got %s", tt.expected, tt.window, avg)
+		}
+	}
+
+	// Emptying the ask side stops the spread from counting
+	engine.CancelOrder(pair, "ask2")
+	engine.CancelOrder(pair, "ask1")
+	clock.Advance(40 * time.Second)
+	if avg := engine.AverageSpread(pair, 40*time.Second); !avg.IsZero() {
+		t.Errorf("Expected zero for a one-sided window, got %s", avg)
+	}
+	if avg := engine.AverageSpread(pair, 80*time.Second); !avg.Equal(decimal.NewFromInt(2)) {
+		t.Errorf("Expected the one-sided stretch to be skipped, got %s", avg)
+	}
+}
@@ -0,0 +1,190 @@
+package engine
+
+import "github.com/shopspring/decimal"
+
+// trailingStop is a pending TrailingStop or MarketIfTouched order held off the
+// book.
+type trailingStop struct {
+	order  Order           // The pending order; order.StopPrice is the current trigger level
+	anchor decimal.Decimal // Most favorable reference price seen, zero until the first
+}
+
+// hundred is the divisor used for percentage offsets.
+var hundred = decimal.NewFromInt(100)
+
+// trail moves the anchor of s in the favorable direction given a reference
+// price and recomputes the stop level. It reports whether price has reversed
+// through the stop level, which triggers the order.
+func (s *trailingStop) trail(price decimal.Decimal) bool {
+	if s.anchor.IsZero() {
+		s.anchor = price
+	} else if s.order.Side == Sell && price.GreaterThan(s.anchor) {
+		s.anchor = price
+	} else if s.order.Side == Buy && price.LessThan(s.anchor) {
+		s.anchor = price
+	}
+
+	offset := s.order.TrailOffset
+	if s.order.TrailPercent {
+		offset = s.anchor.Mul(offset).Div(hundred)
+	}
+	if s.order.Side == Sell {
+		s.order.StopPrice = s.anchor.Sub(offset)
+		return price.LessThanOrEqual(s.order.StopPrice) && !price.Equal(s.anchor)
+	}
+	s.order.StopPrice = s.anchor.Add(offset)
+	return price.GreaterThanOrEqual(s.order.StopPrice) && !price.Equal(s.anchor)
+}
+
+// fires feeds a reference price to s and reports whether it triggers the order.
+// A MarketIfTouched order fires once price reaches its trigger in the
+// favorable direction, the inverse of a stop; a TrailingStop trails price.
+func (s *trailingStop) fires(price decimal.Decimal) bool {
+	if s.order.Type != MarketIfTouched {
+		return s.trail(price)
+	}
+	if s.order.Side == Buy {
+		return price.LessThanOrEqual(s.order.TriggerPrice)
+	}
+	return price.GreaterThanOrEqual(s.order.TriggerPrice)
+}
+
+// restoreAnchor recovers the anchor of s from a previously computed stop
+// level, so that a stop restored from a snapshot keeps trailing where it left
+// off.
+func (s *trailingStop) restoreAnchor() {
+	stop := s.order.StopPrice
+	if stop.IsZero() {
+		return
+	}
+
+	if s.order.TrailPercent {
+		ratio := hundred.Sub(s.order.TrailOffset)
+		if s.order.Side == Buy {
+			ratio = hundred.Add(s.order.TrailOffset)
+		}
+		if !ratio.IsZero() {
+			s.anchor = stop.Mul(hundred).Div(ratio)
+		}
+		return
+	}
+	if s.order.Side == Sell {
+		s.anchor = stop.Add(s.order.TrailOffset)
+	} else {
+		s.anchor = stop.Sub(s.order.TrailOffset)
+	}
+}
+
+// addStop holds a TrailingStop or MarketIfTouched order off the book. A
+// trailing stop is anchored at the current reference price, or at the next
+// one if the book has none yet, while a market-if-touched order waits for the
+// next move of the reference price to reach its trigger. The caller must hold
+// ob.mutex.
+func (ob *OrderBook) addStop(order Order) {
+	s := &trailingStop{order: order}
+	if price, ok := ob.referencePrice(); order.Type == TrailingStop && ok {
+		s.trail(price)
+	}
+	ob.stops = append(ob.stops, s)
+}
+
+// trailStops feeds the moves of the reference price since the trades in res
+// from index start onwards were appended to the pending orders in order,
+// then releases every triggered order into the book with a Triggered event.
+// A trailing stop is released as a Limit order at its price, or at its stop
+// level if it has no price, and a market-if-touched order as a Market order.
+// Moves caused by a released order are fed back in turn, so triggers can
+// cascade. The caller must hold ob.mutex.
+func (ob *OrderBook) trailStops(res *MatchResult, start int) {
+	if len(res.Trades) > start {
+		ob.lastPrice = res.Trades[len(res.Trades)-1].Price
+	}
+	if len(ob.stops) == 0 {
+		return
+	}
+	prices := ob.referenceMoves(res, start)
+	if len(prices) == 0 {
+		return
+	}
+
+	var triggered []Order
+	pending := ob.stops[:0]
+	for _, s := range ob.stops {
+		fired := false
+		for _, price := range prices {
+			if s.fires(price) {
+				fired = true
+				break
+			}
+		}
+		if fired {
+			triggered = append(triggered, s.order)
+		} else {
+			pending = append(pending, s)
+		}
+	}
+	clear(ob.stops[len(pending):])
+	ob.stops = pending
+
+	for _, order := range triggered {
+		ob.emit(Triggered, order)
+		if order.Type == MarketIfTouched {
+			order.Type = Market
+		} else {
+			order.Type = Limit
+			if order.Price.IsZero() {
+				order.Price = order.StopPrice
+			}
+		}
+		ob.execute(order, false, res)
+	}
+}
+
+// cancelStop removes a pending trailing stop or market-if-touched order by
+// ID. The caller must hold ob.mutex.
+func (ob *OrderBook) cancelStop(orderID string) (Order, bool) {
+	for i, s := range ob.stops {
+		if s.order.ID == orderID {
+			ob.stops = append(ob.stops[:i], ob.stops[i+1:]...)
+			return s.order, true
+		}
+	}
+	return Order{}, false
+}
+
+// drainStops removes the pending trailing stops on the given side, or on both
+// sides if side is nil, appending copies of them to into. The caller must hold
+// ob.mutex.
+func (ob *OrderBook) drainStops(side *Side, into []Order) []Order {
+	pending := ob.stops[:0]
+	for _, s := range ob.stops {
+		if side == nil || s.order.Side == *side {
+			into = append(into, s.order)
+		} else {
+			pending = append(pending, s)
+		}
+	}
+	clear(ob.stops[len(pending):])
+	ob.stops = pending
+	return into
+}
+
+// PendingStops returns copies of the trailing stop and market-if-touched
+// orders waiting to trigger, in arrival order. StopPrice holds each trailing
+// stop's current trigger level.
+func (ob *OrderBook) PendingStops() []Order {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	return ob.stopOrders()
+}
+
+// stopOrders returns copies of the pending trailing stops. The caller must
+// hold ob.mutex.
+func (ob *OrderBook) stopOrders() []Order {
+	orders := make([]Order, 0, len(ob.stops))
+	for _, s := range ob.stops {
+		orders = append(orders, s.order)
+	}
+	return orders
+}
@@ -0,0 +1,219 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// crossBook trades one unit at price by resting a sell and lifting it with a buy
+func crossBook(ob *OrderBook, price int64) MatchResult {
+	ob.Execute(Order{ID: "maker", Side: Sell, Price: decimal.NewFromInt(price), Qty: decimal.NewFromInt(1)})
+	return ob.Execute(Order{ID: "taker", Side: Buy, Price: decimal.NewFromInt(price), Qty: decimal.NewFromInt(1)})
+}
+
+// TestTrailingStopRatchets tests that a trailing sell stop follows rising prices and fires on a drop
+func TestTrailingStopRatchets(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	crossBook(ob, 100)
+
+	res := ob.Execute(Order{ID: "stop", Side: Sell, Price: decimal.NewFromInt(90), Qty: decimal.NewFromInt(1), Type: TrailingStop, TrailOffset: decimal.NewFromInt(5)})
+	if len(res.Trades) != 0 || len(res.Fills) != 1 || res.Fills[0].Status != New {
+		t.Fatalf("Expected stop to be accepted without trading, got %+v", res)
+	}
+
+	stops := ob.PendingStops()
+	if len(stops) != 1 || !stops[0].StopPrice.Equal(decimal.NewFromInt(95)) {
+		t.Fatalf("Expected stop anchored at last price with level 95, got %+v", stops)
+	}
+	if bids, asks := ob.OpenOrderCount(); bids != 0 || asks != 0 {
+		t.Errorf("Expected pending stop to stay off the book, got %d bids and %d asks", bids, asks)
+	}
+
+	crossBook(ob, 110)
+	if stop := ob.PendingStops()[0].StopPrice; !stop.Equal(decimal.NewFromInt(105)) {
+		t.Errorf("Expected stop to ratchet up to 105, got %s", stop)
+	}
+
+	crossBook(ob, 108)
+	if stop := ob.PendingStops()[0].StopPrice; !stop.Equal(decimal.NewFromInt(105)) {
+		t.Errorf("Expected stop to hold at 105 on a small dip, got %s", stop)
+	}
+
+	// Two bids at 104: the first absorbs the trade that triggers the stop,
+	// the second is hit by the released stop.
+	ob.Execute(Order{ID: "bid", Side: Buy, Price: decimal.NewFromInt(104), Qty: decimal.NewFromInt(2)})
+	res = ob.Execute(Order{ID: "seller", Side: Sell, Price: decimal.NewFromInt(104), Qty: decimal.NewFromInt(1)})
+
+	if len(ob.PendingStops()) != 0 {
+		t.Error("Expected stop to be released after the reversal")
+	}
+	if len(res.Trades) != 2 {
+		t.Fatalf("Expected the triggering trade and the stop's trade, got %d trades", len(res.Trades))
+	}
+	if res.Trades[1].SellOrderID != "stop" || !res.Trades[1].Price.Equal(decimal.NewFromInt(104)) {
+		t.Errorf("Expected stop to sell at 104, got %+v", res.Trades[1])
+	}
+}
+
+// TestTrailingStopPercent tests a percentage trailing buy stop
+func TestTrailingStopPercent(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+
+	ob.Execute(Order{ID: "stop", Side: Buy, Qty: decimal.NewFromInt(1), Type: TrailingStop, TrailOffset: decimal.NewFromInt(10), TrailPercent: true})
+	if stop := ob.PendingStops()[0].StopPrice; !stop.IsZero() {
+		t.Errorf("Expected no stop level before the first trade, got %s", stop)
+	}
+
+	crossBook(ob, 100)
+	if stop := ob.PendingStops()[0].StopPrice; !stop.Equal(decimal.NewFromInt(110)) {
+		t.Errorf("Expected stop anchored at first trade with level 110, got %s", stop)
+	}
+
+	crossBook(ob, 80)
+	if stop := ob.PendingStops()[0].StopPrice; !stop.Equal(decimal.NewFromInt(88)) {
+		t.Errorf("Expected stop to ratchet down to 88, got %s", stop)
+	}
+
+	// Without a limit price the stop is released at its stop level
+	crossBook(ob, 90)
+	if len(ob.PendingStops()) != 0 {
+		t.Fatal("Expected stop to be released after the reversal")
+	}
+	bid, _, bidQty, _ := ob.TopOfBook()
+	if !bid.Equal(decimal.NewFromInt(88)) || !bidQty.Equal(decimal.NewFromInt(1)) {
+		t.Errorf("Expected released stop to rest as a bid at 88, got %s x %s", bid, bidQty)
+	}
+}
+
+// TestCancelTrailingStop tests that a pending stop can be canceled and is covered by snapshots
+func TestCancelTrailingStop(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	crossBook(ob, 100)
+	ob.Execute(Order{ID: "stop", Side: Sell, Qty: decimal.NewFromInt(1), Type: TrailingStop, TrailOffset: decimal.NewFromInt(5)})
+	crossBook(ob, 120)
+
+	restored := NewOrderBook("BTC-USD")
+	restored.Restore(ob.Snapshot())
+	crossBook(restored, 130)
+	if stop := restored.PendingStops()[0].StopPrice; !stop.Equal(decimal.NewFromInt(125)) {
+		t.Errorf("Expected restored stop to keep trailing to 125, got %s", stop)
+	}
+
+	order, ok := ob.Cancel("stop")
+	if !ok || order.ID != "stop" {
+		t.Fatalf("Expected pending stop to be canceled, got %+v", order)
+	}
+	if len(ob.PendingStops()) != 0 {
+		t.Error("Expected no pending stops after cancel")
+	}
+}
+
+// TestMarketIfTouched tests that a buy MIT fires when the price drops to its trigger and executes at market
+func TestMarketIfTouched(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	ob.recordEvents = true
+	crossBook(ob, 100)
+
+	res := ob.Execute(Order{ID: "mit", Side: Buy, Qty: decimal.NewFromInt(2), Type: MarketIfTouched, TriggerPrice: decimal.NewFromInt(95)})
+	if len(res.Trades) != 0 || len(res.Fills) != 1 || res.Fills[0].Status != New {
+		t.Fatalf("Expected MIT to be accepted without trading, got %+v", res)
+	}
+
+	// A rise moves away from the trigger and leaves the order pending
+	crossBook(ob, 105)
+	if len(ob.PendingStops()) != 1 {
+		t.Fatal("Expected MIT to stay pending while the price rises")
+	}
+
+	// Asks above the trigger price: a market order takes them regardless
+	ob.Execute(Order{ID: "ask1", Side: Sell, Price: decimal.NewFromInt(97), Qty: decimal.NewFromInt(1)})
+	ob.Execute(Order{ID: "ask2", Side: Sell, Price: decimal.NewFromInt(99), Qty: decimal.NewFromInt(3)})
+	ob.Execute(Order{ID: "bid", Side: Buy, Price: decimal.NewFromInt(95), Qty: decimal.NewFromInt(1)})
+	res = ob.Execute(Order{ID: "seller", Side: Sell, Price: decimal.NewFromInt(95), Qty: decimal.NewFromInt(1)})
+
+	if len(ob.PendingStops()) != 0 {
+		t.Error("Expected MIT to be released when the price touched 95")
+	}
+	if len(res.Trades) != 3 {
+		t.Fatalf("Expected the triggering trade and two MIT trades, got %+v", res.Trades)
+	}
+	if res.Trades[1].BuyOrderID != "mit" || !res.Trades[1].Price.Equal(decimal.NewFromInt(97)) ||
+		!res.Trades[2].Price.Equal(decimal.NewFromInt(99)) || !res.Trades[2].Qty.Equal(decimal.NewFromInt(1)) {
+		t.Errorf("Expected MIT to buy 1 at 97 and 1 at 99, got %+v", res.Trades[1:])
+	}
+
+	triggered := false
+	for _, event := range res.Events {
+		triggered = triggered || (event.Type == Triggered && event.Order.ID == "mit")
+	}
+	if !triggered {
+		t.Error("Expected a Triggered event for the MIT order")
+	}
+}
+
+// TestMarketOrderRemainderCanceled tests that a market order never rests
+func TestMarketOrderRemainderCanceled(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	ob.Execute(Order{ID: "ask", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+
+	res := ob.Execute(Order{ID: "market", Side: Buy, Qty: decimal.NewFromInt(3), Type: Market})
+	if len(res.Trades) != 1 || !res.Trades[0].Price.Equal(decimal.NewFromInt(100)) {
+		t.Fatalf("Expected one trade at 100, got %+v", res.Trades)
+	}
+	last := res.Fills[len(res.Fills)-1]
+	if last.Status != Canceled || !last.OriginalQty.Equal(decimal.NewFromInt(2)) {
+		t.Errorf("Expected the remaining 2 to be canceled, got %+v", last)
+	}
+	if bids, _ := ob.OpenOrderCount(); bids != 0 {
+		t.Errorf("Expected nothing to rest, got %d bids", bids)
+	}
+}
+
+// TestMarketOrderLiquidityExhausted tests that a market order larger than the book fills what exists and cancels the rest with a reason
+func TestMarketOrderLiquidityExhausted(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	ob.Execute(Order{ID: "ask1", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.RequireFromString("0.4")})
+	ob.Execute(Order{ID: "ask2", Side: Sell, Price: decimal.NewFromInt(101), Qty: decimal.RequireFromString("0.2")})
+
+	res := ob.Execute(Order{ID: "market", Side: Buy, Qty: decimal.RequireFromString("2.0"), Type: Market})
+	filled := decimal.Zero
+	for _, trade := range res.Trades {
+		filled = filled.Add(trade.Qty)
+	}
+	if !filled.Equal(decimal.RequireFromString("0.6")) {
+		t.Errorf("Expected 0.6 to fill, got %s", filled)
+	}
+	last, _ := lastFill(res.Fills, "market")
+	if last.Status != Canceled || !last.OriginalQty.Equal(decimal.RequireFromString("1.4")) || last.Reason != LiquidityExhausted {
+		t.Errorf("Expected the remaining 1.4 to be canceled for exhausted liquidity, got %+v", last)
+	}
+	if bids, asks := ob.OpenOrderCount(); bids != 0 || asks != 0 {
+		t.Errorf("Expected the remainder not to rest, got %d bids and %d asks", bids, asks)
+	}
+}
+
+// TestMarketableLimitRestsRemainder tests the partial-fill-then-rest sequence of a limit order that sweeps the book
+func TestMarketableLimitRestsRemainder(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	ob.Execute(Order{ID: "ask", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.RequireFromString("0.6")})
+	ob.recordEvents = true
+
+	res := ob.Execute(Order{ID: "limit", Side: Buy, Price: decimal.NewFromInt(105), Qty: decimal.RequireFromString("2.0")})
+	var fills []OrderFill
+	for _, fill := range res.Fills {
+		if fill.OrderID == "limit" {
+			fills = append(fills, fill)
+		}
+	}
+	if len(fills) != 2 || fills[0].Status != New || fills[1].Status != PartiallyFilled || !fills[1].RemainingQty.Equal(decimal.RequireFromString("1.4")) {
+		t.Fatalf("Expected New then PartiallyFilled with 1.4 remaining, got %+v", fills)
+	}
+	if fills[1].Reason != "" {
+		t.Errorf("Expected no reason on a limit order's partial fill, got %s", fills[1].Reason)
+	}
+	added := res.Events[len(res.Events)-1]
+	if added.Type != Added || added.Order.ID != "limit" || !added.Order.Price.Equal(decimal.NewFromInt(105)) || !added.Order.Qty.Equal(decimal.RequireFromString("1.4")) {
+		t.Errorf("Expected the remainder to rest at 105, got %+v", added)
+	}
+}
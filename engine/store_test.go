@@ -0,0 +1,137 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// seedEngine adds a few crossing and resting orders on two pairs
+func seedEngine(engine *Engine) {
+	for _, pair := range []string{"BTC-USD", "ETH-USD"} {
+		engine.AddOrder(pair, Order{ID: pair + "-s1", Side: Sell, Price: decimal.NewFromInt(101), Qty: decimal.NewFromFloat(2.0)})
+		engine.AddOrder(pair, Order{ID: pair + "-s2", Side: Sell, Price: decimal.NewFromInt(102), Qty: decimal.NewFromFloat(1.5)})
+		engine.AddOrder(pair, Order{ID: pair + "-b1", Side: Buy, Price: decimal.NewFromInt(101), Qty: decimal.NewFromFloat(0.5)})
+		engine.AddOrder(pair, Order{ID: pair + "-b2", Side: Buy, Price: decimal.NewFromInt(99), Qty: decimal.NewFromFloat(3.0)})
+		engine.AddOrder(pair, Order{ID: pair + "-b3", Side: Buy, Price: decimal.NewFromInt(98), Qty: decimal.NewFromFloat(1.0)})
+	}
+}
+
+// TestSnapshotRecoverRoundTrip tests that recovering from saved snapshots reproduces the books
+func TestSnapshotRecoverRoundTrip(t *testing.T) {
+	original := NewEngine()
+	seedEngine(original)
+
+	store := NewMemoryStore()
+	for _, snapshot := range original.Snapshot() {
+		if err := store.SaveSnapshot(snapshot.Pair, snapshot); err != nil {
+			t.Fatalf("Failed to save snapshot: %v", err)
+		}
+	}
+
+	recovered := NewEngine()
+	if err := recovered.Recover(store); err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+
+	for _, pair := range []string{"BTC-USD", "ETH-USD"} {
+		want := original.GetOrderBookDepth(pair, 10)
+		got := recovered.GetOrderBookDepth(pair, 10)
+		if got == nil {
+			t.Fatalf("Expected recovered book for %s", pair)
+		}
+		if fmt.Sprint(want.Bids) != fmt.Sprint(got.Bids) || fmt.Sprint(want.Asks) != fmt.Sprint(got.Asks) {
+			t.Errorf("Depth mismatch for %s: want %v/%v, got %v/%v", pair, want.Bids, want.Asks, got.Bids, got.Asks)
+		}
+		if want.TradeCount != got.TradeCount {
+			t.Errorf("Expected trade count %d for %s, got %d", want.TradeCount, pair, got.TradeCount)
+		}
+	}
+
+	// The recovered book keeps matching against the restored orders
+	recovered.AddOrder("BTC-USD", Order{ID: "b4", Side: Buy, Price: decimal.NewFromInt(102), Qty: decimal.NewFromFloat(3.0)})
+	if ask := recovered.getOrCreateBook("BTC-USD").BestAsk(); ask != 0 {
+		t.Errorf("Expected the restored asks to be consumed, best ask %f", ask)
+	}
+}
+
+// TestSnapshotEncodeDecode tests that a snapshot survives the binary encoding with every decimal exact
+func TestSnapshotEncodeDecode(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	ob.Execute(Order{ID: "s1", Side: Sell, Price: decimal.RequireFromString("101.123456789012345678"), Qty: decimal.RequireFromString("0.00000001")})
+	ob.Execute(Order{ID: "b1", Side: Buy, Price: decimal.RequireFromString("99.50"), Qty: decimal.RequireFromString("12345678901234567890.5"), Account: "alice"})
+	ob.Execute(Order{ID: "b2", Side: Buy, Price: decimal.RequireFromString("101.123456789012345678"), Qty: decimal.RequireFromString("0.000000005")})
+	ob.Execute(Order{ID: "stop", Side: Sell, Qty: decimal.NewFromInt(1), Type: TrailingStop, TrailOffset: decimal.RequireFromString("2.125")})
+	snapshot := ob.Snapshot()
+
+	var buf bytes.Buffer
+	if err := snapshot.Encode(&buf); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	decoded := BookSnapshot{Pair: "stale", Bids: []Order{{ID: "stale"}}}
+	if err := decoded.Decode(&buf); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	// The JSON encoding spells out every decimal, so equal JSON means exact values
+	want, _ := json.Marshal(snapshot)
+	got, _ := json.Marshal(decoded)
+	if !bytes.Equal(want, got) {
+		t.Errorf("Expected the decoded snapshot to match\nwant %s\ngot  %s", want, got)
+	}
+	if len(decoded.Stops) != 1 || decoded.TradeSeq != snapshot.TradeSeq || decoded.Timestamp != snapshot.Timestamp {
+		t.Errorf("Expected stops, trade sequence and timestamp to survive, got %+v", decoded)
+	}
+
+	restored := NewOrderBook("BTC-USD")
+	restored.Restore(decoded)
+	if bids, asks := restored.RestingVolume(); !bids.Equal(decimal.RequireFromString("12345678901234567890.5")) || !asks.Equal(decimal.RequireFromString("0.000000005")) {
+		t.Errorf("Expected the restored book to hold the exact volumes, got %s/%s", bids, asks)
+	}
+
+	if err := decoded.Decode(bytes.NewReader([]byte("garbage"))); err == nil {
+		t.Error("Expected an error decoding garbage")
+	}
+}
+
+// TestStartSnapshotter tests that the snapshotter periodically saves every book
+func TestStartSnapshotter(t *testing.T) {
+	engine := NewEngine()
+	seedEngine(engine)
+
+	store := NewMemoryStore()
+	engine.StartSnapshotter(store, 10*time.Millisecond)
+
+	deadline := time.After(500 * time.Millisecond)
+	for {
+		pairs, _ := store.ListPairs()
+		if len(pairs) == 2 {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("Expected 2 snapshots, got %v", pairs)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	snapshot, err := store.LoadSnapshot("BTC-USD")
+	if err != nil {
+		t.Fatalf("Failed to load snapshot: %v", err)
+	}
+	if len(snapshot.Bids) != 2 || len(snapshot.Asks) != 2 {
+		t.Errorf("Expected 2 bids and 2 asks, got %d and %d", len(snapshot.Bids), len(snapshot.Asks))
+	}
+	if !snapshot.Bids[0].Price.Equal(decimal.NewFromInt(99)) {
+		t.Errorf("Expected best bid first in snapshot, got %s", snapshot.Bids[0].Price.String())
+	}
+
+	if _, err := store.LoadSnapshot("XRP-USD"); !errors.Is(err, ErrPairNotFound) {
+		t.Errorf("Expected ErrPairNotFound for unknown pair, got %v", err)
+	}
+}
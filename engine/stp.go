@@ -0,0 +1,99 @@
+package engine
+
+import "container/heap"
+
+// STPMode selects what happens when an incoming order would trade against a
+// resting order of the same account. Orders without an Account never
+// self-trade.
+type STPMode string
+
+const (
+	// STPNone lets orders of the same account trade with each other. It is
+	// also the behaviour when no mode is configured.
+	STPNone STPMode = "none"
+	// STPCancelResting cancels the resting order and lets the incoming order
+	// carry on matching.
+	STPCancelResting STPMode = "cancel_resting"
+	// STPCancelIncoming cancels the remainder of the incoming order, leaving
+	// the resting order untouched.
+	STPCancelIncoming STPMode = "cancel_incoming"
+	// STPCancelBoth cancels the resting order and the remainder of the
+	// incoming order.
+	STPCancelBoth STPMode = "cancel_both"
+	// STPDecrementBoth reduces both orders by the smaller of their remaining
+	// quantities without a trade; the smaller one is canceled and the
+	// incoming order carries on matching with what is left of it.
+	STPDecrementBoth STPMode = "decrement_both"
+)
+
+// WithSelfTradePrevention makes every order book of the engine prevent
+// self-trades with mode, unless the pair's PairConfig sets its own STP mode.
+func WithSelfTradePrevention(mode STPMode) Option {
+	return func(e *Engine) {
+		e.stp = mode
+	}
+}
+
+// stpMode returns the self-trade prevention mode in force: the pair config's
+// if set and the book's default otherwise. The caller must hold ob.mutex.
+func (ob *OrderBook) stpMode() STPMode {
+	if ob.config.STP != "" {
+		return ob.config.STP
+	}
+	return ob.stp
+}
+
+// selfTrade reports whether order and the resting order top belong to the
+// same account under a mode that prevents them from trading.
+func selfTrade(mode STPMode, order, top *Order) bool {
+	return mode != "" && mode != STPNone && top.Account != "" && top.Account == order.Account
+}
+
+// preventSelfTrade applies mode to the incoming order and the resting order
+// at the top of h, which belong to the same account, and counts the action
+// in res. It reports whether matching of the incoming order must stop. The
+// caller must hold ob.mutex.
+func (ob *OrderBook) preventSelfTrade(res *MatchResult, h heap.Interface, mode STPMode, order, top *Order, now int64) bool {
+	res.SelfTrades++
+	switch mode {
+	case STPCancelResting:
+		ob.cancelSelfTrade(res, heap.Pop(h).(*Order), now)
+		return false
+	case STPCancelIncoming:
+		ob.cancelIncoming(res, order, SelfTradePrevented, now)
+		return true
+	case STPCancelBoth:
+		ob.cancelSelfTrade(res, heap.Pop(h).(*Order), now)
+		ob.cancelIncoming(res, order, SelfTradePrevented, now)
+		return true
+	}
+
+	qty := min(order.Qty, top.Qty)
+	if qty.Equal(top.Qty) {
+		ob.cancelSelfTrade(res, heap.Pop(h).(*Order), now)
+	} else {
+		top.Qty = top.Qty.Sub(qty)
+		ob.addVolume(top.Side, qty.Neg())
+		ob.emit(Reduced, *top)
+		res.Fills = append(res.Fills, reducedFill(ob.Pair, *top, qty, now))
+	}
+	if qty.Equal(order.Qty) {
+		ob.cancelIncoming(res, order, SelfTradePrevented, now)
+		return true
+	}
+	order.Qty = order.Qty.Sub(qty)
+	res.Fills = append(res.Fills, reducedFill(ob.Pair, *order, qty, now))
+	return false
+}
+
+// cancelSelfTrade reports a resting order that matching has popped off its
+// heap to prevent a self-trade as canceled and releases it. The caller must
+// hold ob.mutex.
+func (ob *OrderBook) cancelSelfTrade(res *MatchResult, o *Order, now int64) {
+	ob.addVolume(o.Side, o.Qty.Neg())
+	fill := canceledFill(ob.Pair, *o, now)
+	fill.Reason = SelfTradePrevented
+	res.Fills = append(res.Fills, fill)
+	ob.emit(Removed, *o)
+	releaseOrder(o)
+}
@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// TestSelfTradePrevention tests that each STP mode stops an account trading with itself and leaves the right quantities
+func TestSelfTradePrevention(t *testing.T) {
+	tests := []struct {
+		mode      STPMode
+		trades    int
+		ownAsk    float64 // Quantity of the account's own ask left resting
+		otherAsk  float64 // Quantity of the other account's ask left resting
+		restedBid float64 // Quantity of the incoming buy left resting
+	}{
+		{STPNone, 2, 0, 0, 0},
+		{"", 2, 0, 0, 0},
+		{STPCancelResting, 1, 0, 0, 3},
+		{STPCancelIncoming, 0, 3, 5, 0},
+		{STPCancelBoth, 0, 0, 5, 0},
+		{STPDecrementBoth, 1, 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			ob := NewOrderBook("BTC-USD")
+			ob.SetConfig(PairConfig{STP: tt.mode})
+			ob.Execute(Order{ID: "own", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(3), Account: "alice"})
+			ob.Execute(Order{ID: "other", Side: Sell, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(5), Account: "bob"})
+
+			res := ob.Execute(Order{ID: "buy", Side: Buy, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(8), Account: "alice"})
+			if len(res.Trades) != tt.trades {
+				t.Fatalf("Expected %d trades, got %+v", tt.trades, res.Trades)
+			}
+			for _, trade := range res.Trades {
+				if trade.SellOrderID == "own" && tt.mode != STPNone && tt.mode != "" {
+					t.Errorf("Expected no self-trade, got %+v", trade)
+				}
+			}
+
+			snap := ob.Snapshot()
+			remaining := map[string]decimal.Decimal{}
+			for _, o := range append(snap.Bids, snap.Asks...) {
+				remaining[o.ID] = o.Qty
+			}
+			for id, expected := range map[string]float64{"own": tt.ownAsk, "other": tt.otherAsk, "buy": tt.restedBid} {
+				if !remaining[id].Equal(decimal.NewFromFloat(expected)) {
+					t.Errorf("Expected %s to rest %v, got %s", id, expected, remaining[id])
+				}
+			}
+		})
+	}
+}
+
+// TestDecrementBothQuantities tests that decrement-both reduces both orders by the overlap without trading
+func TestDecrementBothQuantities(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	ob.SetConfig(PairConfig{STP: STPDecrementBoth})
+	ob.Execute(Order{ID: "own", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(5), Account: "alice"})
+
+	res := ob.Execute(Order{ID: "buy", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(2), Account: "alice"})
+	if len(res.Trades) != 0 {
+		t.Fatalf("Expected no trades, got %+v", res.Trades)
+	}
+	if res.SelfTrades != 1 {
+		t.Errorf("Expected one self-trade prevented, got %d", res.SelfTrades)
+	}
+	if _, asks := ob.RestingVolume(); !asks.Equal(decimal.NewFromInt(3)) {
+		t.Errorf("Expected the resting ask reduced to 3, got %s", asks)
+	}
+	last := res.Fills[len(res.Fills)-1]
+	if last.OrderID != "buy" || last.Status != Canceled || last.Reason != SelfTradePrevented {
+		t.Errorf("Expected the incoming buy to be canceled by STP, got %+v", last)
+	}
+	if bids, _ := ob.RestingVolume(); !bids.IsZero() {
+		t.Errorf("Expected nothing of the buy to rest, got %s", bids)
+	}
+}
+
+// TestSelfTradePreventionFOK tests that a fill-or-kill order does not count its own account's liquidity
+func TestSelfTradePreventionFOK(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	ob.SetConfig(PairConfig{STP: STPCancelResting})
+	ob.Execute(Order{ID: "own", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(3), Account: "alice"})
+
+	res := ob.Execute(Order{ID: "fok", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(3), Account: "alice", TimeInForce: FOK})
+	if len(res.Trades) != 0 {
+		t.Errorf("Expected no trades, got %+v", res.Trades)
+	}
+	if _, asks := ob.RestingVolume(); !asks.Equal(decimal.NewFromInt(3)) {
+		t.Errorf("Expected the resting ask untouched, got %s", asks)
+	}
+}
+
+// TestEngineSelfTradePrevention tests the engine-wide default, the per-pair override and the STP counter
+func TestEngineSelfTradePrevention(t *testing.T) {
+	engine := NewEngine(WithSelfTradePrevention(STPCancelIncoming))
+	engine.SetPairConfig("ETH-USD", PairConfig{STP: STPNone})
+
+	for _, pair := range []string{"BTC-USD", "ETH-USD"} {
+		engine.AddOrder(pair, Order{ID: pair + "-sell", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1), Account: "alice"})
+		engine.AddOrder(pair, Order{ID: pair + "-buy", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1), Account: "alice"})
+	}
+	drainFills(engine)
+
+	if trade := <-engine.TradeStream; trade.Pair != "ETH-USD" {
+		t.Errorf("Expected only ETH-USD to trade, got %+v", trade)
+	}
+	if len(engine.TradeStream) != 0 {
+		t.Errorf("Expected a single trade, got %d more", len(engine.TradeStream))
+	}
+	if n := engine.Metrics().SelfTradesPrevented; n != 1 {
+		t.Errorf("Expected 1 self-trade prevented, got %d", n)
+	}
+	if _, asks, _ := engine.OpenOrderCount("BTC-USD"); asks != 1 {
+		t.Errorf("Expected the BTC-USD ask to keep resting, got %d asks", asks)
+	}
+}
@@ -0,0 +1,171 @@
+package engine
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// defaultPriceInterval is how often StartPriceBroadcaster sends updates
+	// unless configured otherwise.
+	defaultPriceInterval = 500 * time.Millisecond
+
+	// defaultDepthInterval is how often StartDepthStreamer sends updates
+	// unless configured otherwise.
+	defaultDepthInterval = 100 * time.Millisecond
+
+	// defaultStreamDepth is the number of price levels per side
+	// StartDepthStreamer sends unless configured otherwise.
+	defaultStreamDepth = 10
+)
+
+// PriceBroadcastConfig configures StartPriceBroadcaster. The zero value
+// broadcasts every pair every 500ms.
+type PriceBroadcastConfig struct {
+	Interval time.Duration            // Time between updates, 500ms if zero
+	Pairs    map[string]time.Duration // Per-pair intervals overriding Interval
+
+	OnChange  bool          // Only send a pair when its best bid, best ask or average price changed
+	Heartbeat time.Duration // With OnChange, resend an unchanged pair after this long; never if zero
+
+	// VWAPWindow makes AvgPrice the volume-weighted average of the trades
+	// within this window, measured in one-minute buckets over the 24 hours of
+	// retained history. A pair with no trades in the window, or a zero
+	// window, reports the all-time average.
+	VWAPWindow time.Duration
+}
+
+// DepthStreamConfig configures StartDepthStreamer. The zero value streams 10
+// levels per side for every pair every 100ms.
+type DepthStreamConfig struct {
+	Interval time.Duration                  // Time between updates, 100ms if zero
+	Depth    int                            // Price levels per side, 10 if zero
+	Pairs    map[string]DepthStreamOverride // Per-pair settings overriding Interval and Depth
+
+	OnChange  bool          // Only send a pair when its book changed since it was last sent
+	Heartbeat time.Duration // With OnChange, resend an unchanged pair after this long; never if zero
+}
+
+// DepthStreamOverride holds the depth stream settings of a single pair. Zero
+// fields fall back to the DepthStreamConfig values.
+type DepthStreamOverride struct {
+	Interval time.Duration // Time between updates for the pair
+	Depth    int           // Price levels per side for the pair
+}
+
+// depth returns the number of levels to stream for pair.
+func (c DepthStreamConfig) depth(pair string) int {
+	if override := c.Pairs[pair].Depth; override > 0 {
+		return override
+	}
+	if c.Depth > 0 {
+		return c.Depth
+	}
+	return defaultStreamDepth
+}
+
+// schedule decides which pairs a streaming loop sends on each tick. The loop
+// ticks at the shortest configured interval and sends a pair once its own
+// interval has passed since it was last sent. It is only used by the loop's
+// goroutine.
+type schedule struct {
+	interval  time.Duration
+	overrides map[string]time.Duration
+	last      map[string]time.Time
+}
+
+// newSchedule returns a schedule with the given interval, or fallback if it is
+// not positive, and per-pair overrides.
+func newSchedule(interval, fallback time.Duration, overrides map[string]time.Duration) *schedule {
+	if interval <= 0 {
+		interval = fallback
+	}
+	return &schedule{interval: interval, overrides: overrides, last: make(map[string]time.Time)}
+}
+
+// tick returns the interval the loop runs at.
+func (s *schedule) tick() time.Duration {
+	tick := s.interval
+	for _, interval := range s.overrides {
+		if interval > 0 && interval < tick {
+			tick = interval
+		}
+	}
+	return tick
+}
+
+// due reports whether pair should be sent at now and, if so, records it as
+// sent. Half a tick of slack absorbs ticker jitter, so a pair on the loop's
+// own interval is sent on every tick.
+func (s *schedule) due(pair string, now time.Time) bool {
+	interval := s.interval
+	if override := s.overrides[pair]; override > 0 {
+		interval = override
+	}
+	if last, sent := s.last[pair]; sent && now.Sub(last) < interval-s.tick()/2 {
+		return false
+	}
+	s.last[pair] = now
+	return true
+}
+
+// priceChanges remembers the last price update sent for each pair so unchanged
+// pairs can be skipped. It is only used by the broadcaster's goroutine.
+type priceChanges struct {
+	heartbeat time.Duration
+	last      map[string]sentPrice
+}
+
+// sentPrice is the part of a sent PriceUpdate that change detection compares.
+type sentPrice struct {
+	bid, ask, avg decimal.Decimal
+	at            time.Time
+}
+
+// newPriceChanges returns change detection that resends unchanged pairs after
+// heartbeat, or never if heartbeat is zero.
+func newPriceChanges(heartbeat time.Duration) *priceChanges {
+	return &priceChanges{heartbeat: heartbeat, last: make(map[string]sentPrice)}
+}
+
+// changed reports whether update should be sent at now because its prices
+// differ from the last update sent for the pair or the heartbeat is due, and
+// if so records it as sent.
+func (c *priceChanges) changed(update PriceUpdate, now time.Time) bool {
+	last, sent := c.last[update.Pair]
+	if sent && last.bid.Equal(update.BestBid) && last.ask.Equal(update.BestAsk) && last.avg.Equal(update.AvgPrice) &&
+		(c.heartbeat <= 0 || now.Sub(last.at) < c.heartbeat) {
+		return false
+	}
+	c.last[update.Pair] = sentPrice{bid: update.BestBid, ask: update.BestAsk, avg: update.AvgPrice, at: now}
+	return true
+}
+
+// depthChanges remembers when depth was last sent for each pair so pairs whose
+// book has not changed can be skipped without aggregating their levels. It is
+// only used by the streamer's goroutine.
+type depthChanges struct {
+	heartbeat time.Duration
+	last      map[string]time.Time
+}
+
+// newDepthChanges returns change detection that resends unchanged pairs after
+// heartbeat, or never if heartbeat is zero.
+func newDepthChanges(heartbeat time.Duration) *depthChanges {
+	return &depthChanges{heartbeat: heartbeat, last: make(map[string]time.Time)}
+}
+
+// changed reports whether the depth of pair should be sent at now because
+// book changed since it was last sent or the heartbeat is due, and if so
+// records it as sent. It clears the book's change flag, so it must be called
+// before the depth is read.
+func (c *depthChanges) changed(pair string, book *OrderBook, now time.Time) bool {
+	dirty := book.takeChanged()
+	last, sent := c.last[pair]
+	if sent && !dirty && (c.heartbeat <= 0 || now.Sub(last) < c.heartbeat) {
+		return false
+	}
+	c.last[pair] = now
+	return true
+}
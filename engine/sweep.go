@@ -0,0 +1,42 @@
+package engine
+
+import "github.com/shopspring/decimal"
+
+// sweep tracks the price levels an incoming order has filled at while it is
+// matched, to enforce its MaxLevels and MaxSlippage.
+type sweep struct {
+	levels int             // Distinct price levels filled at so far
+	first  decimal.Decimal // Price of the first level filled at
+	last   decimal.Decimal // Price of the latest level filled at
+}
+
+// allows reports whether order may fill at a resting order priced at price.
+// The first level and further fills at the latest level are always allowed.
+func (s *sweep) allows(order *Order, price decimal.Decimal) bool {
+	if s.levels == 0 || price.Equal(s.last) {
+		return true
+	}
+	if order.MaxLevels > 0 && s.levels >= order.MaxLevels {
+		return false
+	}
+	if order.MaxSlippage.IsPositive() {
+		tolerance := s.first.Mul(order.MaxSlippage).Div(decimal.NewFromInt(100))
+		if order.Side == Buy {
+			return price.LessThanOrEqual(s.first.Add(tolerance))
+		}
+		return price.GreaterThanOrEqual(s.first.Sub(tolerance))
+	}
+	return true
+}
+
+// fill records a fill at a resting order priced at price.
+func (s *sweep) fill(price decimal.Decimal) {
+	if s.levels > 0 && price.Equal(s.last) {
+		return
+	}
+	if s.levels == 0 {
+		s.first = price
+	}
+	s.levels++
+	s.last = price
+}
@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// TestMaxLevels tests that a large order with MaxLevels=1 fills only the best level and cancels the rest
+func TestMaxLevels(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	ob.Execute(Order{ID: "ask1", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	ob.Execute(Order{ID: "ask2", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	ob.Execute(Order{ID: "ask3", Side: Sell, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(1)})
+
+	res := ob.Execute(Order{ID: "buy", Side: Buy, Price: decimal.NewFromInt(105), Qty: decimal.NewFromInt(5), MaxLevels: 1})
+	if len(res.Trades) != 2 {
+		t.Fatalf("Expected 2 trades at the best level, got %+v", res.Trades)
+	}
+	for _, trade := range res.Trades {
+		if !trade.Price.Equal(decimal.NewFromInt(100)) {
+			t.Errorf("Expected trades only at 100, got %s", trade.Price)
+		}
+	}
+	last := res.Fills[len(res.Fills)-1]
+	if last.OrderID != "buy" || last.Status != Canceled || last.Reason != SlippageLimit {
+		t.Errorf("Expected the remainder to be canceled for the slippage limit, got %+v", last)
+	}
+	if bids, asks := ob.RestingVolume(); !bids.IsZero() || !asks.Equal(decimal.NewFromInt(1)) {
+		t.Errorf("Expected no bid and the 101 ask to rest, got bids %s asks %s", bids, asks)
+	}
+}
+
+// TestMaxSlippage tests that a sell stops sweeping at the first level more than MaxSlippage percent below its first fill
+func TestMaxSlippage(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	for i, price := range []int64{100, 99, 97} {
+		ob.Execute(Order{ID: string(rune('a' + i)), Side: Buy, Price: decimal.NewFromInt(price), Qty: decimal.NewFromInt(1)})
+	}
+
+	res := ob.Execute(Order{ID: "sell", Side: Sell, Type: Market, Qty: decimal.NewFromInt(3), MaxSlippage: decimal.NewFromInt(2)})
+	if len(res.Trades) != 2 || !res.Trades[1].Price.Equal(decimal.NewFromInt(99)) {
+		t.Fatalf("Expected trades at 100 and 99, got %+v", res.Trades)
+	}
+	if last := res.Fills[len(res.Fills)-1]; last.Reason != SlippageLimit || !last.OriginalQty.Equal(decimal.NewFromInt(1)) {
+		t.Errorf("Expected 1 canceled for the slippage limit, got %+v", last)
+	}
+
+	// Without a limit the order sweeps as far as its price allows and rests the rest
+	res = ob.Execute(Order{ID: "sell2", Side: Sell, Price: decimal.NewFromInt(90), Qty: decimal.NewFromInt(2)})
+	if len(res.Trades) != 1 {
+		t.Errorf("Expected 1 trade, got %+v", res.Trades)
+	}
+	if _, asks := ob.RestingVolume(); !asks.Equal(decimal.NewFromInt(1)) {
+		t.Errorf("Expected the uncapped remainder to rest, got %s", asks)
+	}
+}
@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"container/heap"
+	"math"
+
+	"github.com/shopspring/decimal"
+)
+
+// maxTicks bounds the tick counts stored on orders so they fit in an int64.
+var maxTicks = decimal.NewFromInt(math.MaxInt64)
+
+// setTicks records o's price as a whole number of the book's tick size, which
+// lets the heaps and the matching loop compare prices as integers. Prices that
+// are not a whole number of ticks, or books without a tick size, fall back to
+// decimal comparisons. The caller must hold ob.mutex.
+func (ob *OrderBook) setTicks(o *Order) {
+	o.ticks, o.ticked = 0, false
+	tick := ob.config.TickSize
+	if !tick.IsPositive() || o.Price.IsNegative() {
+		return
+	}
+	q, r := o.Price.QuoRem(tick, 0)
+	if r.IsZero() && q.LessThanOrEqual(maxTicks) {
+		o.ticks, o.ticked = q.IntPart(), true
+	}
+}
+
+// retick recomputes the tick counts of every resting order after the tick
+// size changed. Prices are unchanged, so priority is too, but the heaps are
+// rebuilt in case orders moved between integer and decimal comparison. The
+// caller must hold ob.mutex.
+func (ob *OrderBook) retick() {
+	for _, h := range []orderHeap{ob.bids.orderHeap, ob.asks.orderHeap} {
+		for _, o := range h {
+			ob.setTicks(o)
+		}
+	}
+	heap.Init(ob.bids)
+	heap.Init(ob.asks)
+	ob.dirty.Store(true)
+}
+
+// comparePrices compares the prices of a and b like decimal.Cmp, using their
+// tick counts when both have one.
+func comparePrices(a, b *Order) int {
+	if a.ticked && b.ticked {
+		switch {
+		case a.ticks < b.ticks:
+			return -1
+		case a.ticks > b.ticks:
+			return 1
+		}
+		return 0
+	}
+	return a.Price.Cmp(b.Price)
+}
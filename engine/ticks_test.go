@@ -0,0 +1,56 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// TestTickPriceOrdering tests that integer-tick comparisons keep price-time priority, including off-tick orders
+func TestTickPriceOrdering(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	ob.SetConfig(PairConfig{TickSize: decimal.RequireFromString("0.5")})
+
+	ob.Execute(Order{ID: "ask1", Side: Sell, Price: decimal.RequireFromString("101.5"), Qty: decimal.NewFromInt(1)})
+	ob.Execute(Order{ID: "ask2", Side: Sell, Price: decimal.RequireFromString("101.25"), Qty: decimal.NewFromInt(1)})
+	ob.Execute(Order{ID: "ask3", Side: Sell, Price: decimal.RequireFromString("101"), Qty: decimal.NewFromInt(1)})
+	ob.Execute(Order{ID: "ask4", Side: Sell, Price: decimal.RequireFromString("101.0"), Qty: decimal.NewFromInt(1)})
+
+	ob.mutex.Lock()
+	for _, o := range ob.asks.orderHeap {
+		if expected := o.ID != "ask2"; o.ticked != expected {
+			t.Errorf("Expected %s ticked=%v, got %v", o.ID, expected, o.ticked)
+		}
+	}
+	ob.mutex.Unlock()
+
+	res := ob.Execute(Order{ID: "bid", Side: Buy, Price: decimal.RequireFromString("101.5"), Qty: decimal.NewFromInt(4)})
+	expected := []string{"ask3", "ask4", "ask2", "ask1"}
+	if len(res.Trades) != len(expected) {
+		t.Fatalf("Expected %d trades, got %d", len(expected), len(res.Trades))
+	}
+	for i, id := range expected {
+		if res.Trades[i].SellOrderID != id {
+			t.Errorf("Expected trade %d against %s, got %s", i, id, res.Trades[i].SellOrderID)
+		}
+	}
+}
+
+// TestTickSizeChange tests that resting orders are re-ticked when the tick size changes
+func TestTickSizeChange(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	ob.SetConfig(PairConfig{TickSize: decimal.NewFromInt(1)})
+	ob.Execute(Order{ID: "bid1", Side: Buy, Price: decimal.NewFromInt(99), Qty: decimal.NewFromInt(1)})
+	ob.Execute(Order{ID: "bid2", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+
+	ob.SetConfig(PairConfig{TickSize: decimal.RequireFromString("0.1")})
+	ob.Execute(Order{ID: "bid3", Side: Buy, Price: decimal.RequireFromString("99.5"), Qty: decimal.NewFromInt(1)})
+
+	if err := ob.Validate(); err != nil {
+		t.Fatalf("Expected a valid book after the tick change, got %v", err)
+	}
+	levels := ob.GetBidDepth(3)
+	if len(levels) != 3 || !levels[0].Price.Equal(decimal.NewFromInt(100)) || !levels[1].Price.Equal(decimal.RequireFromString("99.5")) {
+		t.Errorf("Expected bids 100, 99.5, 99, got %+v", levels)
+	}
+}
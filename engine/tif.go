@@ -0,0 +1,177 @@
+package engine
+
+import (
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Session describes the trading day that Day orders live for.
+type Session struct {
+	Close    time.Duration  // Offset from midnight at which the session closes
+	Location *time.Location // Time zone the session is defined in, UTC if nil
+}
+
+// nextClose returns the Unix timestamp of the first session close strictly
+// after now.
+func (s Session) nextClose(now time.Time) int64 {
+	loc := s.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	local := now.In(loc)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	end := midnight.Add(s.Close)
+	if !end.After(local) {
+		end = midnight.AddDate(0, 0, 1).Add(s.Close)
+	}
+	return end.Unix()
+}
+
+// WithSession sets the trading session used to expire Day orders. By default
+// the session closes at midnight UTC.
+func WithSession(s Session) Option {
+	return func(e *Engine) {
+		e.session = s
+	}
+}
+
+// SetSession replaces the trading session used to expire the book's Day
+// orders. Day orders already resting keep their expiry.
+func (ob *OrderBook) SetSession(s Session) {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+	ob.session = s
+}
+
+// expired reports whether o is a GTD or Day order whose expiry has passed.
+func (o *Order) expired(now int64) bool {
+	return (o.TimeInForce == GTD || o.TimeInForce == Day) && o.ExpireAt <= now
+}
+
+// immediate reports whether any remainder of o is canceled instead of resting
+// once it has matched, as for IOC and Market orders.
+func (o *Order) immediate() bool {
+	return o.TimeInForce == IOC || o.Type == Market
+}
+
+// fillsCompletely reports whether order would be filled in full by the
+// orders resting on the opposite side at prices it accepts, ignoring expired
+// orders, orders self-trade prevention keeps it from trading with, orders the
+// match policy deems ineligible and all-or-none orders too large for what
+// would remain of it. The caller must hold ob.mutex.
+func (ob *OrderBook) fillsCompletely(order Order, now int64) bool {
+	h := ob.bids.orderHeap
+	worse := decimal.Decimal.LessThan
+	if order.Side == Buy {
+		h = ob.asks.orderHeap
+		worse = decimal.Decimal.GreaterThan
+	}
+	if len(h) == 0 {
+		return order.Qty.IsZero()
+	}
+
+	// Collect the crossing orders; a heap's children are never better than
+	// their parent, so a subtree can be pruned at the first worse price.
+	var candidates orderHeap
+	hasAON := false
+	stp := ob.stpMode()
+	stack := []int{0}
+	for len(stack) > 0 {
+		i := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if order.Type != Market && worse(h[i].Price, order.Price) {
+			continue
+		}
+		if !h[i].expired(now) && !selfTrade(stp, &order, h[i]) && ob.policy.Eligible(&order, h[i]) {
+			candidates = append(candidates, h[i])
+			hasAON = hasAON || h[i].AON
+		}
+		for _, child := range []int{2*i + 1, 2*i + 2} {
+			if child < len(h) {
+				stack = append(stack, child)
+			}
+		}
+	}
+
+	// All-or-none orders are passed over depending on what remains when
+	// matching reaches them, so walk the candidates in priority order.
+	if hasAON {
+		if order.Side == Buy {
+			sort.Sort(askOrders(candidates))
+		} else {
+			sort.Sort(bidOrders(candidates))
+		}
+	}
+	remaining := order.Qty
+	for _, o := range candidates {
+		if remaining.IsZero() {
+			break
+		}
+		if o.AON && o.Qty.GreaterThan(remaining) {
+			continue
+		}
+		remaining = remaining.Sub(min(remaining, o.Qty))
+	}
+	return remaining.IsZero()
+}
+
+// expiredIDs returns the IDs of the resting GTD and Day orders whose expiry
+// is at or before now.
+func (ob *OrderBook) expiredIDs(now int64) []string {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	var ids []string
+	for _, h := range []orderHeap{ob.bids.orderHeap, ob.asks.orderHeap} {
+		for _, o := range h {
+			if o.expired(now) {
+				ids = append(ids, o.ID)
+			}
+		}
+	}
+	return ids
+}
+
+// ExpireOrders cancels every resting GTD and Day order whose expiry has
+// passed, across all pairs, emitting a Canceled fill for each. Each pair is
+// swept on its shard, so expiry never interleaves with matching. Returns the
+// number of orders expired.
+func (e *Engine) ExpireOrders() int {
+	count := 0
+	for _, pair := range e.Pairs() {
+		_ = e.submit(pair, Order{}, func(book *OrderBook, res *MatchResult) error {
+			now := e.clock.Now().Unix()
+			for _, id := range book.expiredIDs(now) {
+				if err := e.journal.AppendCancel(pair, id); err != nil {
+					return err
+				}
+				if order, ok := book.Cancel(id); ok {
+					res.Fills = append(res.Fills, canceledFill(pair, order, now))
+					count++
+				}
+			}
+			return nil
+		})
+	}
+	return count
+}
+
+// StartExpirySweeper starts a background goroutine that calls ExpireOrders
+// every interval. Expired orders that are reached by matching before the next
+// sweep are canceled at that point instead. The sweeper exits when the engine
+// is closed.
+func (e *Engine) StartExpirySweeper(interval time.Duration) {
+	go func() {
+		for {
+			select {
+			case <-e.closing:
+				return
+			case <-time.After(interval):
+			}
+			e.ExpireOrders()
+		}
+	}()
+}
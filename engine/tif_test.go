@@ -0,0 +1,149 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// lastFill returns the final fill for the given order ID
+func lastFill(fills []OrderFill, id string) (OrderFill, bool) {
+	for i := len(fills) - 1; i >= 0; i-- {
+		if fills[i].OrderID == id {
+			return fills[i], true
+		}
+	}
+	return OrderFill{}, false
+}
+
+// TestTimeInForceGTC tests that GTC and unspecified orders rest
+func TestTimeInForceGTC(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	ob.Execute(Order{ID: "gtc", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1), TimeInForce: GTC})
+	ob.Execute(Order{ID: "default", Side: Buy, Price: decimal.NewFromInt(99), Qty: decimal.NewFromInt(1)})
+
+	if bids, _ := ob.OpenOrderCount(); bids != 2 {
+		t.Errorf("Expected 2 resting bids, got %d", bids)
+	}
+}
+
+// TestTimeInForceIOC tests that the unfilled remainder of an IOC order is canceled
+func TestTimeInForceIOC(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	ob.Execute(Order{ID: "ask", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+
+	res := ob.Execute(Order{ID: "ioc", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(3), TimeInForce: IOC})
+	if len(res.Trades) != 1 {
+		t.Fatalf("Expected 1 trade, got %d", len(res.Trades))
+	}
+	fill, _ := lastFill(res.Fills, "ioc")
+	if fill.Status != Canceled || !fill.OriginalQty.Equal(decimal.NewFromInt(2)) {
+		t.Errorf("Expected remainder of 2 canceled, got %+v", fill)
+	}
+	if bids, _ := ob.OpenOrderCount(); bids != 0 {
+		t.Errorf("Expected IOC remainder not to rest, got %d bids", bids)
+	}
+
+	res = ob.Execute(Order{ID: "miss", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1), TimeInForce: IOC})
+	if len(res.Fills) != 2 || res.Fills[0].Status != New || res.Fills[1].Status != Canceled {
+		t.Errorf("Expected an unmatched IOC to be acknowledged and canceled, got %+v", res.Fills)
+	}
+}
+
+// TestTimeInForceFOK tests that FOK orders fill completely or not at all
+func TestTimeInForceFOK(t *testing.T) {
+	ob := NewOrderBook("BTC-USD")
+	ob.Execute(Order{ID: "ask1", Side: Sell, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1)})
+	ob.Execute(Order{ID: "ask2", Side: Sell, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(1)})
+	ob.Execute(Order{ID: "ask3", Side: Sell, Price: decimal.NewFromInt(105), Qty: decimal.NewFromInt(5)})
+
+	res := ob.Execute(Order{ID: "kill", Side: Buy, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(3), TimeInForce: FOK})
+	if len(res.Trades) != 0 || len(res.Fills) != 2 || res.Fills[1].Status != Canceled {
+		t.Errorf("Expected FOK to be canceled without trading, got %+v", res)
+	}
+	if _, asks := ob.OpenOrderCount(); asks != 3 {
+		t.Errorf("Expected book untouched, got %d asks", asks)
+	}
+
+	res = ob.Execute(Order{ID: "fill", Side: Buy, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(2), TimeInForce: FOK})
+	if len(res.Trades) != 2 {
+		t.Errorf("Expected FOK to fill across 2 orders, got %d trades", len(res.Trades))
+	}
+	if fill, _ := lastFill(res.Fills, "fill"); fill.Status != Filled {
+		t.Errorf("Expected FOK to be filled, got %s", fill.Status)
+	}
+}
+
+// TestTimeInForceGTD tests that GTD orders expire by sweep and when reached by matching
+func TestTimeInForceGTD(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	engine := NewEngine(WithClock(clock))
+	pair := "BTC-USD"
+	now := clock.Now().Unix()
+
+	engine.AddOrder(pair, Order{ID: "past", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1), TimeInForce: GTD, ExpireAt: now})
+	if fill := <-engine.FillStream; fill.Status != Rejected {
+		t.Errorf("Expected GTD order already past expiry to be rejected, got %s", fill.Status)
+	}
+
+	engine.AddOrder(pair, Order{ID: "bid", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1), TimeInForce: GTD, ExpireAt: now + 60})
+	engine.AddOrder(pair, Order{ID: "ask", Side: Sell, Price: decimal.NewFromInt(110), Qty: decimal.NewFromInt(1), TimeInForce: GTD, ExpireAt: now + 120})
+	drainFills(engine)
+
+	if n := engine.ExpireOrders(); n != 0 {
+		t.Errorf("Expected nothing to expire yet, got %d", n)
+	}
+
+	clock.Advance(time.Minute)
+	if n := engine.ExpireOrders(); n != 1 {
+		t.Errorf("Expected 1 expired order, got %d", n)
+	}
+	if fill := <-engine.FillStream; fill.OrderID != "bid" || fill.Status != Canceled {
+		t.Errorf("Expected bid to be canceled by the sweep, got %+v", fill)
+	}
+
+	// An expired ask reached by matching is canceled instead of traded
+	clock.Advance(time.Minute)
+	engine.AddOrder(pair, Order{ID: "buy", Side: Buy, Price: decimal.NewFromInt(110), Qty: decimal.NewFromInt(1)})
+	if len(engine.TradeStream) != 0 {
+		t.Errorf("Expected no trade against an expired order, got %d", len(engine.TradeStream))
+	}
+	<-engine.FillStream // the buy's acknowledgment
+	if fill := <-engine.FillStream; fill.OrderID != "ask" || fill.Status != Canceled {
+		t.Errorf("Expected expired ask to be canceled, got %+v", fill)
+	}
+}
+
+// TestTimeInForceDay tests that Day orders expire at the configured session close
+func TestTimeInForceDay(t *testing.T) {
+	loc := time.FixedZone("EST", -5*60*60)
+	clock := newFakeClock(time.Date(2024, 1, 1, 15, 0, 0, 0, loc))
+	engine := NewEngine(WithClock(clock), WithSession(Session{Close: 16 * time.Hour, Location: loc}))
+	pair := "BTC-USD"
+
+	engine.AddOrder(pair, Order{ID: "day", Side: Buy, Price: decimal.NewFromInt(100), Qty: decimal.NewFromInt(1), TimeInForce: Day})
+	drainFills(engine)
+
+	book, _ := engine.book(pair)
+	expected := time.Date(2024, 1, 1, 16, 0, 0, 0, loc).Unix()
+	if snapshot := book.Snapshot(); snapshot.Bids[0].ExpireAt != expected {
+		t.Errorf("Expected expiry at session close %d, got %d", expected, snapshot.Bids[0].ExpireAt)
+	}
+
+	clock.Advance(59 * time.Minute)
+	if n := engine.ExpireOrders(); n != 0 {
+		t.Errorf("Expected Day order to survive until the close, got %d expired", n)
+	}
+	clock.Advance(time.Minute)
+	if n := engine.ExpireOrders(); n != 1 {
+		t.Errorf("Expected Day order to expire at the close, got %d expired", n)
+	}
+}
+
+// drainFills discards any buffered fill events
+func drainFills(engine *Engine) {
+	for len(engine.FillStream) > 0 {
+		<-engine.FillStream
+	}
+}
@@ -1,7 +1,11 @@
 // Package engine provides types and data structures for the order matching engine.
 package engine
 
-import "github.com/shopspring/decimal"
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
 
 // Side represents the direction of a trading order (buy or sell).
 type Side string
@@ -13,55 +17,153 @@ const (
 	Sell Side = "sell"
 )
 
+// OrderType determines how an order is handled when it reaches the book.
+type OrderType string
+
+const (
+	// Limit is an order that matches at its price or better and rests any
+	// remainder. An order with an empty Type is treated as Limit.
+	Limit OrderType = "limit"
+	// TrailingStop is held off the book with a stop level that follows
+	// favorable moves of the pair's reference price, the last trade price
+	// unless PairConfig.ReferencePrice says otherwise, by TrailOffset. When
+	// the market reverses through the stop level the order is released into
+	// the book as a Limit order.
+	TrailingStop OrderType = "trailing_stop"
+	// Pegged rests at a price derived from a reference price plus PegOffset
+	// and is re-priced whenever the reference moves.
+	Pegged OrderType = "pegged"
+	// Market matches against the best available prices whatever its Price;
+	// any remainder is canceled instead of resting.
+	Market OrderType = "market"
+	// MarketIfTouched is held off the book until the pair's reference price,
+	// the last trade price by default, moves to or through TriggerPrice in the
+	// order's favor, at or below it for a buy and at or above it for a sell,
+	// and is then released as a Market order.
+	MarketIfTouched OrderType = "market_if_touched"
+)
+
+// PegReference selects the price a Pegged order tracks. References are taken
+// from the non-pegged orders in the book.
+type PegReference string
+
+const (
+	// PegBestBid tracks the best bid. An empty reference tracks the best
+	// price on the order's own side.
+	PegBestBid PegReference = "best_bid"
+	// PegBestAsk tracks the best ask.
+	PegBestAsk PegReference = "best_ask"
+	// PegMid tracks the midpoint of the best bid and best ask.
+	PegMid PegReference = "mid"
+)
+
+// TimeInForce determines how long an order remains active.
+type TimeInForce string
+
+const (
+	// GTC orders rest until filled or canceled. An order with an empty
+	// TimeInForce is treated as GTC.
+	GTC TimeInForce = "GTC"
+	// GTD orders rest until filled, canceled or their ExpireAt time passes.
+	GTD TimeInForce = "GTD"
+	// IOC orders match what they can immediately; any remainder is canceled
+	// instead of resting.
+	IOC TimeInForce = "IOC"
+	// FOK orders execute in full immediately or are canceled without trading.
+	FOK TimeInForce = "FOK"
+	// Day orders rest until the close of the current trading session.
+	Day TimeInForce = "DAY"
+)
+
 // Order represents a trading order with all necessary information for matching.
 // Orders are the fundamental unit of trading in the engine and contain all
 // details needed for price-time priority matching.
 type Order struct {
-	ID    string          // Unique identifier for the order
-	Side  Side            // Direction of the order (Buy or Sell)
-	Price decimal.Decimal // Price per unit for the order
-	Qty   decimal.Decimal // Quantity/amount to trade
-	Time  int64           // Unix timestamp when the order was created
+	ID    string          `json:"id"`    // Unique identifier for the order
+	Side  Side            `json:"side"`  // Direction of the order (Buy or Sell)
+	Price decimal.Decimal `json:"price"` // Price per unit for the order
+	Qty   decimal.Decimal `json:"qty"`   // Quantity/amount to trade
+	Time  int64           `json:"time"`  // Unix timestamp when the order was created; informational, priority follows Seq
+	Seq   uint64          `json:"seq"`   // Arrival sequence assigned by the book, used for time priority
+
+	ReceivedAt time.Time `json:"received_at"` // When the book accepted the order, to the nanosecond and increasing per pair; set by the book
+
+	Type         OrderType       `json:"type,omitempty"`          // Order type, Limit if empty
+	TrailOffset  decimal.Decimal `json:"trail_offset"`            // Distance a trailing stop keeps from the best price seen
+	TrailPercent bool            `json:"trail_percent,omitempty"` // Whether TrailOffset is a percentage rather than a price amount
+	StopPrice    decimal.Decimal `json:"stop_price"`              // Current trigger level of a trailing stop, maintained by the book
+	PegOffset    decimal.Decimal `json:"peg_offset"`              // Amount added to the reference price of a pegged order
+	PegReference PegReference    `json:"peg_reference,omitempty"` // Price a pegged order tracks
+	TriggerPrice decimal.Decimal `json:"trigger_price"`           // Reference price that releases a market-if-touched order
+	TimeInForce  TimeInForce     `json:"time_in_force,omitempty"` // Order lifetime, GTC if empty
+	ExpireAt     int64           `json:"expire_at,omitempty"`     // Unix timestamp a GTD or Day order expires at
+	Account      string          `json:"account,omitempty"`       // Account that placed the order
+	AON          bool            `json:"aon,omitempty"`           // All-or-none: the order only ever fills in its entirety
+	MaxLevels    int             `json:"max_levels,omitempty"`    // Most price levels the order sweeps before its remainder is canceled; unlimited if zero
+	MaxSlippage  decimal.Decimal `json:"max_slippage"`            // Furthest a level may be from the first fill, as a percentage, before the remainder is canceled; unlimited if zero
+
+	ClientOrderID string `json:"client_order_id,omitempty"` // Caller's own identifier, passed through untouched for correlation
+
+	ExecutedQty   decimal.Decimal `json:"executed_qty"`   // Quantity executed so far, maintained by the book
+	ExecutedValue decimal.Decimal `json:"executed_value"` // Sum of price * quantity executed so far, maintained by the book
+
+	// Price as a whole number of the book's tick size, set by the book when
+	// the price is on a tick so comparisons avoid decimal arithmetic.
+	ticks  int64
+	ticked bool
 }
 
 // Trade represents a successful match between two orders resulting in an execution.
 // Trades are generated when buy and sell orders are matched at a specific price and quantity.
 type Trade struct {
-	Pair        string          // Trading pair identifier (e.g., "BTC-USD")
-	BuyOrderID  string          // ID of the buy order involved in the trade
-	SellOrderID string          // ID of the sell order involved in the trade
-	Price       decimal.Decimal // Execution price of the trade
-	Qty         decimal.Decimal // Quantity traded
+	ID          string          `json:"id"`            // Trade ID, sequential within the pair (e.g., "BTC-USD-1")
+	Pair        string          `json:"pair"`          // Trading pair identifier (e.g., "BTC-USD")
+	BuyOrderID  string          `json:"buy_order_id"`  // ID of the buy order involved in the trade
+	SellOrderID string          `json:"sell_order_id"` // ID of the sell order involved in the trade
+	Price       decimal.Decimal `json:"price"`         // Execution price of the trade
+	Qty         decimal.Decimal `json:"qty"`           // Quantity traded
+	Timestamp   int64           `json:"timestamp"`     // Unix timestamp when the trade executed
+	TakerSide   Side            `json:"taker_side"`    // Side of the incoming order that took liquidity
+	MatchedAt   time.Time       `json:"matched_at"`    // When the trade executed, to the nanosecond
+
+	MakerRemaining decimal.Decimal `json:"maker_remaining"` // Quantity of the resting order left after the trade, zero if it was fully consumed
 }
 
 // PriceUpdate contains current best bid/ask prices and average price information
 // for a trading pair. These updates are broadcast periodically to provide
 // real-time price information to market participants.
 type PriceUpdate struct {
-	Pair     string          // Trading pair identifier
-	BestBid  decimal.Decimal // Highest bid (buy) price currently available
-	BestAsk  decimal.Decimal // Lowest ask (sell) price currently available
-	AvgPrice decimal.Decimal // Volume-weighted average price of recent trades
+	Pair      string          `json:"pair"`      // Trading pair identifier
+	BestBid   decimal.Decimal `json:"best_bid"`  // Highest bid (buy) price currently available
+	BestAsk   decimal.Decimal `json:"best_ask"`  // Lowest ask (sell) price currently available
+	AvgPrice  decimal.Decimal `json:"avg_price"` // Volume-weighted average price of recent trades
+	BidQty    decimal.Decimal `json:"bid_qty"`   // Total quantity resting at the best bid price
+	AskQty    decimal.Decimal `json:"ask_qty"`   // Total quantity resting at the best ask price
+	Mid       decimal.Decimal `json:"mid"`       // Midpoint of best bid and ask, zero if either side is empty
+	Timestamp int64           `json:"timestamp"` // Unix timestamp of the snapshot
 }
 
 // DepthLevel represents a single price level in the order book with aggregated
 // quantity and order count information. Multiple orders at the same price are
 // aggregated into a single depth level.
 type DepthLevel struct {
-	Price      decimal.Decimal // Price level
-	Quantity   decimal.Decimal // Total quantity available at this price level
-	TradeCount int             // Number of individual orders at this price level
+	Price      decimal.Decimal `json:"price"`       // Price level
+	Quantity   decimal.Decimal `json:"quantity"`    // Total quantity available at this price level
+	TradeCount int             `json:"trade_count"` // Number of individual orders at this price level
+
+	CumQuantity decimal.Decimal `json:"cum_quantity"` // Total quantity from the best price down to and including this level
+	CumNotional decimal.Decimal `json:"cum_notional"` // Total price * quantity from the best price down to and including this level
 }
 
 // DepthUpdate provides a snapshot of the order book depth showing the best
 // bid and ask levels. This gives market participants visibility into market
 // liquidity and potential support/resistance levels.
 type DepthUpdate struct {
-	Pair       string       // Trading pair identifier
-	Bids       []DepthLevel // Bid (buy) levels ordered from highest to lowest price
-	Asks       []DepthLevel // Ask (sell) levels ordered from lowest to highest price
-	Timestamp  int64        // Unix timestamp of the snapshot
-	TradeCount int64        // Total number of trades executed for this pair
+	Pair       string       `json:"pair"`        // Trading pair identifier
+	Bids       []DepthLevel `json:"bids"`        // Bid (buy) levels ordered from highest to lowest price
+	Asks       []DepthLevel `json:"asks"`        // Ask (sell) levels ordered from lowest to highest price
+	Timestamp  int64        `json:"timestamp"`   // Unix timestamp of the snapshot
+	TradeCount int64        `json:"trade_count"` // Total number of trades executed for this pair
 }
 
 // FillStatus represents the current execution status of an order.
@@ -76,22 +178,126 @@ const (
 	// Filled indicates the order has been completely executed with no remaining quantity.
 	Filled FillStatus = "FILLED"
 
-	// New indicates the order has been accepted but not yet executed.
+	// New acknowledges that the order has been accepted. Every accepted
+	// order gets exactly one New fill, ahead of any fill from matching it.
 	New FillStatus = "NEW"
+
+	// Canceled indicates the order was removed from the book before being
+	// completely executed.
+	Canceled FillStatus = "CANCELED"
+
+	// Rejected indicates the order was not accepted and never reached the book.
+	Rejected FillStatus = "REJECTED"
 )
 
 // OrderFill represents the execution details of an order or part of an order.
 // Fill events provide detailed information about order execution status and
 // are essential for order management and trade reporting.
 type OrderFill struct {
-	OrderID      string          // Unique identifier of the order being filled
-	Pair         string          // Trading pair identifier
-	Side         Side            // Direction of the order (Buy or Sell)
-	OriginalQty  decimal.Decimal // Original quantity when the order was placed
-	ExecutedQty  decimal.Decimal // Quantity executed in this fill event
-	RemainingQty decimal.Decimal // Quantity remaining to be filled
-	Price        decimal.Decimal // Original order price
-	FillPrice    decimal.Decimal // Actual execution price for this fill
-	Status       FillStatus      // Current status of the order after this fill
-	Timestamp    int64           // Unix timestamp when the fill occurred
+	OrderID      string          `json:"order_id"`      // Unique identifier of the order being filled
+	Pair         string          `json:"pair"`          // Trading pair identifier
+	Side         Side            `json:"side"`          // Direction of the order (Buy or Sell)
+	OriginalQty  decimal.Decimal `json:"original_qty"`  // Original quantity when the order was placed
+	ExecutedQty  decimal.Decimal `json:"executed_qty"`  // Quantity executed in this fill event
+	RemainingQty decimal.Decimal `json:"remaining_qty"` // Quantity remaining to be filled
+	Price        decimal.Decimal `json:"price"`         // Original order price
+	FillPrice    decimal.Decimal `json:"fill_price"`    // Actual execution price for this fill
+	Status       FillStatus      `json:"status"`        // Current status of the order after this fill
+	Timestamp    int64           `json:"timestamp"`     // Unix timestamp when the fill occurred
+
+	CumulativeQty      decimal.Decimal `json:"cumulative_qty"`       // Total quantity executed over the order's life
+	CumulativeAvgPrice decimal.Decimal `json:"cumulative_avg_price"` // Volume-weighted average price of all executions so far
+	AvgFillPrice       decimal.Decimal `json:"avg_fill_price"`       // Blended price the incoming order paid across the levels it swept, zero on resting orders' fills
+
+	Account       string          `json:"account,omitempty"`         // Account that placed the order
+	ClientOrderID string          `json:"client_order_id,omitempty"` // Client order ID of the order, if it had one
+	Fee           decimal.Decimal `json:"fee"`                       // Fee charged to the account for this execution, negative for a rebate
+
+	Reason RejectReason `json:"reason,omitempty"` // Why the order was rejected, or LiquidityExhausted when a market order's remainder is canceled
+
+	MatchedAt time.Time `json:"matched_at"` // When the execution happened, to the nanosecond; zero on fills that record no execution
+}
+
+// RejectReason explains why an order was refused, or why the remainder of a
+// market order was canceled.
+type RejectReason string
+
+const (
+	// RejectJournalFailed indicates the order could not be written to the journal.
+	RejectJournalFailed RejectReason = "JOURNAL_FAILED"
+	// RejectExpired indicates a GTD order arrived after its expiry time.
+	RejectExpired RejectReason = "EXPIRED"
+	// RejectRateLimited indicates the order's account exceeded its order rate.
+	RejectRateLimited RejectReason = "RATE_LIMITED"
+	// RejectBelowMinQty indicates the order quantity is below the pair's MinQty.
+	RejectBelowMinQty RejectReason = "BELOW_MIN_QTY"
+	// RejectBelowMinNotional indicates price * quantity is below the pair's
+	// MinNotional.
+	RejectBelowMinNotional RejectReason = "BELOW_MIN_NOTIONAL"
+	// RejectInvalidQty indicates the order quantity is not positive, for
+	// example after rounding down to the pair's StepSize.
+	RejectInvalidQty RejectReason = "INVALID_QTY"
+	// RejectOffTick indicates an amendment set a price that is not a multiple
+	// of the pair's TickSize.
+	RejectOffTick RejectReason = "OFF_TICK"
+	// RejectOffStep indicates an amendment left a quantity that is not a
+	// multiple of the pair's StepSize.
+	RejectOffStep RejectReason = "OFF_STEP"
+	// RejectInvalidPrice indicates the order price is negative.
+	RejectInvalidPrice RejectReason = "INVALID_PRICE"
+	// RejectHalted indicates trading in the pair is halted.
+	RejectHalted RejectReason = "HALTED"
+	// RejectBookFull indicates the book already holds as many resting orders
+	// as WithMaxRestingOrders allows, so the order could not rest.
+	RejectBookFull RejectReason = "BOOK_FULL"
+	// RejectPreTrade indicates a pre-trade hook registered with UsePreTrade
+	// refused the order.
+	RejectPreTrade RejectReason = "PRE_TRADE_REJECTED"
+	// SelfTradePrevented indicates the order was canceled, in full or for
+	// its remainder, because it would have traded with an order of the same
+	// account under the pair's STPMode.
+	SelfTradePrevented RejectReason = "SELF_TRADE_PREVENTED"
+	// OutsideDepth indicates a resting order was canceled because its price
+	// fell beyond the price levels retained under WithMaxPriceLevels.
+	OutsideDepth RejectReason = "OUTSIDE_DEPTH"
+	// SlippageLimit indicates the remainder of an order was canceled because
+	// matching it further would have swept past its MaxLevels or MaxSlippage.
+	SlippageLimit RejectReason = "SLIPPAGE_LIMIT"
+	// LiquidityExhausted indicates a market order consumed all the liquidity
+	// available to it and its unfilled remainder was canceled.
+	LiquidityExhausted RejectReason = "LIQUIDITY_EXHAUSTED"
+)
+
+// OrderEventType identifies a change to an individual resting order.
+type OrderEventType string
+
+const (
+	// Added indicates an order started resting in the book.
+	Added OrderEventType = "ADDED"
+	// Reduced indicates a resting order was partially filled; Order carries
+	// the remaining quantity.
+	Reduced OrderEventType = "REDUCED"
+	// Amended indicates a resting order's quantity was lowered by ReduceOrder
+	// or AmendOrder, keeping its place in the queue; Order carries the new
+	// quantity. An amendment that raises the quantity or changes the price
+	// loses priority and shows as Removed followed by Added under a new Seq.
+	Amended OrderEventType = "AMENDED"
+	// Removed indicates an order left the book because it was filled,
+	// canceled, expired or re-priced.
+	Removed OrderEventType = "REMOVED"
+	// Triggered indicates a pending trailing stop or market-if-touched order
+	// was released into the book. It does not change the resting orders by
+	// itself; the released order's own events follow.
+	Triggered OrderEventType = "TRIGGERED"
+)
+
+// OrderEvent is a level-3 market data event describing a change to a single
+// resting order. Replaying the events of a pair in sequence order reconstructs
+// its book order by order.
+type OrderEvent struct {
+	Seq       uint64         `json:"seq"`       // Per-pair sequence number, increasing by one per event
+	Type      OrderEventType `json:"type"`      // Kind of change
+	Pair      string         `json:"pair"`      // Trading pair identifier
+	Order     Order          `json:"order"`     // State of the order after the change
+	Timestamp int64          `json:"timestamp"` // Unix timestamp of the change
 }
@@ -0,0 +1,136 @@
+package engine
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// roundTrip marshals v, unmarshals it into out and returns the encoded JSON
+func roundTrip(t *testing.T, v, out interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	again, err := json.Marshal(out)
+	if err != nil {
+		t.Fatalf("Marshal after round trip failed: %v", err)
+	}
+	if string(data) != string(again) {
+		t.Errorf("Round trip changed encoding:\n%s\n%s", data, again)
+	}
+	return string(data)
+}
+
+// assertKeys fails the test unless every key appears in the encoded JSON
+func assertKeys(t *testing.T, data string, keys ...string) {
+	t.Helper()
+	for _, key := range keys {
+		if !strings.Contains(data, `"`+key+`":`) {
+			t.Errorf("Expected key %q in %s", key, data)
+		}
+	}
+}
+
+var precise = decimal.RequireFromString("12345.678901234567890123456789")
+
+// TestOrderJSON tests Order JSON encoding
+func TestOrderJSON(t *testing.T) {
+	in := Order{ID: "o1", Side: Sell, Price: precise, Qty: decimal.RequireFromString("0.10"), Time: 1700000000}
+	var out Order
+	data := roundTrip(t, in, &out)
+
+	assertKeys(t, data, "id", "side", "price", "qty", "time")
+	if !strings.Contains(data, `"side":"sell"`) {
+		t.Errorf("Expected side encoded as string constant, got %s", data)
+	}
+	if !strings.Contains(data, `"price":"12345.678901234567890123456789"`) {
+		t.Errorf("Expected price encoded as exact string, got %s", data)
+	}
+	if out.ID != in.ID || out.Side != in.Side || !out.Price.Equal(in.Price) || !out.Qty.Equal(in.Qty) || out.Time != in.Time {
+		t.Errorf("Expected %+v, got %+v", in, out)
+	}
+}
+
+// TestTradeJSON tests Trade JSON encoding
+func TestTradeJSON(t *testing.T) {
+	in := Trade{Pair: "BTC-USD", BuyOrderID: "b1", SellOrderID: "s1", Price: precise, Qty: decimal.NewFromFloat(0.25)}
+	var out Trade
+	data := roundTrip(t, in, &out)
+
+	assertKeys(t, data, "pair", "buy_order_id", "sell_order_id", "price", "qty")
+	if out.BuyOrderID != in.BuyOrderID || out.SellOrderID != in.SellOrderID || !out.Price.Equal(in.Price) || !out.Qty.Equal(in.Qty) {
+		t.Errorf("Expected %+v, got %+v", in, out)
+	}
+}
+
+// TestOrderFillJSON tests OrderFill JSON encoding
+func TestOrderFillJSON(t *testing.T) {
+	in := OrderFill{
+		OrderID:      "o1",
+		Pair:         "BTC-USD",
+		Side:         Buy,
+		OriginalQty:  decimal.NewFromInt(2),
+		ExecutedQty:  decimal.NewFromFloat(0.5),
+		RemainingQty: decimal.NewFromFloat(1.5),
+		Price:        precise,
+		FillPrice:    precise,
+		Status:       PartiallyFilled,
+		Timestamp:    1700000000,
+	}
+	var out OrderFill
+	data := roundTrip(t, in, &out)
+
+	assertKeys(t, data, "order_id", "original_qty", "executed_qty", "remaining_qty", "fill_price", "status")
+	if !strings.Contains(data, `"status":"PARTIALLY_FILLED"`) {
+		t.Errorf("Expected status encoded as string constant, got %s", data)
+	}
+	if out.Status != in.Status || !out.RemainingQty.Equal(in.RemainingQty) || !out.FillPrice.Equal(in.FillPrice) {
+		t.Errorf("Expected %+v, got %+v", in, out)
+	}
+}
+
+// TestDepthJSON tests DepthUpdate and DepthLevel JSON encoding
+func TestDepthJSON(t *testing.T) {
+	in := DepthUpdate{
+		Pair:       "BTC-USD",
+		Bids:       []DepthLevel{{Price: precise, Quantity: decimal.NewFromInt(3), TradeCount: 2}},
+		Asks:       []DepthLevel{},
+		Timestamp:  1700000000,
+		TradeCount: 7,
+	}
+	var out DepthUpdate
+	data := roundTrip(t, in, &out)
+
+	assertKeys(t, data, "pair", "bids", "asks", "timestamp", "trade_count", "quantity")
+	if len(out.Bids) != 1 || !out.Bids[0].Price.Equal(precise) || out.Bids[0].TradeCount != 2 {
+		t.Errorf("Expected %+v, got %+v", in, out)
+	}
+}
+
+// TestPriceUpdateJSON tests PriceUpdate JSON encoding
+func TestPriceUpdateJSON(t *testing.T) {
+	in := PriceUpdate{
+		Pair:      "BTC-USD",
+		BestBid:   decimal.NewFromInt(100),
+		BestAsk:   decimal.NewFromInt(101),
+		AvgPrice:  precise,
+		BidQty:    decimal.NewFromInt(1),
+		AskQty:    decimal.NewFromInt(2),
+		Mid:       decimal.NewFromFloat(100.5),
+		Timestamp: 1700000000,
+	}
+	var out PriceUpdate
+	data := roundTrip(t, in, &out)
+
+	assertKeys(t, data, "best_bid", "best_ask", "avg_price", "bid_qty", "ask_qty", "mid")
+	if !out.AvgPrice.Equal(in.AvgPrice) || !out.Mid.Equal(in.Mid) {
+		t.Errorf("Expected %+v, got %+v", in, out)
+	}
+}
@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	// ErrCrossedBook is returned by Validate when the best bid is at or above
+	// the best ask.
+	ErrCrossedBook = errors.New("engine: crossed book")
+
+	// ErrCorruptBook is returned by Validate when the book's internal state is
+	// inconsistent.
+	ErrCorruptBook = errors.New("engine: corrupt book")
+)
+
+// Validate checks the internal consistency of the book: both heaps satisfy
+// their ordering invariant, every resting order has a positive quantity, no
+// order ID rests more than once, the ID index locates every resting order,
+// the running volumes and cached top of book agree with the heaps, and the
+// best bid is below the best ask, disregarding all-or-none orders. It returns
+// nil if the book is consistent.
+func (ob *OrderBook) Validate() error {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	return ob.validate()
+}
+
+// validate implements Validate. The caller must hold ob.mutex.
+func (ob *OrderBook) validate() error {
+	seen := make(map[string]struct{}, ob.bids.Len()+ob.asks.Len())
+	sides := []struct {
+		name   string
+		h      heap.Interface
+		orders orderHeap
+		index  map[string]int
+		volume decimal.Decimal
+		top    *decimal.Decimal
+	}{
+		{"bid", ob.bids, ob.bids.orderHeap, ob.bids.index, ob.bidVol, ob.bestBid.Load()},
+		{"ask", ob.asks, ob.asks.orderHeap, ob.asks.index, ob.askVol, ob.bestAsk.Load()},
+	}
+
+	for _, side := range sides {
+		total := decimal.Zero
+		for i, o := range side.orders {
+			if i > 0 && side.h.Less(i, (i-1)/2) {
+				return fmt.Errorf("%w: %s heap out of order at %s", ErrCorruptBook, side.name, o.ID)
+			}
+			if !o.Qty.IsPositive() {
+				return fmt.Errorf("%w: %s %s rests with quantity %s", ErrCorruptBook, side.name, o.ID, o.Qty)
+			}
+			if _, dup := seen[o.ID]; dup {
+				return fmt.Errorf("%w: order %s rests more than once", ErrCorruptBook, o.ID)
+			}
+			seen[o.ID] = struct{}{}
+			if j, ok := side.index[o.ID]; !ok || j != i {
+				return fmt.Errorf("%w: %s index does not locate %s", ErrCorruptBook, side.name, o.ID)
+			}
+			total = total.Add(o.Qty)
+		}
+		if len(side.index) != len(side.orders) {
+			return fmt.Errorf("%w: %s index holds %d orders, heap %d", ErrCorruptBook, side.name, len(side.index), len(side.orders))
+		}
+
+		if !total.Equal(side.volume) {
+			return fmt.Errorf("%w: %s volume %s does not match resting total %s", ErrCorruptBook, side.name, side.volume, total)
+		}
+		switch {
+		case len(side.orders) == 0 && side.top != nil:
+			return fmt.Errorf("%w: cached best %s set on an empty side", ErrCorruptBook, side.name)
+		case len(side.orders) > 0 && (side.top == nil || !side.top.Equal(side.orders[0].Price)):
+			return fmt.Errorf("%w: cached best %s does not match the heap", ErrCorruptBook, side.name)
+		}
+	}
+
+	if bid, ask, crossed := ob.crossedPrices(); crossed {
+		return fmt.Errorf("%w: best bid %s >= best ask %s", ErrCrossedBook, bid, ask)
+	}
+	return nil
+}
+
+// bestMatchable returns the best price among the orders in h that are not
+// all-or-none, where better reports whether one price beats another.
+func bestMatchable(h orderHeap, better func(decimal.Decimal, decimal.Decimal) bool) (decimal.Decimal, bool) {
+	if len(h) > 0 && !h[0].AON {
+		return h[0].Price, true
+	}
+	best, found := decimal.Zero, false
+	for _, o := range h {
+		if !o.AON && (!found || better(o.Price, best)) {
+			best, found = o.Price, true
+		}
+	}
+	return best, found
+}
+
+// SetInvariantChecks enables or disables validation of the book after every
+// match. When enabled, a match that leaves the book inconsistent panics with
+// the Validate error. This is a debugging aid for catching regressions and
+// costs O(n) per order.
+func (ob *OrderBook) SetInvariantChecks(enabled bool) {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+	ob.checkInvariants = enabled
+}
+
+// WithInvariantChecks enables SetInvariantChecks on every order book the
+// engine creates.
+func WithInvariantChecks() Option {
+	return func(e *Engine) {
+		e.checkInvariants = true
+	}
+}
@@ -0,0 +1,84 @@
+package engine
+
+import (
+	"container/heap"
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// validBook returns a small consistent book
+func validBook() *OrderBook {
+	ob := NewOrderBook("BTC-USD")
+	ob.Execute(Order{ID: "bid1", Side: Buy, Price: decimal.NewFromInt(99), Qty: decimal.NewFromInt(1)})
+	ob.Execute(Order{ID: "bid2", Side: Buy, Price: decimal.NewFromInt(98), Qty: decimal.NewFromInt(2)})
+	ob.Execute(Order{ID: "ask1", Side: Sell, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(1)})
+	return ob
+}
+
+// TestValidate tests that Validate accepts a consistent book and reports each kind of corruption
+func TestValidate(t *testing.T) {
+	if err := validBook().Validate(); err != nil {
+		t.Fatalf("Expected valid book, got %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		corrupt  func(ob *OrderBook)
+		expected error
+	}{
+		{"crossed", func(ob *OrderBook) {
+			heap.Push(ob.bids, &Order{ID: "bid3", Side: Buy, Price: decimal.NewFromInt(102), Qty: decimal.NewFromInt(1)})
+			ob.bidVol = ob.bidVol.Add(decimal.NewFromInt(1))
+			ob.refreshTop()
+		}, ErrCrossedBook},
+		{"zero quantity", func(ob *OrderBook) {
+			ob.bids.orderHeap[1].Qty = decimal.Zero
+			ob.bidVol = decimal.NewFromInt(1)
+		}, ErrCorruptBook},
+		{"duplicate id", func(ob *OrderBook) {
+			heap.Push(ob.asks, &Order{ID: "bid1", Side: Sell, Price: decimal.NewFromInt(105), Qty: decimal.NewFromInt(1)})
+			ob.askVol = ob.askVol.Add(decimal.NewFromInt(1))
+		}, ErrCorruptBook},
+		{"heap order", func(ob *OrderBook) {
+			ob.bids.orderHeap[0].Price = decimal.NewFromInt(90)
+			ob.refreshTop()
+		}, ErrCorruptBook},
+		{"stale index", func(ob *OrderBook) {
+			ob.bids.index["bid1"] = 1
+		}, ErrCorruptBook},
+		{"volume", func(ob *OrderBook) {
+			ob.askVol = decimal.NewFromInt(7)
+		}, ErrCorruptBook},
+		{"stale top", func(ob *OrderBook) {
+			price := decimal.NewFromInt(100)
+			ob.bestAsk.Store(&price)
+		}, ErrCorruptBook},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ob := validBook()
+			tt.corrupt(ob)
+			if err := ob.Validate(); !errors.Is(err, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+// TestInvariantChecks tests that matching a corrupted book panics when checks are enabled
+func TestInvariantChecks(t *testing.T) {
+	ob := validBook()
+	ob.SetInvariantChecks(true)
+	ob.Execute(Order{ID: "buy", Side: Buy, Price: decimal.NewFromInt(101), Qty: decimal.NewFromInt(1)})
+
+	ob.askVol = decimal.NewFromInt(7)
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("Expected invariant check to panic on a corrupt book")
+		}
+	}()
+	ob.Execute(Order{ID: "bid", Side: Buy, Price: decimal.NewFromInt(90), Qty: decimal.NewFromInt(1)})
+}
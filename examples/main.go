@@ -11,40 +11,25 @@ import (
 func main() {
 	e := engine.NewEngine()
 
-	e.StartPriceBroadcaster()
-	e.StartDepthStreamer(5)
+	e.StartPriceBroadcaster(engine.PriceBroadcastConfig{})
+	e.StartDepthStreamer(engine.DepthStreamConfig{Depth: 5})
 
 	go func() {
 		for trade := range e.TradeStream {
-			fmt.Printf("[TRADE] %s %.4f @ %.2f\n",
-				trade.Pair,
-				trade.Qty.InexactFloat64(),
-				trade.Price.InexactFloat64())
+			fmt.Println("[TRADE]", trade)
 		}
 	}()
 
 	go func() {
 		for price := range e.PriceUpdates {
-			fmt.Printf("[PRICE] %s BID: %.4f / ASK: %.4f / AVG: %.4f\n",
-				price.Pair,
-				price.BestBid.InexactFloat64(),
-				price.BestAsk.InexactFloat64(),
-				price.AvgPrice.InexactFloat64())
+			fmt.Println("[PRICE]", price)
 		}
 	}()
 
 	// Listen to order fill events
 	go func() {
 		for fill := range e.FillStream {
-			fmt.Printf("[FILL] Order %s (%s %s) - Status: %s | Executed: %.4f | Remaining: %.4f | Price: %.4f | Fill Price: %.4f\n",
-				fill.OrderID,
-				fill.Side,
-				fill.Pair,
-				fill.Status,
-				fill.ExecutedQty.InexactFloat64(),
-				fill.RemainingQty.InexactFloat64(),
-				fill.Price.InexactFloat64(),
-				fill.FillPrice.InexactFloat64())
+			fmt.Println("[FILL]", fill)
 		}
 	}()
 